@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"log"
 	"math"
@@ -9,6 +10,7 @@ import (
 	"os"
 	"sort"
 	"strconv"
+	"strings"
 	"time"
 
 	omisocial "github.com/Boxme-Global/tracking/src"
@@ -74,12 +76,73 @@ func main() {
 	// }
 	// Set up a default tracker with a salt.
 	// This will buffer and store hits and generate sessions by default.
-	tracker := omisocial.NewTracker(store, "BuS7BsvURhatRPqr", nil)
+	// Android app referrers are resolved in the background and cached in the database.
+	// Site search terms are extracted from the query parameters in SEARCH_QUERY_PARAMS (comma separated) for all sites.
+	// Visitors are considered returning if they had a session within RETURNING_VISITOR_MAX_AGE (like 720h, 30 days by default).
+	returningVisitorMaxAge, _ := time.ParseDuration(os.Getenv("RETURNING_VISITOR_MAX_AGE"))
+	tracker := omisocial.NewTracker(store, "BuS7BsvURhatRPqr", &omisocial.TrackerConfig{
+		AndroidAppResolver: omisocial.NewAndroidAppResolver(omisocial.AndroidAppResolverConfig{
+			Store: store,
+		}),
+		SearchQueryParams: map[uint64][]string{
+			0: splitEnvList(os.Getenv("SEARCH_QUERY_PARAMS")),
+		},
+		ReturningVisitorMaxAge: returningVisitorMaxAge,
+	})
+
+	// Load additional bot and referrer spam lists (comma separated file paths) and reload them when they change.
+	blacklists, err := omisocial.NewBlacklists(omisocial.BlacklistConfig{
+		UserAgentFiles: splitEnvList(os.Getenv("BLACKLIST_USER_AGENT_FILES")),
+		ReferrerFiles:  splitEnvList(os.Getenv("BLACKLIST_REFERRER_FILES")),
+	})
+
+	if err != nil {
+		log.Fatalf("Error loading blacklists: %s", err)
+	}
+
+	omisocial.SetBlacklists(blacklists)
+	blacklists.Watch(time.Minute)
+
+	// Load localized country, city, and language names used by the reports (comma separated file paths).
+	localizer, err := omisocial.NewLocalizer(omisocial.LocalizerConfig{
+		LocationFiles: splitEnvList(os.Getenv("LOCALIZER_LOCATION_FILES")),
+		LanguageFiles: splitEnvList(os.Getenv("LOCALIZER_LANGUAGE_FILES")),
+	})
+
+	if err != nil {
+		log.Fatalf("Error loading localizations: %s", err)
+	}
+
+	omisocial.SetLocalizer(localizer)
+
+	// Keep the GeoDB up to date. The database is downloaded from MaxMind unless GEODB_URL points to a mirror.
+	var geoDBUpdater *omisocial.GeoDBUpdater
+
+	if geoDBPath := os.Getenv("GEODB_PATH"); geoDBPath != "" {
+		geoDBUpdater, err = omisocial.NewGeoDBUpdater(omisocial.GeoDBUpdaterConfig{
+			Path:        geoDBPath,
+			URL:         os.Getenv("GEODB_URL"),
+			ChecksumURL: os.Getenv("GEODB_CHECKSUM_URL"),
+			LicenseKey:  os.Getenv("GEOLITE2_LICENSE_KEY"),
+			ASNFile:     os.Getenv("GEODB_ASN_FILE"),
+			Tracker:     tracker,
+		})
+
+		if err != nil {
+			log.Fatalf("Error creating GeoDB updater: %s", err)
+		}
+
+		geoDBUpdater.Start()
+	}
+
+	// Saved segments are applied to the reports using the segment_id query parameter.
+	segments := omisocial.NewSegments(store)
 
 	// Create a handler to serve traffic.
 	// We prevent tracking resources by checking the path. So a file on /my-file.txt won't create a new hit
 	// but all page calls will be tracked.
 	http.Handle("/", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		omisocial.SetAcceptClientHints(w)
 		eventName := r.URL.Query().Get("event_name")
 		pageloadEvents := []string{"pageload", "pageclose"}
 
@@ -100,7 +163,7 @@ func main() {
 		w.Write([]byte("hi"))
 	}))
 
-	http.Handle("/report/visitors", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	http.Handle("/report/visitors", segmentHandler(segments, func(w http.ResponseWriter, r *http.Request) {
 		analyzer := omisocial.NewAnalyzer(store)
 
 		from, _ := strconv.ParseInt(r.URL.Query().Get("from"), 10, 64)
@@ -108,8 +171,7 @@ func main() {
 		site_id, _ := strconv.ParseInt(r.URL.Query().Get("site_id"), 10, 64)
 		group_by := r.URL.Query().Get("group_by")
 
-		groups := []string{"day", "week", "month"}
-		if from == 0 || to == 0 || site_id == 0 || from > to || (group_by != "" && !omisocial.Contains(groups, group_by)) {
+		if from == 0 || to == 0 || site_id == 0 || from > to || (group_by != "" && !omisocial.Contains(omisocial.Periods, group_by)) {
 			jData, _ := json.Marshal(&omisocial.Response{
 				Message: "Invalid input data",
 				Error:   true,
@@ -121,11 +183,11 @@ func main() {
 		}
 
 		visitors, _ := analyzer.Visitors(
-			&omisocial.Filter{
+			withSegment(r, &omisocial.Filter{
 				From:     time.Unix(from, 0),
 				To:       time.Unix(to, 0),
 				ClientID: site_id,
-			},
+			}),
 			group_by,
 		)
 
@@ -138,7 +200,96 @@ func main() {
 		w.Write(jData)
 	}))
 
-	http.Handle("/report/total-visitors", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	// New and returning visitors over time, like:
+	// /report/new-visitors?site_id=1&from=...&to=...&group_by=week
+	http.Handle("/report/new-visitors", segmentHandler(segments, func(w http.ResponseWriter, r *http.Request) {
+		analyzer := omisocial.NewAnalyzer(store)
+
+		from, _ := strconv.ParseInt(r.URL.Query().Get("from"), 10, 64)
+		to, _ := strconv.ParseInt(r.URL.Query().Get("to"), 10, 64)
+		site_id, _ := strconv.ParseInt(r.URL.Query().Get("site_id"), 10, 64)
+		group_by := r.URL.Query().Get("group_by")
+
+		if from == 0 || to == 0 || site_id == 0 || from > to || (group_by != "" && !omisocial.Contains(omisocial.Periods, group_by)) {
+			jData, _ := json.Marshal(&omisocial.Response{
+				Message: "Invalid input data",
+				Error:   true,
+				Data:    nil,
+			})
+			w.Header().Set("Content-Type", "application/json")
+			w.Write(jData)
+			return
+		}
+
+		response := &omisocial.Response{
+			Message: "",
+			Error:   false,
+			Data:    nil,
+		}
+		visitors, err := analyzer.NewVisitors(withSegment(r, &omisocial.Filter{
+			From:     time.Unix(from, 0),
+			To:       time.Unix(to, 0),
+			ClientID: site_id,
+		}), group_by)
+
+		if err != nil {
+			response.Message = err.Error()
+			response.Error = true
+		} else {
+			response.Data = visitors
+		}
+
+		jData, _ := json.Marshal(response)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(jData)
+	}))
+
+	// Visitors, sessions, and events for each day of week and hour in the given timezone (UTC by default), like:
+	// /report/heatmap?site_id=1&from=...&to=...&timezone=Asia/Ho_Chi_Minh
+	http.Handle("/report/heatmap", segmentHandler(segments, func(w http.ResponseWriter, r *http.Request) {
+		analyzer := omisocial.NewAnalyzer(store)
+
+		from, _ := strconv.ParseInt(r.URL.Query().Get("from"), 10, 64)
+		to, _ := strconv.ParseInt(r.URL.Query().Get("to"), 10, 64)
+		site_id, _ := strconv.ParseInt(r.URL.Query().Get("site_id"), 10, 64)
+		timezone, err := time.LoadLocation(r.URL.Query().Get("timezone"))
+
+		if from == 0 || to == 0 || site_id == 0 || from > to || err != nil {
+			jData, _ := json.Marshal(&omisocial.Response{
+				Message: "Invalid input data",
+				Error:   true,
+				Data:    nil,
+			})
+			w.Header().Set("Content-Type", "application/json")
+			w.Write(jData)
+			return
+		}
+
+		response := &omisocial.Response{
+			Message: "",
+			Error:   false,
+			Data:    nil,
+		}
+		heatmap, err := analyzer.TrafficHeatmap(withSegment(r, &omisocial.Filter{
+			From:     time.Unix(from, 0),
+			To:       time.Unix(to, 0),
+			ClientID: site_id,
+			Timezone: timezone,
+		}))
+
+		if err != nil {
+			response.Message = err.Error()
+			response.Error = true
+		} else {
+			response.Data = heatmap
+		}
+
+		jData, _ := json.Marshal(response)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(jData)
+	}))
+
+	http.Handle("/report/total-visitors", segmentHandler(segments, func(w http.ResponseWriter, r *http.Request) {
 		analyzer := omisocial.NewAnalyzer(store)
 
 		from, _ := strconv.ParseInt(r.URL.Query().Get("from"), 10, 64)
@@ -156,11 +307,11 @@ func main() {
 			return
 		}
 
-		growth, _ := analyzer.TotalVisitors(&omisocial.Filter{
+		growth, _ := analyzer.TotalVisitors(withSegment(r, &omisocial.Filter{
 			From:     time.Unix(from, 0),
 			To:       time.Unix(to, 0),
 			ClientID: site_id,
-		})
+		}))
 
 		jData, _ := json.Marshal(&omisocial.ResponseTotalVisitors{
 			Message: "",
@@ -171,7 +322,7 @@ func main() {
 		w.Write(jData)
 	}))
 
-	http.Handle("/report/platforms", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	http.Handle("/report/platforms", segmentHandler(segments, func(w http.ResponseWriter, r *http.Request) {
 		analyzer := omisocial.NewAnalyzer(store)
 
 		from, _ := strconv.ParseInt(r.URL.Query().Get("from"), 10, 64)
@@ -189,11 +340,11 @@ func main() {
 			return
 		}
 
-		platforms, _ := analyzer.PlatformVisitors(&omisocial.Filter{
+		platforms, _ := analyzer.PlatformVisitors(withSegment(r, &omisocial.Filter{
 			From:     time.Unix(from, 0),
 			To:       time.Unix(to, 0),
 			ClientID: site_id,
-		})
+		}))
 
 		jData, _ := json.Marshal(&omisocial.ResponsePlatformVisitors{
 			Message: "",
@@ -204,7 +355,7 @@ func main() {
 		w.Write(jData)
 	}))
 
-	http.Handle("/report/pages", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	http.Handle("/report/pages", segmentHandler(segments, func(w http.ResponseWriter, r *http.Request) {
 		analyzer := omisocial.NewAnalyzer(store)
 
 		from, _ := strconv.ParseInt(r.URL.Query().Get("from"), 10, 64)
@@ -233,12 +384,12 @@ func main() {
 			page_size = 25
 		}
 
-		count, _ := analyzer.PageCount(&omisocial.Filter{
+		count, _ := analyzer.PageCount(withSegment(r, &omisocial.Filter{
 			From:        time.Unix(from, 0),
 			To:          time.Unix(to, 0),
 			ClientID:    site_id,
 			PathPattern: path_pattern,
-		})
+		}))
 
 		if count == 0 {
 			jData, _ := json.Marshal(&omisocial.ResponsePages{
@@ -263,14 +414,14 @@ func main() {
 
 		offset := (int(page) - 1) * int(page_size)
 
-		pages, _ := analyzer.Pages(&omisocial.Filter{
+		pages, _ := analyzer.Pages(withSegment(r, &omisocial.Filter{
 			From:        time.Unix(from, 0),
 			To:          time.Unix(to, 0),
 			ClientID:    site_id,
 			PathPattern: path_pattern,
 			Limit:       int(page_size),
 			Offset:      int(offset),
-		})
+		}))
 
 		jData, _ := json.Marshal(&omisocial.ResponsePages{
 			Message:    "",
@@ -285,7 +436,7 @@ func main() {
 		w.Write(jData)
 	}))
 
-	http.Handle("/report/referrers", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	http.Handle("/report/referrers", segmentHandler(segments, func(w http.ResponseWriter, r *http.Request) {
 		analyzer := omisocial.NewAnalyzer(store)
 
 		from, _ := strconv.ParseInt(r.URL.Query().Get("from"), 10, 64)
@@ -313,11 +464,11 @@ func main() {
 			page_size = 25
 		}
 
-		count, _ := analyzer.ReferrerCount(&omisocial.Filter{
+		count, _ := analyzer.ReferrerCount(withSegment(r, &omisocial.Filter{
 			From:     time.Unix(from, 0),
 			To:       time.Unix(to, 0),
 			ClientID: site_id,
-		})
+		}))
 
 		if count == 0 {
 			jData, _ := json.Marshal(&omisocial.ResponseReferrers{
@@ -342,13 +493,14 @@ func main() {
 
 		offset := (int(page) - 1) * int(page_size)
 
-		referrers, _ := analyzer.Referrer(&omisocial.Filter{
+		referrers, _ := analyzer.Referrer(withSegment(r, &omisocial.Filter{
 			From:     time.Unix(from, 0),
 			To:       time.Unix(to, 0),
 			ClientID: site_id,
 			Limit:    int(page_size),
 			Offset:   int(offset),
-		})
+			Other:    r.URL.Query().Get("other") == "true",
+		}))
 
 		jData, _ := json.Marshal(&omisocial.ResponseReferrers{
 			Message:    "",
@@ -363,7 +515,7 @@ func main() {
 		w.Write(jData)
 	}))
 
-	http.Handle("/report/events", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	http.Handle("/report/events", segmentHandler(segments, func(w http.ResponseWriter, r *http.Request) {
 		analyzer := omisocial.NewAnalyzer(store)
 
 		from, _ := strconv.ParseInt(r.URL.Query().Get("from"), 10, 64)
@@ -381,11 +533,11 @@ func main() {
 			return
 		}
 
-		events, _ := analyzer.Events(&omisocial.Filter{
+		events, _ := analyzer.Events(withSegment(r, &omisocial.Filter{
 			From:     time.Unix(from, 0),
 			To:       time.Unix(to, 0),
 			ClientID: site_id,
-		})
+		}))
 
 		jData, _ := json.Marshal(&omisocial.ResponseEvents{
 			Message: "",
@@ -396,7 +548,7 @@ func main() {
 		w.Write(jData)
 	}))
 
-	http.Handle("/report/group-events", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	http.Handle("/report/group-events", segmentHandler(segments, func(w http.ResponseWriter, r *http.Request) {
 		analyzer := omisocial.NewAnalyzer(store)
 
 		from, _ := strconv.ParseInt(r.URL.Query().Get("from"), 10, 64)
@@ -404,8 +556,7 @@ func main() {
 		site_id, _ := strconv.ParseInt(r.URL.Query().Get("site_id"), 10, 64)
 		group_by := r.URL.Query().Get("group_by")
 
-		groups := []string{"day", "week", "month"}
-		if from == 0 || to == 0 || site_id == 0 || from > to || (group_by != "" && !omisocial.Contains(groups, group_by)) {
+		if from == 0 || to == 0 || site_id == 0 || from > to || (group_by != "" && !omisocial.Contains(omisocial.Periods, group_by)) {
 			jData, _ := json.Marshal(&omisocial.Response{
 				Message: "Invalid input data",
 				Error:   true,
@@ -417,17 +568,17 @@ func main() {
 		}
 
 		events, _ := analyzer.GroupEvents(
-			&omisocial.Filter{
+			withSegment(r, &omisocial.Filter{
 				From:     time.Unix(from, 0),
 				To:       time.Unix(to, 0),
 				ClientID: site_id,
-			},
+			}),
 			group_by,
 		)
 
-		group_events := map[string]omisocial.GroupEvents{}
+		group_events := map[int64]omisocial.GroupEvents{}
 		for _, event := range events {
-			if val, ok := group_events[event.Period]; ok {
+			if val, ok := group_events[event.Period.Unix()]; ok {
 				val.Events = append(
 					val.Events,
 					omisocial.GroupEvent{
@@ -437,7 +588,7 @@ func main() {
 						Sessions: event.Sessions,
 					},
 				)
-				group_events[val.Period] = val
+				group_events[val.Period.Unix()] = val
 			} else {
 				group_event := omisocial.GroupEvents{
 					Period: event.Period,
@@ -450,16 +601,18 @@ func main() {
 						},
 					},
 				}
-				group_events[event.Period] = group_event
+				group_events[event.Period.Unix()] = group_event
 			}
 		}
 
-		keys := []string{}
+		keys := []int64{}
 		for key := range group_events {
 			keys = append(keys, key)
 
 		}
-		sort.Strings(keys)
+		sort.Slice(keys, func(i, j int) bool {
+			return keys[i] < keys[j]
+		})
 
 		data := []omisocial.GroupEvents{}
 		for _, key := range keys {
@@ -475,7 +628,7 @@ func main() {
 		w.Write(jData)
 	}))
 
-	http.Handle("/report/over-time/", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	http.Handle("/report/over-time/", segmentHandler(segments, func(w http.ResponseWriter, r *http.Request) {
 		analyzer := omisocial.NewAnalyzer(store)
 
 		from, _ := strconv.ParseInt(r.URL.Query().Get("from"), 10, 64)
@@ -483,8 +636,7 @@ func main() {
 		site_id, _ := strconv.ParseInt(r.URL.Query().Get("site_id"), 10, 64)
 		group_by := r.URL.Query().Get("group_by")
 
-		groups := []string{"day", "week", "month"}
-		if from == 0 || to == 0 || site_id == 0 || from > to || (group_by != "" && !omisocial.Contains(groups, group_by)) {
+		if from == 0 || to == 0 || site_id == 0 || from > to || (group_by != "" && !omisocial.Contains(omisocial.Periods, group_by)) {
 			jData, _ := json.Marshal(&omisocial.Response{
 				Message: "Invalid input data",
 				Error:   true,
@@ -496,26 +648,26 @@ func main() {
 		}
 
 		visitors, _ := analyzer.Visitors(
-			&omisocial.Filter{
+			withSegment(r, &omisocial.Filter{
 				From:     time.Unix(from, 0),
 				To:       time.Unix(to, 0),
 				ClientID: site_id,
-			},
+			}),
 			group_by,
 		)
 
 		events, _ := analyzer.GroupEvents(
-			&omisocial.Filter{
+			withSegment(r, &omisocial.Filter{
 				From:     time.Unix(from, 0),
 				To:       time.Unix(to, 0),
 				ClientID: site_id,
-			},
+			}),
 			group_by,
 		)
 
-		group_events := map[string]omisocial.GroupEvents{}
+		group_events := map[int64]omisocial.GroupEvents{}
 		for _, event := range events {
-			if val, ok := group_events[event.Period]; ok {
+			if val, ok := group_events[event.Period.Unix()]; ok {
 				val.Events = append(
 					val.Events,
 					omisocial.GroupEvent{
@@ -525,7 +677,7 @@ func main() {
 						Sessions: event.Sessions,
 					},
 				)
-				group_events[val.Period] = val
+				group_events[val.Period.Unix()] = val
 			} else {
 				group_event := omisocial.GroupEvents{
 					Period: event.Period,
@@ -538,22 +690,24 @@ func main() {
 						},
 					},
 				}
-				group_events[event.Period] = group_event
+				group_events[event.Period.Unix()] = group_event
 			}
 		}
 
-		keys := []string{}
+		keys := []int64{}
 		for key := range group_events {
 			keys = append(keys, key)
 
 		}
-		sort.Strings(keys)
+		sort.Slice(keys, func(i, j int) bool {
+			return keys[i] < keys[j]
+		})
 
 		data := []omisocial.OverTime{}
 		for _, key := range keys {
 			visitor := 0
 			for _, item := range visitors {
-				if item.Period == group_events[key].Period {
+				if item.Period.Equal(group_events[key].Period) {
 					visitor = item.Visitors
 				}
 			}
@@ -576,7 +730,7 @@ func main() {
 		w.Write(jData)
 	}))
 
-	http.Handle("/report/utm-sources", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	http.Handle("/report/utm-sources", segmentHandler(segments, func(w http.ResponseWriter, r *http.Request) {
 		analyzer := omisocial.NewAnalyzer(store)
 
 		from, _ := strconv.ParseInt(r.URL.Query().Get("from"), 10, 64)
@@ -604,11 +758,11 @@ func main() {
 			page_size = 25
 		}
 
-		count, _ := analyzer.UTMSourceCount(&omisocial.Filter{
+		count, _ := analyzer.UTMSourceCount(withSegment(r, &omisocial.Filter{
 			From:     time.Unix(from, 0),
 			To:       time.Unix(to, 0),
 			ClientID: site_id,
-		})
+		}))
 
 		if count == 0 {
 			jData, _ := json.Marshal(&omisocial.ResponseUTMSources{
@@ -633,13 +787,14 @@ func main() {
 
 		offset := (int(page) - 1) * int(page_size)
 
-		sources, _ := analyzer.UTMSource(&omisocial.Filter{
+		sources, _ := analyzer.UTMSource(withSegment(r, &omisocial.Filter{
 			From:     time.Unix(from, 0),
 			To:       time.Unix(to, 0),
 			ClientID: site_id,
 			Limit:    int(page_size),
 			Offset:   int(offset),
-		})
+			Other:    r.URL.Query().Get("other") == "true",
+		}))
 
 		jData, _ := json.Marshal(&omisocial.ResponseUTMSources{
 			Message:    "",
@@ -654,7 +809,7 @@ func main() {
 		w.Write(jData)
 	}))
 
-	http.Handle("/report/otm-sources", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	http.Handle("/report/otm-sources", segmentHandler(segments, func(w http.ResponseWriter, r *http.Request) {
 		analyzer := omisocial.NewAnalyzer(store)
 
 		from, _ := strconv.ParseInt(r.URL.Query().Get("from"), 10, 64)
@@ -682,11 +837,11 @@ func main() {
 			page_size = 25
 		}
 
-		count, _ := analyzer.OTMSourceCount(&omisocial.Filter{
+		count, _ := analyzer.OTMSourceCount(withSegment(r, &omisocial.Filter{
 			From:     time.Unix(from, 0),
 			To:       time.Unix(to, 0),
 			ClientID: site_id,
-		})
+		}))
 
 		if count == 0 {
 			jData, _ := json.Marshal(&omisocial.ResponseOTMSources{
@@ -711,13 +866,13 @@ func main() {
 
 		offset := (int(page) - 1) * int(page_size)
 
-		sources, _ := analyzer.OTMSource(&omisocial.Filter{
+		sources, _ := analyzer.OTMSource(withSegment(r, &omisocial.Filter{
 			From:     time.Unix(from, 0),
 			To:       time.Unix(to, 0),
 			ClientID: site_id,
 			Limit:    int(page_size),
 			Offset:   int(offset),
-		})
+		}))
 
 		jData, _ := json.Marshal(&omisocial.ResponseOTMSources{
 			Message:    "",
@@ -732,9 +887,387 @@ func main() {
 		w.Write(jData)
 	}))
 
+	// Custom report for any combination of dimensions and metrics, like:
+	// /report/query?site_id=1&from=...&to=...&dimensions=country_code,browser&metrics=visitors,bounce_rate&sort=-visitors&limit=10&other=true&country_code=!us
+	http.Handle("/report/query", segmentHandler(segments, func(w http.ResponseWriter, r *http.Request) {
+		analyzer := omisocial.NewAnalyzer(store)
+		query := r.URL.Query()
+
+		from, _ := strconv.ParseInt(query.Get("from"), 10, 64)
+		to, _ := strconv.ParseInt(query.Get("to"), 10, 64)
+		site_id, _ := strconv.ParseInt(query.Get("site_id"), 10, 64)
+		limit, _ := strconv.ParseInt(query.Get("limit"), 10, 64)
+		offset, _ := strconv.ParseInt(query.Get("offset"), 10, 64)
+
+		if from == 0 || to == 0 || site_id == 0 || from > to || limit < 0 || offset < 0 {
+			jData, _ := json.Marshal(&omisocial.Response{
+				Message: "Invalid input data",
+				Error:   true,
+				Data:    nil,
+			})
+			w.Header().Set("Content-Type", "application/json")
+			w.Write(jData)
+			return
+		}
+
+		filter := withSegment(r, &omisocial.Filter{
+			From:        time.Unix(from, 0),
+			To:          time.Unix(to, 0),
+			ClientID:    site_id,
+			Limit:       int(limit),
+			Offset:      int(offset),
+			Other:       query.Get("other") == "true",
+			IncludeBots: query.Get("include_bots") == "true",
+		})
+
+		for _, name := range omisocial.QueryFilters() {
+			if value := query.Get(name); value != "" {
+				filter.SetQueryFilter(name, value)
+			}
+		}
+
+		options := omisocial.QueryOptions{
+			Dimensions: splitEnvList(query.Get("dimensions")),
+			Metrics:    splitEnvList(query.Get("metrics")),
+			Sort:       splitEnvList(query.Get("sort")),
+		}
+		stats, err := analyzer.Query(filter, options)
+
+		if err != nil {
+			jData, _ := json.Marshal(&omisocial.Response{
+				Message: err.Error(),
+				Error:   true,
+				Data:    nil,
+			})
+			w.Header().Set("Content-Type", "application/json")
+			w.Write(jData)
+			return
+		}
+
+		columns := append(options.Dimensions, options.Metrics...)
+
+		if filter.Other {
+			columns = append(columns, "other")
+		}
+
+		rows := make([]map[string]interface{}, 0, len(stats))
+
+		for i := range stats {
+			rows = append(rows, stats[i].Columns(columns))
+		}
+
+		jData, _ := json.Marshal(&omisocial.Response{
+			Message: "",
+			Error:   false,
+			Data:    rows,
+		})
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(jData)
+	}))
+
+	// Pages viewed right after or before a path, like:
+	// /report/journey?site_id=1&from=...&to=...&path=/pricing&direction=previous
+	http.Handle("/report/journey", segmentHandler(segments, func(w http.ResponseWriter, r *http.Request) {
+		analyzer := omisocial.NewAnalyzer(store)
+
+		from, _ := strconv.ParseInt(r.URL.Query().Get("from"), 10, 64)
+		to, _ := strconv.ParseInt(r.URL.Query().Get("to"), 10, 64)
+		site_id, _ := strconv.ParseInt(r.URL.Query().Get("site_id"), 10, 64)
+		direction := r.URL.Query().Get("direction")
+
+		if from == 0 || to == 0 || site_id == 0 || from > to || (direction != "" && direction != "next" && direction != "previous") {
+			jData, _ := json.Marshal(&omisocial.Response{
+				Message: "Invalid input data",
+				Error:   true,
+				Data:    nil,
+			})
+			w.Header().Set("Content-Type", "application/json")
+			w.Write(jData)
+			return
+		}
+
+		filter := withSegment(r, &omisocial.Filter{
+			From:     time.Unix(from, 0),
+			To:       time.Unix(to, 0),
+			ClientID: site_id,
+			Path:     r.URL.Query().Get("path"),
+		})
+		response := &omisocial.Response{
+			Message: "",
+			Error:   false,
+			Data:    nil,
+		}
+		var err error
+
+		if direction == "previous" {
+			response.Data, err = analyzer.PreviousPages(filter)
+		} else {
+			response.Data, err = analyzer.NextPages(filter)
+		}
+
+		if err != nil {
+			response.Message = err.Error()
+			response.Error = true
+			response.Data = nil
+		}
+
+		jData, _ := json.Marshal(response)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(jData)
+	}))
+
+	// Most common sequences of pages for Sankey diagrams, optionally starting on a path, like:
+	// /report/top-paths?site_id=1&from=...&to=...&steps=4&path=/pricing&limit=20
+	http.Handle("/report/top-paths", segmentHandler(segments, func(w http.ResponseWriter, r *http.Request) {
+		analyzer := omisocial.NewAnalyzer(store)
+
+		from, _ := strconv.ParseInt(r.URL.Query().Get("from"), 10, 64)
+		to, _ := strconv.ParseInt(r.URL.Query().Get("to"), 10, 64)
+		site_id, _ := strconv.ParseInt(r.URL.Query().Get("site_id"), 10, 64)
+		steps, _ := strconv.Atoi(r.URL.Query().Get("steps"))
+		limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+
+		if from == 0 || to == 0 || site_id == 0 || from > to || steps < 0 || limit < 0 {
+			jData, _ := json.Marshal(&omisocial.Response{
+				Message: "Invalid input data",
+				Error:   true,
+				Data:    nil,
+			})
+			w.Header().Set("Content-Type", "application/json")
+			w.Write(jData)
+			return
+		}
+
+		response := &omisocial.Response{
+			Message: "",
+			Error:   false,
+			Data:    nil,
+		}
+		paths, err := analyzer.TopPaths(withSegment(r, &omisocial.Filter{
+			From:     time.Unix(from, 0),
+			To:       time.Unix(to, 0),
+			ClientID: site_id,
+			Path:     r.URL.Query().Get("path"),
+			Limit:    limit,
+		}), steps)
+
+		if err != nil {
+			response.Message = err.Error()
+			response.Error = true
+		} else {
+			response.Data = paths
+		}
+
+		jData, _ := json.Marshal(response)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(jData)
+	}))
+
+	// Saved segments of a site, like:
+	// GET /segments?site_id=1 lists all segments
+	// POST /segments?site_id=1 with {"id": 0, "name": "...", "filter": {"Country": "vn", "Segments": [{"EventName": "purchase"}]}} creates or updates a segment
+	// DELETE /segments?site_id=1&id=... deletes a segment
+	// The segment_id query parameter applies a saved segment to all reports.
+	http.Handle("/segments", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		site_id, _ := strconv.ParseInt(r.URL.Query().Get("site_id"), 10, 64)
+
+		if site_id == 0 {
+			jData, _ := json.Marshal(&omisocial.Response{
+				Message: "Invalid input data",
+				Error:   true,
+				Data:    nil,
+			})
+			w.Header().Set("Content-Type", "application/json")
+			w.Write(jData)
+			return
+		}
+
+		response := &omisocial.Response{
+			Message: "",
+			Error:   false,
+			Data:    nil,
+		}
+		var err error
+
+		switch r.Method {
+		case http.MethodGet:
+			response.Data, err = segments.List(site_id)
+		case http.MethodPost:
+			segment := new(omisocial.SavedSegment)
+
+			if err = json.NewDecoder(r.Body).Decode(segment); err == nil {
+				segment.ClientID = site_id
+				err = segments.Save(segment)
+				response.Data = segment
+			}
+		case http.MethodDelete:
+			id, _ := strconv.ParseUint(r.URL.Query().Get("id"), 10, 64)
+			err = segments.Delete(site_id, id)
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+
+		if err != nil {
+			response.Message = err.Error()
+			response.Error = true
+			response.Data = nil
+		}
+
+		jData, _ := json.Marshal(response)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(jData)
+	}))
+
+	// Health check including the state of the GeoDB updates.
+	http.Handle("/health", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		health := make(map[string]interface{})
+
+		if geoDBUpdater != nil {
+			health["geodb"] = geoDBUpdater.Status()
+		}
+
+		jData, _ := json.Marshal(&omisocial.Response{
+			Message: "",
+			Error:   false,
+			Data:    health,
+		})
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(jData)
+	}))
+
+	// Reload the bot and referrer spam lists. The endpoint is disabled unless ADMIN_TOKEN is set.
+	http.Handle("/admin/reload-blacklists", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		adminToken := os.Getenv("ADMIN_TOKEN")
+
+		if r.Method != http.MethodPost || adminToken == "" || r.Header.Get("X-Admin-Token") != adminToken {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		response := &omisocial.Response{
+			Message: "",
+			Error:   false,
+			Data:    nil,
+		}
+
+		if err := blacklists.Reload(); err != nil {
+			response.Message = err.Error()
+			response.Error = true
+		}
+
+		jData, _ := json.Marshal(response)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(jData)
+	}))
+
+	// List User-Agents that look like bots but aren't blacklisted yet, for review. The endpoint is disabled unless ADMIN_TOKEN is set.
+	http.Handle("/admin/bot-candidates", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		adminToken := os.Getenv("ADMIN_TOKEN")
+
+		if adminToken == "" || r.Header.Get("X-Admin-Token") != adminToken {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		analyzer := omisocial.NewAnalyzer(store)
+
+		from, _ := strconv.ParseInt(r.URL.Query().Get("from"), 10, 64)
+		to, _ := strconv.ParseInt(r.URL.Query().Get("to"), 10, 64)
+		site_id, _ := strconv.ParseInt(r.URL.Query().Get("site_id"), 10, 64)
+		min_sessions, _ := strconv.Atoi(r.URL.Query().Get("min_sessions"))
+
+		if from == 0 || to == 0 || site_id == 0 || from > to {
+			jData, _ := json.Marshal(&omisocial.Response{
+				Message: "Invalid input data",
+				Error:   true,
+				Data:    nil,
+			})
+			w.Header().Set("Content-Type", "application/json")
+			w.Write(jData)
+			return
+		}
+
+		response := &omisocial.Response{
+			Message: "",
+			Error:   false,
+			Data:    nil,
+		}
+
+		candidates, err := analyzer.BotCandidates(&omisocial.Filter{
+			From:     time.Unix(from, 0),
+			To:       time.Unix(to, 0),
+			ClientID: site_id,
+		}, &omisocial.BotMiningConfig{
+			MinSessions: min_sessions,
+		})
+
+		if err != nil {
+			response.Message = err.Error()
+			response.Error = true
+		} else {
+			response.Data = candidates
+		}
+
+		jData, _ := json.Marshal(response)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(jData)
+	}))
+
 	// And finally, start the server.
 	// We don't flush hits on shutdown but you should add that in a real application by calling Tracker.Flush().
 	log.Println("Starting server on port 8080...")
 	http.ListenAndServe(":8080", nil)
 
 }
+
+func splitEnvList(value string) []string {
+	var list []string
+
+	for _, entry := range strings.Split(value, ",") {
+		if entry = strings.TrimSpace(entry); entry != "" {
+			list = append(list, entry)
+		}
+	}
+
+	return list
+}
+
+type segmentContextKey struct{}
+
+// segmentHandler loads the saved segment for the segment_id and site_id query parameters, so that it can be applied to the filter using withSegment.
+func segmentHandler(segments *omisocial.Segments, handler func(http.ResponseWriter, *http.Request)) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if segment_id := r.URL.Query().Get("segment_id"); segment_id != "" {
+			id, _ := strconv.ParseUint(segment_id, 10, 64)
+			site_id, _ := strconv.ParseInt(r.URL.Query().Get("site_id"), 10, 64)
+			segment, err := segments.Get(site_id, id)
+
+			if err != nil {
+				jData, _ := json.Marshal(&omisocial.Response{
+					Message: err.Error(),
+					Error:   true,
+					Data:    nil,
+				})
+				w.Header().Set("Content-Type", "application/json")
+				w.Write(jData)
+				return
+			}
+
+			r = r.WithContext(context.WithValue(r.Context(), segmentContextKey{}, segment))
+		}
+
+		handler(w, r)
+	})
+}
+
+// withSegment applies the saved segment loaded by segmentHandler to the filter.
+func withSegment(r *http.Request, filter *omisocial.Filter) *omisocial.Filter {
+	if segment, ok := r.Context().Value(segmentContextKey{}).(*omisocial.SavedSegment); ok {
+		if err := segment.Apply(filter); err != nil {
+			log.Printf("Error applying segment %d: %s", segment.ID, err)
+		}
+	}
+
+	return filter
+}
@@ -7,6 +7,43 @@ import (
 	"time"
 )
 
+const (
+	// PeriodMinute groups time series by minute, which is useful for realtime statistics.
+	// Ranges longer than a week are grouped by hour instead.
+	PeriodMinute = "minute"
+
+	// PeriodHour groups time series by hour.
+	// Ranges longer than a year are grouped by day instead.
+	PeriodHour = "hour"
+
+	// PeriodDay groups time series by day.
+	PeriodDay = "day"
+
+	// PeriodWeek groups time series by week, starting on Monday.
+	PeriodWeek = "week"
+
+	// PeriodMonth groups time series by month.
+	PeriodMonth = "month"
+
+	// PeriodQuarter groups time series by quarter.
+	PeriodQuarter = "quarter"
+
+	// PeriodYear groups time series by year.
+	PeriodYear = "year"
+)
+
+// Periods are all periods time series can be grouped by.
+// Months, quarters, and years are filled using WITH FILL ... STEP INTERVAL, which requires ClickHouse 21.11 or later.
+var Periods = []string{
+	PeriodMinute,
+	PeriodHour,
+	PeriodDay,
+	PeriodWeek,
+	PeriodMonth,
+	PeriodQuarter,
+	PeriodYear,
+}
+
 var (
 	// ErrNoPeriodOrDay is returned in case no period or day was specified to calculate the growth rate.
 	ErrNoPeriodOrDay = errors.New("no period or day specified")
@@ -131,16 +168,13 @@ func (analyzer *Analyzer) TotalVisitors(filter *Filter) (*TotalVisitorStats, err
 	return stats, nil
 }
 
-// Visitors returns the visitor count, session count, bounce rate, and views grouped by day.
+// Visitors returns the visitor count, session count, bounce rate, and views grouped by given period (like PeriodWeek).
+// Each entry is labeled with the start of the period and the time series is grouped by day in case the period is empty or unknown.
+// In case Filter.Compare is set, each entry contains the entry at the same position of the comparison period.
 func (analyzer *Analyzer) Visitors(filter *Filter, group_by string) ([]VisitorStats, error) {
-	group_by_field := fieldDay
-	if group_by == "week" {
-		group_by_field = fieldWeek
-	}
-	if group_by == "month" {
-		group_by_field = fieldMonth
-	}
-	args, query := buildQuery(analyzer.getFilter(filter), []field{
+	filter = analyzer.getFilter(filter)
+	group_by_field := periodField(filter, group_by)
+	args, query := buildQuery(filter, []field{
 		group_by_field,
 		fieldVisitors,
 		fieldSessions,
@@ -160,6 +194,44 @@ func (analyzer *Analyzer) Visitors(filter *Filter, group_by string) ([]VisitorSt
 		return nil, err
 	}
 
+	if previous := filter.comparisonFilter(); previous != nil {
+		previousStats, err := analyzer.Visitors(previous, group_by)
+
+		if err != nil {
+			return nil, err
+		}
+
+		for i := range stats {
+			if i < len(previousStats) {
+				stats[i].Previous = &previousStats[i]
+			}
+		}
+	}
+
+	return stats, nil
+}
+
+// NewVisitors returns the new and returning visitor count grouped by given period (like PeriodWeek).
+// Visitors are considered returning if they had a session within the HitOptions.ReturningVisitorMaxAge before their session started.
+func (analyzer *Analyzer) NewVisitors(filter *Filter, period string) ([]NewVisitorStats, error) {
+	filter = analyzer.getFilter(filter)
+	periodGroup := periodField(filter, period)
+	args, query := buildQuery(filter, []field{
+		periodGroup,
+		fieldVisitors,
+		fieldNewVisitors,
+		fieldReturningVisitors,
+	}, []field{
+		periodGroup,
+	}, []field{
+		periodGroup,
+	})
+	var stats []NewVisitorStats
+
+	if err := analyzer.store.Select(&stats, query, args...); err != nil {
+		return nil, err
+	}
+
 	return stats, nil
 }
 
@@ -168,6 +240,7 @@ func (analyzer *Analyzer) PlatformVisitors(filter *Filter) ([]PlatformVisitorSta
 	args, query := buildQuery(analyzer.getFilter(filter), []field{
 		fieldDesktop,
 		fieldMobile,
+		fieldDeviceType,
 		fieldVisitors,
 		fieldSessions,
 		fieldViews,
@@ -176,10 +249,12 @@ func (analyzer *Analyzer) PlatformVisitors(filter *Filter) ([]PlatformVisitorSta
 	}, []field{
 		fieldDesktop,
 		fieldMobile,
+		fieldDeviceType,
 	}, []field{
 		fieldDesktop,
 		fieldMobile,
 		fieldVisitors,
+		fieldDeviceType,
 	})
 
 	var stats []PlatformVisitorStats
@@ -192,7 +267,7 @@ func (analyzer *Analyzer) PlatformVisitors(filter *Filter) ([]PlatformVisitorSta
 }
 
 // Growth returns the growth rate for visitor count, session count, bounces, views, and average session duration or average time on page (if path is set).
-// The growth rate is relative to the previous time range or day.
+// The growth rate is relative to the period set by Filter.Compare, or the previous time range or day if not set.
 // The period or day for the filter must be set, else an error is returned.
 func (analyzer *Analyzer) Growth(filter *Filter) (*Growth, error) {
 	filter = analyzer.getFilter(filter)
@@ -220,7 +295,7 @@ func (analyzer *Analyzer) Growth(filter *Filter) (*Growth, error) {
 
 	if filter.EventName != "" {
 		currentTimeSpent, err = analyzer.totalEventDuration(filter)
-	} else if filter.Path == "" {
+	} else if !filter.pageFilter() {
 		currentTimeSpent, err = analyzer.totalSessionDuration(filter)
 	} else {
 		currentTimeSpent, err = analyzer.totalTimeOnPage(filter)
@@ -230,12 +305,16 @@ func (analyzer *Analyzer) Growth(filter *Filter) (*Growth, error) {
 		return nil, err
 	}
 
-	if filter.Day.IsZero() {
-		days := filter.To.Sub(filter.From)
-		filter.To = filter.From.Add(-time.Hour * 24)
-		filter.From = filter.To.Add(-days)
-	} else {
-		filter.Day = filter.Day.Add(-time.Hour * 24)
+	compare := filter.Compare
+
+	if compare == "" {
+		compare = ComparePrevious
+	}
+
+	filter = filter.comparePeriod(compare)
+
+	if filter == nil {
+		return nil, ErrNoPeriodOrDay
 	}
 
 	args, query = buildQuery(filter, fields, nil, nil)
@@ -249,7 +328,7 @@ func (analyzer *Analyzer) Growth(filter *Filter) (*Growth, error) {
 
 	if filter.EventName != "" {
 		previousTimeSpent, err = analyzer.totalEventDuration(filter)
-	} else if filter.Path == "" {
+	} else if !filter.pageFilter() {
 		previousTimeSpent, err = analyzer.totalSessionDuration(filter)
 	} else {
 		previousTimeSpent, err = analyzer.totalTimeOnPage(filter)
@@ -287,6 +366,66 @@ func (analyzer *Analyzer) VisitorHours(filter *Filter) ([]VisitorHourStats, erro
 	return stats, nil
 }
 
+// TrafficHeatmap returns the visitor, session, and event count grouped by day of week and time of day in the timezone of the filter.
+// The result always contains all 168 cells, ordered by weekday (starting on Monday) and hour.
+func (analyzer *Analyzer) TrafficHeatmap(filter *Filter) ([]TrafficHeatmapStats, error) {
+	filter = analyzer.getFilter(filter)
+	filter.Compare = ""
+	filter.Limit = 0
+	filter.Offset = 0
+
+	// the hours are filled for each weekday below
+	hour := fieldHour
+	hour.queryWithFill = ""
+	groupBy := []field{fieldWeekday, hour}
+	args, query := buildQuery(filter, []field{
+		fieldWeekday,
+		hour,
+		fieldVisitors,
+		fieldSessions,
+	}, groupBy, groupBy)
+	var visitors []TrafficHeatmapStats
+
+	if err := analyzer.store.Select(&visitors, query, args...); err != nil {
+		return nil, err
+	}
+
+	eventFilter := *filter
+	eventFilter.eventFilter = true
+	args, query = buildQuery(&eventFilter, []field{
+		fieldWeekday,
+		hour,
+		fieldEvents,
+	}, groupBy, groupBy)
+	var events []TrafficHeatmapStats
+
+	if err := analyzer.store.Select(&events, query, args...); err != nil {
+		return nil, err
+	}
+
+	stats := make([]TrafficHeatmapStats, 7*24)
+
+	for i := range stats {
+		stats[i].Weekday = i/24 + 1
+		stats[i].Hour = i % 24
+	}
+
+	for _, cell := range visitors {
+		if i := heatmapCell(cell); i >= 0 {
+			stats[i].Visitors = cell.Visitors
+			stats[i].Sessions = cell.Sessions
+		}
+	}
+
+	for _, cell := range events {
+		if i := heatmapCell(cell); i >= 0 {
+			stats[i].Events = cell.Events
+		}
+	}
+
+	return stats, nil
+}
+
 // Pages returns the visitor count, session count, bounce rate, views, and average time on page grouped by path and (optional) page title.
 func (analyzer *Analyzer) Pages(filter *Filter) ([]PageStats, error) {
 	filter = analyzer.getFilter(filter)
@@ -421,6 +560,27 @@ func (analyzer *Analyzer) EntryPages(filter *Filter) ([]EntryStats, error) {
 		}
 	}
 
+	if previous := filter.comparisonFilter(); previous != nil {
+		previousTotal, err := analyzer.totalVisitorsSessions(previous, paths)
+
+		if err != nil {
+			return nil, err
+		}
+
+		for i := range stats {
+			previousVisitors := 0
+
+			for j := range previousTotal {
+				if stats[i].Path == previousTotal[j].Path {
+					previousVisitors = previousTotal[j].Visitors
+					break
+				}
+			}
+
+			analyzer.compareVisitors(&stats[i].ComparisonStats, stats[i].Visitors, previousVisitors)
+		}
+	}
+
 	if filter.IncludeTimeOnPage {
 		top, err := analyzer.avgTimeOnPage(filter, paths)
 
@@ -497,6 +657,70 @@ func (analyzer *Analyzer) ExitPages(filter *Filter) ([]ExitStats, error) {
 		}
 	}
 
+	if previous := filter.comparisonFilter(); previous != nil {
+		previousTotal, err := analyzer.totalVisitorsSessions(previous, paths)
+
+		if err != nil {
+			return nil, err
+		}
+
+		for i := range stats {
+			previousVisitors := 0
+
+			for j := range previousTotal {
+				if stats[i].Path == previousTotal[j].Path {
+					previousVisitors = previousTotal[j].Visitors
+					break
+				}
+			}
+
+			analyzer.compareVisitors(&stats[i].ComparisonStats, stats[i].Visitors, previousVisitors)
+		}
+	}
+
+	return stats, nil
+}
+
+// SearchTerms returns the number of searches, visitors, sessions, and exits grouped by site search term.
+// Search terms are extracted from page URLs using the HitOptions.SearchQueryParams.
+func (analyzer *Analyzer) SearchTerms(filter *Filter) ([]SearchTermStats, error) {
+	filter = analyzer.getFilter(filter)
+	args, searchQuery := analyzer.siteSearchQuery(filter)
+	query := fmt.Sprintf(`SELECT search_term,
+		count(*) searches,
+		uniq(visitor_id) visitors,
+		uniq(visitor_id, session_id) sessions,
+		countIf(is_exit) exits,
+		exits / searches exit_rate
+		FROM (%s)
+		GROUP BY search_term
+		ORDER BY searches DESC, search_term
+		%s%s`, searchQuery, filter.withLimit(), filter.withOffset())
+	var stats []SearchTermStats
+
+	if err := analyzer.store.Select(&stats, query, args...); err != nil {
+		return nil, err
+	}
+
+	return stats, nil
+}
+
+// SiteSearch returns the total number of searches, sessions using the site search, searches per session, and search exits.
+func (analyzer *Analyzer) SiteSearch(filter *Filter) (*SiteSearchStats, error) {
+	filter = analyzer.getFilter(filter)
+	args, searchQuery := analyzer.siteSearchQuery(filter)
+	query := fmt.Sprintf(`SELECT count(*) searches,
+		uniq(visitor_id, session_id) sessions,
+		if(sessions = 0, 0, searches / sessions) searches_per_session,
+		countIf(is_exit) exits,
+		if(searches = 0, 0, exits / searches) exit_rate
+		FROM (%s)`, searchQuery)
+	stats := new(SiteSearchStats)
+
+	if err := analyzer.store.Get(stats, query, args...); err != nil {
+		return nil, err
+	}
+
 	return stats, nil
 }
 
@@ -553,30 +777,28 @@ func (analyzer *Analyzer) Events(filter *Filter) ([]EventStats, error) {
 		return nil, err
 	}
 
+	if previous := filter.comparisonFilter(); previous != nil {
+		previousStats, err := analyzer.Events(previous)
+
+		if err != nil {
+			return nil, err
+		}
+
+		analyzer.compareEvents(stats, previousStats)
+	}
+
 	return stats, nil
 }
 
-// GroupEvents returns the visitor count, views, and conversion rate for events group by group_by.
+// GroupEvents returns the visitor count, views, and sessions for events grouped by given period (like PeriodWeek) and event name.
+// Each entry is labeled with the start of the period and the events are grouped by day in case the period is empty or unknown.
 func (analyzer *Analyzer) GroupEvents(filter *Filter, group_by string) ([]GroupEventStats, error) {
 	filter = analyzer.getFilter(filter)
 	filter.eventFilter = true
 	filterArgs, outerFilterQuery := filter.query()
-	fillArgs := []interface{}{filter.From, filter.To}
+	period := periodField(filter, group_by)
+	fillArgs, fillQuery := fieldWithFill(filter, period)
 	filterArgs = append(filterArgs, fillArgs...)
-
-	if group_by == "" {
-		group_by = "day"
-	}
-	group_field := map[string]string{
-		"day":   fmt.Sprintf(`toDate(time, '%s')`, filter.Timezone.String()),
-		"week":  fmt.Sprintf(`toWeek(time, 9, '%s')`, filter.Timezone.String()),
-		"month": fmt.Sprintf(`toMonth(time, '%s')`, filter.Timezone.String()),
-	}
-	group_fill := map[string]string{
-		"day":   fmt.Sprintf(`toDate(?, '%s')`, filter.Timezone.String()),
-		"week":  fmt.Sprintf(`toWeek(?, 9, '%s')`, filter.Timezone.String()),
-		"month": fmt.Sprintf(`toMonth(?, '%s')`, filter.Timezone.String()),
-	}
 	query := fmt.Sprintf(`SELECT %s period, event_name,
 		count(DISTINCT visitor_id) visitors,
 		count(DISTINCT session_id) sessions,
@@ -584,9 +806,9 @@ func (analyzer *Analyzer) GroupEvents(filter *Filter, group_by string) ([]GroupE
 		FROM event
 		WHERE %s
 		GROUP BY period, event_name
-		ORDER BY period ASC WITH FILL FROM %s TO %s, visitors DESC, sessions DESC, event_name DESC
-		%s`, group_field[group_by], outerFilterQuery,
-		group_fill[group_by], group_fill[group_by],
+		ORDER BY period ASC %s, visitors DESC, sessions DESC, event_name DESC
+		%s`, fmt.Sprintf(period.queryPageViews, filter.Timezone.String()), outerFilterQuery,
+		fillQuery,
 		filter.withLimit(),
 	)
 	var stats []GroupEventStats
@@ -634,6 +856,16 @@ func (analyzer *Analyzer) EventBreakdown(filter *Filter) ([]EventStats, error) {
 		return nil, err
 	}
 
+	if previous := filter.comparisonFilter(); previous != nil {
+		previousStats, err := analyzer.EventBreakdown(previous)
+
+		if err != nil {
+			return nil, err
+		}
+
+		analyzer.compareEvents(stats, previousStats)
+	}
+
 	return stats, nil
 }
 
@@ -688,18 +920,37 @@ func (analyzer *Analyzer) ReferrerCount(filter *Filter) (int, error) {
 	return count, err
 }
 
-// Platform returns the visitor count grouped by platform.
+// Channels returns the visitor count grouped by traffic channel.
+func (analyzer *Analyzer) Channels(filter *Filter) ([]ChannelStats, error) {
+	var stats []ChannelStats
+
+	if err := analyzer.selectByAttribute(&stats, filter, fieldChannel); err != nil {
+		return nil, err
+	}
+
+	return stats, nil
+}
+
+// Platform returns the visitor count grouped by platform and device type.
+// Note that the device types are a more granular split, tablets for example are counted as mobile platform too.
+// Sessions flagged as bot traffic are counted as DeviceBot, which requires Filter.IncludeBots to be set.
 func (analyzer *Analyzer) Platform(filter *Filter) (*PlatformStats, error) {
 	filter = analyzer.getFilter(filter)
 	table := filter.table()
 	filterArgs, filterQuery := filter.query()
-	args := make([]interface{}, 0, len(filterArgs)*4)
+	args := make([]interface{}, 0, len(filterArgs)*(4+len(deviceTypes)))
 	var query strings.Builder
 
 	if table == "session" {
 		query.WriteString(`SELECT sum(desktop*sign) platform_desktop,
 			sum(mobile*sign) platform_mobile,
 			sum(sign)-platform_desktop-platform_mobile platform_unknown, `)
+
+		for _, deviceType := range deviceTypes {
+			query.WriteString(fmt.Sprintf(`sum((device_type = '%s')*sign) device_%s, `, deviceType, deviceType))
+		}
+
+		query.WriteString(fmt.Sprintf(`sum(sign)-%s device_unknown, `, strings.Join(deviceTypeFields(), "-")))
 	} else {
 		args = append(args, filterArgs...)
 		args = append(args, filterArgs...)
@@ -725,16 +976,37 @@ func (analyzer *Analyzer) Platform(filter *Filter) (*PlatformStats, error) {
 				AND desktop = 0
 				AND mobile = 0
 			) platform_unknown, `, filterQuery, filterQuery, filterQuery))
+
+		for i, name := range append(deviceTypeFields(), "device_unknown") {
+			deviceType := ""
+
+			if i < len(deviceTypes) {
+				deviceType = deviceTypes[i]
+			}
+
+			args = append(args, filterArgs...)
+			query.WriteString(fmt.Sprintf(`(
+				SELECT uniq(visitor_id)
+				FROM event
+				WHERE %s
+				AND device_type = '%s'
+			) %s, `, filterQuery, deviceType, name))
+		}
 	}
 
 	query.WriteString(`"platform_desktop" / IF("platform_desktop" + "platform_mobile" + "platform_unknown" = 0, 1, "platform_desktop" + "platform_mobile" + "platform_unknown") AS relative_platform_desktop,
 		"platform_mobile" / IF("platform_desktop" + "platform_mobile" + "platform_unknown" = 0, 1, "platform_desktop" + "platform_mobile" + "platform_unknown") AS relative_platform_mobile,
 		"platform_unknown" / IF("platform_desktop" + "platform_mobile" + "platform_unknown" = 0, 1, "platform_desktop" + "platform_mobile" + "platform_unknown") AS relative_platform_unknown `)
+	total := strings.Join(append(deviceTypeFields(), "device_unknown"), "+")
+
+	for _, name := range append(deviceTypeFields(), "device_unknown") {
+		query.WriteString(fmt.Sprintf(`, "%s" / IF(%s = 0, 1, %s) AS relative_%s `, name, total, total, name))
+	}
 
 	if table == "session" {
 		query.WriteString(`FROM session s `)
 
-		if filter.Path != "" || filter.PathPattern != "" {
+		if filter.pageFilter() {
 			entryPath, exitPath, eventName := filter.EntryPath, filter.ExitPath, filter.EventName
 			filter.EntryPath, filter.ExitPath, filter.EventName = "", "", ""
 			innerFilterArgs, innerFilterQuery := filter.query()
@@ -743,7 +1015,8 @@ func (analyzer *Analyzer) Platform(filter *Filter) (*PlatformStats, error) {
 			query.WriteString(fmt.Sprintf(`INNER JOIN (
 			SELECT visitor_id,
 			session_id,
-			path
+			path,
+			title
 			FROM page_view
 			WHERE %s
 		) v
@@ -771,6 +1044,14 @@ func (analyzer *Analyzer) Languages(filter *Filter) ([]LanguageStats, error) {
 		return nil, err
 	}
 
+	if filter != nil && filter.Locale != "" {
+		l := getLocalizer()
+
+		for i := range stats {
+			stats[i].LanguageName = l.LanguageName(stats[i].Language, filter.Locale)
+		}
+	}
+
 	return stats, nil
 }
 
@@ -782,14 +1063,177 @@ func (analyzer *Analyzer) Countries(filter *Filter) ([]CountryStats, error) {
 		return nil, err
 	}
 
+	if filter != nil && filter.Locale != "" {
+		l := getLocalizer()
+
+		for i := range stats {
+			stats[i].CountryName = l.CountryName(stats[i].CountryCode, filter.Locale)
+		}
+	}
+
 	return stats, nil
 }
 
 // Cities returns the visitor count grouped by city.
 func (analyzer *Analyzer) Cities(filter *Filter) ([]CityStats, error) {
+	args, query := buildQuery(analyzer.getFilter(filter), []field{
+		fieldCity,
+		fieldCityGeoNameID,
+		fieldVisitors,
+		fieldRelativeVisitors,
+	}, []field{
+		fieldCity,
+	}, []field{
+		fieldVisitors,
+		fieldCity,
+	})
 	var stats []CityStats
 
-	if err := analyzer.selectByAttribute(&stats, filter, fieldCity); err != nil {
+	if err := analyzer.store.Select(&stats, query, args...); err != nil {
+		return nil, err
+	}
+
+	if filter != nil && filter.Locale != "" {
+		l := getLocalizer()
+
+		for i := range stats {
+			stats[i].CityName = analyzer.cityName(l, stats[i].GeoNameID, stats[i].City, filter.Locale)
+		}
+	}
+
+	return stats, nil
+}
+
+// Regions returns the visitor count grouped by country and region.
+func (analyzer *Analyzer) Regions(filter *Filter) ([]RegionStats, error) {
+	args, query := buildQuery(analyzer.getFilter(filter), []field{
+		fieldCountry,
+		fieldRegion,
+		fieldVisitors,
+		fieldRelativeVisitors,
+	}, []field{
+		fieldCountry,
+		fieldRegion,
+	}, []field{
+		fieldVisitors,
+		fieldCountry,
+		fieldRegion,
+	})
+	var stats []RegionStats
+
+	if err := analyzer.store.Select(&stats, query, args...); err != nil {
+		return nil, err
+	}
+
+	if filter != nil && filter.Locale != "" {
+		l := getLocalizer()
+
+		for i := range stats {
+			stats[i].CountryName = l.CountryName(stats[i].CountryCode, filter.Locale)
+		}
+	}
+
+	return stats, nil
+}
+
+// PostalCodes returns the visitor count grouped by country and postal code.
+func (analyzer *Analyzer) PostalCodes(filter *Filter) ([]PostalCodeStats, error) {
+	args, query := buildQuery(analyzer.getFilter(filter), []field{
+		fieldCountry,
+		fieldPostalCode,
+		fieldVisitors,
+		fieldRelativeVisitors,
+	}, []field{
+		fieldCountry,
+		fieldPostalCode,
+	}, []field{
+		fieldVisitors,
+		fieldCountry,
+		fieldPostalCode,
+	})
+	var stats []PostalCodeStats
+
+	if err := analyzer.store.Select(&stats, query, args...); err != nil {
+		return nil, err
+	}
+
+	if filter != nil && filter.Locale != "" {
+		l := getLocalizer()
+
+		for i := range stats {
+			stats[i].CountryName = l.CountryName(stats[i].CountryCode, filter.Locale)
+		}
+	}
+
+	return stats, nil
+}
+
+// Locations returns the visitor count grouped by country and city, including the coordinates of the city.
+// The coordinates are approximate and can be used to display the visitors on a map.
+func (analyzer *Analyzer) Locations(filter *Filter) ([]LocationStats, error) {
+	args, query := buildQuery(analyzer.getFilter(filter), []field{
+		fieldCountry,
+		fieldCity,
+		fieldCityGeoNameID,
+		fieldLatitude,
+		fieldLongitude,
+		fieldVisitors,
+		fieldRelativeVisitors,
+	}, []field{
+		fieldCountry,
+		fieldCity,
+	}, []field{
+		fieldVisitors,
+		fieldCountry,
+		fieldCity,
+	})
+	var stats []LocationStats
+
+	if err := analyzer.store.Select(&stats, query, args...); err != nil {
+		return nil, err
+	}
+
+	if filter != nil && filter.Locale != "" {
+		l := getLocalizer()
+
+		for i := range stats {
+			stats[i].CountryName = l.CountryName(stats[i].CountryCode, filter.Locale)
+			stats[i].CityName = analyzer.cityName(l, stats[i].GeoNameID, stats[i].City, filter.Locale)
+		}
+	}
+
+	return stats, nil
+}
+
+// TimeZones returns the visitor count grouped by the time zone of their location.
+func (analyzer *Analyzer) TimeZones(filter *Filter) ([]TimeZoneStats, error) {
+	var stats []TimeZoneStats
+
+	if err := analyzer.selectByAttribute(&stats, filter, fieldTimeZone); err != nil {
+		return nil, err
+	}
+
+	return stats, nil
+}
+
+// ASNs returns the visitor count grouped by autonomous system number and organization.
+func (analyzer *Analyzer) ASNs(filter *Filter) ([]ASNStats, error) {
+	args, query := buildQuery(analyzer.getFilter(filter), []field{
+		fieldASN,
+		fieldASOrganization,
+		fieldVisitors,
+		fieldRelativeVisitors,
+	}, []field{
+		fieldASN,
+		fieldASOrganization,
+	}, []field{
+		fieldVisitors,
+		fieldASN,
+		fieldASOrganization,
+	})
+	var stats []ASNStats
+
+	if err := analyzer.store.Select(&stats, query, args...); err != nil {
 		return nil, err
 	}
 
@@ -818,6 +1262,74 @@ func (analyzer *Analyzer) OS(filter *Filter) ([]OSStats, error) {
 	return stats, nil
 }
 
+// Devices returns the visitor count grouped by device vendor and model.
+func (analyzer *Analyzer) Devices(filter *Filter) ([]DeviceStats, error) {
+	args, query := buildQuery(analyzer.getFilter(filter), []field{
+		fieldDeviceVendor,
+		fieldDeviceModel,
+		fieldVisitors,
+		fieldRelativeVisitors,
+	}, []field{
+		fieldDeviceVendor,
+		fieldDeviceModel,
+	}, []field{
+		fieldVisitors,
+		fieldDeviceVendor,
+		fieldDeviceModel,
+	})
+	var stats []DeviceStats
+
+	if err := analyzer.store.Select(&stats, query, args...); err != nil {
+		return nil, err
+	}
+
+	return stats, nil
+}
+
+// Bots returns the number of requests and visitors filtered as bot traffic, grouped by reason, User-Agent, and country.
+// The BotReason, Country, and Path can be used to filter the results.
+func (analyzer *Analyzer) Bots(filter *Filter) ([]BotStats, error) {
+	filter = analyzer.getFilter(filter)
+	args, timeQuery := filter.queryTime()
+	queryFields := make([]string, 0, 3)
+	filter.appendQuery(&queryFields, &args, "bot_reason", filter.BotReason)
+	filter.appendQuery(&queryFields, &args, "country_code", filter.Country)
+	filter.appendQuery(&queryFields, &args, "path", filter.Path)
+
+	if len(queryFields) > 0 {
+		timeQuery += "AND " + strings.Join(queryFields, "AND ")
+	}
+
+	query := fmt.Sprintf(`SELECT bot_reason,
+		user_agent,
+		country_code,
+		count(*) hits,
+		uniq(visitor_id) visitors
+		FROM bot
+		WHERE %s
+		GROUP BY bot_reason, user_agent, country_code
+		ORDER BY hits DESC, bot_reason, user_agent, country_code
+		%s%s`, timeQuery, filter.withLimit(), filter.withOffset())
+	var stats []BotStats
+
+	if err := analyzer.store.Select(&stats, query, args...); err != nil {
+		return nil, err
+	}
+
+	return stats, nil
+}
+
+// WebView returns the visitor count grouped by whether the site was visited from an in-app browser or embedded webview.
+func (analyzer *Analyzer) WebView(filter *Filter) ([]WebViewStats, error) {
+	var stats []WebViewStats
+
+	if err := analyzer.selectByAttribute(&stats, filter, fieldWebView); err != nil {
+		return nil, err
+	}
+
+	return stats, nil
+}
+
 // ScreenClass returns the visitor count grouped by screen class.
 func (analyzer *Analyzer) ScreenClass(filter *Filter) ([]ScreenClassStats, error) {
 	var stats []ScreenClassStats
@@ -969,8 +1481,9 @@ func (analyzer *Analyzer) BrowserVersion(filter *Filter) ([]BrowserVersionStats,
 	return stats, nil
 }
 
-// AvgSessionDuration returns the average session duration grouped by day.
-func (analyzer *Analyzer) AvgSessionDuration(filter *Filter) ([]TimeSpentStats, error) {
+// AvgSessionDuration returns the average session duration grouped by given period (like PeriodWeek).
+// The Period of each entry is the start of the period and the durations are grouped by day in case the period is empty or unknown.
+func (analyzer *Analyzer) AvgSessionDuration(filter *Filter, period string) ([]TimeSpentStats, error) {
 	filter = analyzer.getFilter(filter)
 
 	if filter.table() == "event" {
@@ -979,19 +1492,21 @@ func (analyzer *Analyzer) AvgSessionDuration(filter *Filter) ([]TimeSpentStats,
 
 	filterArgs, filterQuery := filter.query()
 	innerFilterArgs, innerFilterQuery := filter.queryTime()
-	withFillArgs, withFillQuery := filter.withFill()
+	periodGroup := periodField(filter, period)
+	withFillArgs, withFillQuery := fieldWithFill(filter, periodGroup)
 	args := make([]interface{}, 0, len(filterArgs)+len(innerFilterArgs)+len(withFillArgs))
 	var query strings.Builder
-	query.WriteString(`SELECT toDate(time) day,
+	query.WriteString(fmt.Sprintf(`SELECT %s period,
 		ifNull(toUInt64(avg(nullIf(duration_seconds, 0)*sign)), 0) average_time_spent_seconds
-		FROM session s `)
+		FROM session s `, fmt.Sprintf(periodGroup.querySessions, filter.Timezone.String())))
 
-	if filter.Path != "" || filter.PathPattern != "" {
+	if filter.pageFilter() {
 		args = append(args, innerFilterArgs...)
 		query.WriteString(fmt.Sprintf(`INNER JOIN (
 			SELECT visitor_id,
 			session_id,
-			path
+			path,
+			title
 			FROM page_view
 			WHERE %s
 		) v
@@ -1002,8 +1517,8 @@ func (analyzer *Analyzer) AvgSessionDuration(filter *Filter) ([]TimeSpentStats,
 	args = append(args, withFillArgs...)
 	query.WriteString(fmt.Sprintf(`WHERE %s
 		AND duration_seconds != 0
-		GROUP BY day
-		ORDER BY day
+		GROUP BY period
+		ORDER BY period
 		%s`, filterQuery, withFillQuery))
 	var stats []TimeSpentStats
 
@@ -1014,8 +1529,9 @@ func (analyzer *Analyzer) AvgSessionDuration(filter *Filter) ([]TimeSpentStats,
 	return stats, nil
 }
 
-// AvgTimeOnPage returns the average time on page grouped by day.
-func (analyzer *Analyzer) AvgTimeOnPage(filter *Filter) ([]TimeSpentStats, error) {
+// AvgTimeOnPage returns the average time on page grouped by given period (like PeriodWeek).
+// The Period of each entry is the start of the period and the time on page is grouped by day in case the period is empty or unknown.
+func (analyzer *Analyzer) AvgTimeOnPage(filter *Filter, period string) ([]TimeSpentStats, error) {
 	filter = analyzer.getFilter(filter)
 
 	if filter.table() == "event" {
@@ -1035,20 +1551,21 @@ func (analyzer *Analyzer) AvgTimeOnPage(filter *Filter) ([]TimeSpentStats, error
 		fieldsQuery = "," + fieldsQuery
 	}
 
-	withFillArgs, withFillQuery := filter.withFill()
+	periodGroup := periodField(filter, period)
+	withFillArgs, withFillQuery := fieldWithFill(filter, periodGroup)
 	args := make([]interface{}, 0, len(timeArgs)*2+len(fieldArgs)+len(withFillArgs))
 	var query strings.Builder
-	query.WriteString(fmt.Sprintf(`SELECT day,
+	query.WriteString(fmt.Sprintf(`SELECT period,
 		ifNull(toUInt64(avg(nullIf(time_on_page, 0))), 0) average_time_spent_seconds
 		FROM (
-			SELECT day,
+			SELECT period,
 			%s time_on_page
 			FROM (
 				SELECT session_id,
-				toDate(time, '%s') day,
+				%s period,
 				duration_seconds
 				%s
-				FROM page_view v `, analyzer.timeOnPageQuery(filter), filter.Timezone.String(), fieldsQuery))
+				FROM page_view v `, analyzer.timeOnPageQuery(filter), fmt.Sprintf(periodGroup.queryPageViews, filter.Timezone.String()), fieldsQuery))
 
 	if filter.EntryPath != "" || filter.ExitPath != "" {
 		args = append(args, timeArgs...)
@@ -1073,8 +1590,8 @@ func (analyzer *Analyzer) AvgTimeOnPage(filter *Filter) ([]TimeSpentStats, error
 			AND time_on_page > 0
 			%s
 		)
-		GROUP BY day
-		ORDER BY day
+		GROUP BY period
+		ORDER BY period
 		%s`, timeQuery, fieldQuery, withFillQuery))
 	var stats []TimeSpentStats
 
@@ -1128,12 +1645,13 @@ func (analyzer *Analyzer) totalSessionDuration(filter *Filter) (int, error) {
 			SELECT sum(duration_seconds*sign) duration_seconds
 			FROM session s `)
 
-	if filter.Path != "" || filter.PathPattern != "" {
+	if filter.pageFilter() {
 		args = append(args, innerFilterArgs...)
 		query.WriteString(fmt.Sprintf(`INNER JOIN (
 			SELECT visitor_id,
 			session_id,
-			path
+			path,
+			title
 			FROM page_view
 			WHERE %s
 		) v
@@ -1310,6 +1828,46 @@ func (analyzer *Analyzer) timeOnPageQuery(filter *Filter) string {
 	return timeOnPage
 }
 
+// siteSearchQuery returns the query for all page views with a search term and whether it was the last page view of the session.
+func (analyzer *Analyzer) siteSearchQuery(filter *Filter) ([]interface{}, string) {
+	args, timeQuery := filter.queryTime()
+	entryPath, exitPath, eventName := filter.EntryPath, filter.ExitPath, filter.EventName
+	filter.EntryPath, filter.ExitPath, filter.EventName = "", "", ""
+	filterArgs, filterQuery := filter.query()
+	filter.EntryPath, filter.ExitPath, filter.EventName = entryPath, exitPath, eventName
+	args = append(args, filterArgs...)
+	sessionQuery := ""
+
+	if entryPath != "" || exitPath != "" {
+		path, pathPattern, conditions := filter.Path, filter.PathPattern, filter.Conditions
+		filter.Path, filter.PathPattern, filter.EventName, filter.Conditions = "", "", "", filter.sessionConditions()
+		sessionArgs, sessionFilterQuery := filter.query()
+		filter.Path, filter.PathPattern, filter.EventName, filter.Conditions = path, pathPattern, eventName, conditions
+		args = append(args, sessionArgs...)
+		sessionQuery = fmt.Sprintf(`AND (visitor_id, session_id) IN (
+			SELECT visitor_id, session_id
+			FROM session
+			WHERE %s
+			GROUP BY visitor_id, session_id
+			HAVING sum(sign) > 0
+		) `, sessionFilterQuery)
+	}
+
+	return args, fmt.Sprintf(`SELECT v.visitor_id visitor_id,
+		v.session_id session_id,
+		v.search_term search_term,
+		v.time = s.last is_exit
+		FROM page_view v
+		INNER JOIN (
+			SELECT visitor_id, session_id, max(time) last
+			FROM page_view
+			WHERE %s
+			GROUP BY visitor_id, session_id
+		) s
+		ON s.visitor_id = v.visitor_id AND s.session_id = v.session_id
+		WHERE %s AND search_term != '' %s`, timeQuery, filterQuery, sessionQuery)
+}
+
 func (analyzer *Analyzer) selectByAttribute(results interface{}, filter *Filter, attr field) error {
 	args, query := buildQuery(analyzer.getFilter(filter), []field{
 		attr,
@@ -1324,6 +1882,38 @@ func (analyzer *Analyzer) selectByAttribute(results interface{}, filter *Filter,
 	return analyzer.store.Select(results, query, args...)
 }
 
+// cityName returns the localized name for given city, or the stored (English) name if there is none.
+func (analyzer *Analyzer) cityName(l *Localizer, geoNameID uint32, city, locale string) string {
+	if name := l.CityName(geoNameID, locale); name != "" {
+		return name
+	}
+
+	return city
+}
+
+// compareEvents sets the visitor count of the comparison period for the events with the same name and meta value.
+func (analyzer *Analyzer) compareEvents(stats, previous []EventStats) {
+	for i := range stats {
+		previousVisitors := 0
+
+		for j := range previous {
+			if stats[i].Name == previous[j].Name && stats[i].MetaValue == previous[j].MetaValue {
+				previousVisitors = previous[j].Visitors
+				break
+			}
+		}
+
+		analyzer.compareVisitors(&stats[i].ComparisonStats, stats[i].Visitors, previousVisitors)
+	}
+}
+
+// compareVisitors sets the visitor count of the comparison period and the change relative to it.
+func (analyzer *Analyzer) compareVisitors(stats *ComparisonStats, current, previous int) {
+	stats.PreviousVisitors = previous
+	stats.VisitorsDelta = current - previous
+	stats.VisitorsGrowth = analyzer.calculateGrowth(current, previous)
+}
+
 func (analyzer *Analyzer) calculateGrowth(current, previous int) float64 {
 	if current == 0 && previous == 0 {
 		return 0
@@ -1357,3 +1947,12 @@ func (analyzer *Analyzer) getFilter(filter *Filter) *Filter {
 	filterCopy := *filter
 	return &filterCopy
 }
+
+// heatmapCell returns the index of given weekday and hour in the TrafficHeatmap, or -1 if it is out of range.
+func heatmapCell(stats TrafficHeatmapStats) int {
+	if stats.Weekday < 1 || stats.Weekday > 7 || stats.Hour < 0 || stats.Hour > 23 {
+		return -1
+	}
+
+	return (stats.Weekday-1)*24 + stats.Hour
+}
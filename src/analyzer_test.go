@@ -172,14 +172,14 @@ func TestAnalyzer_VisitorsAndAvgSessionDuration(t *testing.T) {
 		{VisitorID: 9, Time: Today(), Path: "/"},
 	}))
 	analyzer := NewAnalyzer(dbClient)
-	visitors, err := analyzer.Visitors(&Filter{From: pastDay(4), To: Today()})
+	visitors, err := analyzer.Visitors(&Filter{From: pastDay(4), To: Today()}, PeriodDay)
 	assert.NoError(t, err)
 	assert.Len(t, visitors, 5)
-	assert.Equal(t, pastDay(4), visitors[0].Day)
-	assert.Equal(t, pastDay(3), visitors[1].Day)
-	assert.Equal(t, pastDay(2), visitors[2].Day)
-	assert.Equal(t, pastDay(1), visitors[3].Day)
-	assert.Equal(t, Today(), visitors[4].Day)
+	assert.Equal(t, pastDay(4), visitors[0].Period)
+	assert.Equal(t, pastDay(3), visitors[1].Period)
+	assert.Equal(t, pastDay(2), visitors[2].Period)
+	assert.Equal(t, pastDay(1), visitors[3].Period)
+	assert.Equal(t, Today(), visitors[4].Period)
 	assert.Equal(t, 4, visitors[0].Visitors)
 	assert.Equal(t, 0, visitors[1].Visitors)
 	assert.Equal(t, 4, visitors[2].Visitors)
@@ -205,7 +205,7 @@ func TestAnalyzer_VisitorsAndAvgSessionDuration(t *testing.T) {
 	assert.InDelta(t, 0.5, visitors[2].BounceRate, 0.01)
 	assert.InDelta(t, 0, visitors[3].BounceRate, 0.01)
 	assert.InDelta(t, 1, visitors[4].BounceRate, 0.01)
-	visitors, err = analyzer.Visitors(&Filter{Path: "/", From: pastDay(4), To: Today()})
+	visitors, err = analyzer.Visitors(&Filter{Path: "/", From: pastDay(4), To: Today()}, PeriodDay)
 	assert.NoError(t, err)
 	assert.Len(t, visitors, 5)
 	assert.Equal(t, 4, visitors[0].Visitors)
@@ -233,39 +233,74 @@ func TestAnalyzer_VisitorsAndAvgSessionDuration(t *testing.T) {
 	assert.InDelta(t, 1, visitors[2].BounceRate, 0.01)
 	assert.InDelta(t, 0, visitors[3].BounceRate, 0.01)
 	assert.InDelta(t, 1, visitors[4].BounceRate, 0.01)
-	asd, err := analyzer.AvgSessionDuration(nil)
+	asd, err := analyzer.AvgSessionDuration(nil, PeriodDay)
 	assert.NoError(t, err)
 	assert.Len(t, asd, 2)
-	assert.Equal(t, pastDay(4), asd[0].Day)
-	assert.Equal(t, pastDay(2), asd[1].Day)
+	assert.Equal(t, pastDay(4), asd[0].Period)
+	assert.Equal(t, pastDay(2), asd[1].Period)
 	assert.Equal(t, 300, asd[0].AverageTimeSpentSeconds)
 	assert.Equal(t, 450, asd[1].AverageTimeSpentSeconds)
 	tsd, err := analyzer.totalSessionDuration(&Filter{})
 	assert.NoError(t, err)
 	assert.Equal(t, 1200, tsd)
-	visitors, err = analyzer.Visitors(&Filter{From: pastDay(4), To: pastDay(1)})
+	visitors, err = analyzer.Visitors(&Filter{From: pastDay(4), To: pastDay(1)}, PeriodDay)
 	assert.NoError(t, err)
 	assert.Len(t, visitors, 4)
-	assert.Equal(t, pastDay(4), visitors[0].Day)
-	assert.Equal(t, pastDay(2), visitors[2].Day)
-	asd, err = analyzer.AvgSessionDuration(&Filter{From: pastDay(3), To: pastDay(1)})
+	assert.Equal(t, pastDay(4), visitors[0].Period)
+	assert.Equal(t, pastDay(2), visitors[2].Period)
+	asd, err = analyzer.AvgSessionDuration(&Filter{From: pastDay(3), To: pastDay(1)}, PeriodDay)
 	assert.NoError(t, err)
 	assert.Len(t, asd, 3)
 	tsd, err = analyzer.totalSessionDuration(&Filter{From: pastDay(3), To: pastDay(1)})
 	assert.NoError(t, err)
 	assert.Equal(t, 900, tsd)
-	_, err = analyzer.Visitors(getMaxFilter(""))
+	_, err = analyzer.Visitors(getMaxFilter(""), PeriodDay)
 	assert.NoError(t, err)
-	_, err = analyzer.Visitors(getMaxFilter("event"))
+	_, err = analyzer.Visitors(getMaxFilter("event"), PeriodDay)
 	assert.NoError(t, err)
-	_, err = analyzer.AvgSessionDuration(getMaxFilter(""))
+	_, err = analyzer.AvgSessionDuration(getMaxFilter(""), PeriodDay)
 	assert.NoError(t, err)
-	_, err = analyzer.AvgSessionDuration(getMaxFilter("event"))
+	_, err = analyzer.AvgSessionDuration(getMaxFilter("event"), PeriodDay)
 	assert.NoError(t, err)
 	_, err = analyzer.totalSessionDuration(getMaxFilter(""))
 	assert.NoError(t, err)
 }
 
+func TestAnalyzer_NewVisitors(t *testing.T) {
+	cleanupDB()
+	saveSessions(t, [][]Session{
+		{
+			{Sign: 1, VisitorID: 1, SessionID: 1, Time: pastDay(2), NewVisitor: true},
+			{Sign: 1, VisitorID: 2, SessionID: 2, Time: pastDay(2), NewVisitor: true},
+			{Sign: 1, VisitorID: 1, SessionID: 3, Time: pastDay(1)},
+			{Sign: 1, VisitorID: 3, SessionID: 4, Time: pastDay(1), NewVisitor: true},
+			{Sign: 1, VisitorID: 2, SessionID: 5, Time: Today()},
+		},
+	})
+	time.Sleep(time.Millisecond * 20)
+	analyzer := NewAnalyzer(dbClient)
+	stats, err := analyzer.NewVisitors(&Filter{From: pastDay(2), To: Today()}, "")
+	assert.NoError(t, err)
+	assert.Len(t, stats, 3)
+	assert.Equal(t, 2, stats[0].Visitors)
+	assert.Equal(t, 2, stats[0].NewVisitors)
+	assert.Equal(t, 0, stats[0].ReturningVisitors)
+	assert.Equal(t, 2, stats[1].Visitors)
+	assert.Equal(t, 1, stats[1].NewVisitors)
+	assert.Equal(t, 1, stats[1].ReturningVisitors)
+	assert.Equal(t, 1, stats[2].Visitors)
+	assert.Equal(t, 0, stats[2].NewVisitors)
+	assert.Equal(t, 1, stats[2].ReturningVisitors)
+	stats, err = analyzer.NewVisitors(&Filter{From: pastDay(2), To: Today(), NewVisitor: "false"}, PeriodWeek)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, stats)
+	assert.Zero(t, stats[len(stats)-1].NewVisitors)
+	_, err = analyzer.NewVisitors(getMaxFilter(""), "")
+	assert.NoError(t, err)
+	_, err = analyzer.NewVisitors(getMaxFilter("event"), "")
+	assert.NoError(t, err)
+}
+
 func TestAnalyzer_Growth(t *testing.T) {
 	cleanupDB()
 	saveSessions(t, [][]Session{
@@ -434,6 +469,62 @@ func TestAnalyzer_VisitorHours(t *testing.T) {
 	assert.NoError(t, err)
 }
 
+func TestAnalyzer_TrafficHeatmap(t *testing.T) {
+	cleanupDB()
+	monday := Today().AddDate(0, 0, -(int(Today().Weekday())+6)%7-7) // last week
+	saveSessions(t, [][]Session{
+		{
+			{Sign: 1, VisitorID: 1, SessionID: 1, Time: monday.Add(time.Hour * 9), ExitPath: "/"},
+			{Sign: 1, VisitorID: 2, SessionID: 2, Time: monday.Add(time.Hour * 9), ExitPath: "/"},
+			{Sign: 1, VisitorID: 1, SessionID: 3, Time: monday.Add(time.Hour * 9), ExitPath: "/"},
+			{Sign: 1, VisitorID: 3, SessionID: 4, Time: monday.AddDate(0, 0, 6).Add(time.Hour * 23), ExitPath: "/"},
+		},
+	})
+	assert.NoError(t, dbClient.SaveEvents([]Event{
+		{Name: "event", VisitorID: 1, SessionID: 1, Time: monday.Add(time.Hour * 9), Path: "/"},
+		{Name: "event", VisitorID: 1, SessionID: 3, Time: monday.Add(time.Hour * 9), Path: "/"},
+		{Name: "event", VisitorID: 3, SessionID: 4, Time: monday.AddDate(0, 0, 2).Add(time.Hour * 14), Path: "/"},
+	}))
+	time.Sleep(time.Millisecond * 20)
+	analyzer := NewAnalyzer(dbClient)
+	stats, err := analyzer.TrafficHeatmap(&Filter{From: monday, To: Today()})
+	assert.NoError(t, err)
+	assert.Len(t, stats, 168)
+	assert.Equal(t, 1, stats[9].Weekday)
+	assert.Equal(t, 9, stats[9].Hour)
+	assert.Equal(t, 2, stats[9].Visitors)
+	assert.Equal(t, 3, stats[9].Sessions)
+	assert.Equal(t, 2, stats[9].Events)
+	assert.Equal(t, 1, stats[2*24+14].Events)
+	assert.Zero(t, stats[2*24+14].Visitors)
+	assert.Equal(t, 7, stats[167].Weekday)
+	assert.Equal(t, 23, stats[167].Hour)
+	assert.Equal(t, 1, stats[167].Visitors)
+	stats, err = analyzer.TrafficHeatmap(&Filter{From: monday, To: Today(), EventName: "event"})
+	assert.NoError(t, err)
+	assert.Len(t, stats, 168)
+	assert.Equal(t, 1, stats[9].Visitors)
+	assert.Equal(t, 2, stats[9].Sessions)
+	assert.Equal(t, 2, stats[9].Events)
+	_, err = analyzer.TrafficHeatmap(getMaxFilter(""))
+	assert.NoError(t, err)
+	_, err = analyzer.TrafficHeatmap(getMaxFilter("event"))
+	assert.NoError(t, err)
+}
+
+func TestAnalyzer_TrafficHeatmapEmpty(t *testing.T) {
+	analyzer := NewAnalyzer(NewMockClient())
+	stats, err := analyzer.TrafficHeatmap(nil)
+	assert.NoError(t, err)
+	assert.Len(t, stats, 168)
+	assert.Equal(t, 1, stats[0].Weekday)
+	assert.Equal(t, 0, stats[0].Hour)
+	assert.Equal(t, 3, stats[24*2+5].Weekday)
+	assert.Equal(t, 5, stats[24*2+5].Hour)
+	assert.Equal(t, -1, heatmapCell(TrafficHeatmapStats{}))
+	assert.Equal(t, 24*6+23, heatmapCell(TrafficHeatmapStats{Weekday: 7, Hour: 23}))
+}
+
 func TestAnalyzer_PagesAndAvgTimeOnPage(t *testing.T) {
 	cleanupDB()
 	assert.NoError(t, dbClient.SavePageViews([]PageView{
@@ -509,11 +600,11 @@ func TestAnalyzer_PagesAndAvgTimeOnPage(t *testing.T) {
 	assert.Equal(t, 300, visitors[0].AverageTimeSpentSeconds)
 	assert.Equal(t, 600, visitors[1].AverageTimeSpentSeconds)
 	assert.Equal(t, 0, visitors[2].AverageTimeSpentSeconds)
-	top, err := analyzer.AvgTimeOnPage(nil)
+	top, err := analyzer.AvgTimeOnPage(nil, PeriodDay)
 	assert.NoError(t, err)
 	assert.Len(t, top, 2)
-	assert.Equal(t, pastDay(4), top[0].Day)
-	assert.Equal(t, pastDay(2), top[1].Day)
+	assert.Equal(t, pastDay(4), top[0].Period)
+	assert.Equal(t, pastDay(2), top[1].Period)
 	assert.Equal(t, 150, top[0].AverageTimeSpentSeconds)
 	assert.Equal(t, 600, top[1].AverageTimeSpentSeconds)
 	ttop, err := analyzer.totalTimeOnPage(&Filter{})
@@ -531,12 +622,12 @@ func TestAnalyzer_PagesAndAvgTimeOnPage(t *testing.T) {
 	assert.Equal(t, 600, visitors[0].AverageTimeSpentSeconds)
 	assert.Equal(t, 600, visitors[1].AverageTimeSpentSeconds)
 	assert.Equal(t, 0, visitors[2].AverageTimeSpentSeconds)
-	top, err = analyzer.AvgTimeOnPage(&Filter{From: pastDay(3), To: pastDay(1), IncludeTimeOnPage: true})
+	top, err = analyzer.AvgTimeOnPage(&Filter{From: pastDay(3), To: pastDay(1), IncludeTimeOnPage: true}, PeriodDay)
 	assert.NoError(t, err)
 	assert.Len(t, top, 3)
-	assert.Equal(t, pastDay(3), top[0].Day)
-	assert.Equal(t, pastDay(2), top[1].Day)
-	assert.Equal(t, pastDay(1), top[2].Day)
+	assert.Equal(t, pastDay(3), top[0].Period)
+	assert.Equal(t, pastDay(2), top[1].Period)
+	assert.Equal(t, pastDay(1), top[2].Period)
 	assert.Equal(t, 0, top[0].AverageTimeSpentSeconds)
 	assert.Equal(t, 600, top[1].AverageTimeSpentSeconds)
 	assert.Equal(t, 0, top[2].AverageTimeSpentSeconds)
@@ -734,6 +825,70 @@ func TestAnalyzer_EntryExitPages(t *testing.T) {
 	assert.NoError(t, err)
 }
 
+func TestAnalyzer_SearchTerms(t *testing.T) {
+	saveSiteSearches(t)
+	analyzer := NewAnalyzer(dbClient)
+	stats, err := analyzer.SearchTerms(nil)
+	assert.NoError(t, err)
+	assert.Len(t, stats, 2)
+	assert.Equal(t, "boots", stats[0].SearchTerm)
+	assert.Equal(t, "shoes", stats[1].SearchTerm)
+	assert.Equal(t, 2, stats[0].Searches)
+	assert.Equal(t, 2, stats[1].Searches)
+	assert.Equal(t, 2, stats[0].Visitors)
+	assert.Equal(t, 2, stats[1].Visitors)
+	assert.Equal(t, 2, stats[0].Sessions)
+	assert.Equal(t, 2, stats[1].Sessions)
+	assert.Equal(t, 2, stats[0].Exits)
+	assert.Equal(t, 0, stats[1].Exits)
+	assert.InDelta(t, 1, stats[0].ExitRate, 0.01)
+	assert.InDelta(t, 0, stats[1].ExitRate, 0.01)
+	stats, err = analyzer.SearchTerms(&Filter{Path: "/search"})
+	assert.NoError(t, err)
+	assert.Len(t, stats, 2)
+	assert.Equal(t, "boots", stats[0].SearchTerm)
+	assert.Equal(t, 2, stats[0].Searches)
+	assert.Equal(t, "shoes", stats[1].SearchTerm)
+	assert.Equal(t, 1, stats[1].Searches)
+	stats, err = analyzer.SearchTerms(&Filter{EntryPath: "/blog"})
+	assert.NoError(t, err)
+	assert.Len(t, stats, 1)
+	assert.Equal(t, "boots", stats[0].SearchTerm)
+	assert.Equal(t, 1, stats[0].Searches)
+	assert.Equal(t, 1, stats[0].Exits)
+	_, err = analyzer.SearchTerms(getMaxFilter(""))
+	assert.NoError(t, err)
+}
+
+func TestAnalyzer_SiteSearch(t *testing.T) {
+	saveSiteSearches(t)
+	analyzer := NewAnalyzer(dbClient)
+	stats, err := analyzer.SiteSearch(nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 4, stats.Searches)
+	assert.Equal(t, 3, stats.Sessions)
+	assert.InDelta(t, 1.3333, stats.SearchesPerSession, 0.01)
+	assert.Equal(t, 2, stats.Exits)
+	assert.InDelta(t, 0.5, stats.ExitRate, 0.01)
+	stats, err = analyzer.SiteSearch(&Filter{Path: "/search"})
+	assert.NoError(t, err)
+	assert.Equal(t, 3, stats.Searches)
+	assert.Equal(t, 2, stats.Sessions)
+	assert.Equal(t, 2, stats.Exits)
+	stats, err = analyzer.SiteSearch(&Filter{EntryPath: "/search"})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, stats.Searches)
+	assert.Equal(t, 1, stats.Sessions)
+	assert.InDelta(t, 2, stats.SearchesPerSession, 0.01)
+	assert.Equal(t, 1, stats.Exits)
+	stats, err = analyzer.SiteSearch(&Filter{From: pastDay(2), To: pastDay(1)})
+	assert.NoError(t, err)
+	assert.Equal(t, 0, stats.Searches)
+	assert.InDelta(t, 0, stats.SearchesPerSession, 0.01)
+	_, err = analyzer.SiteSearch(getMaxFilter(""))
+	assert.NoError(t, err)
+}
+
 func TestAnalyzer_PageConversions(t *testing.T) {
 	cleanupDB()
 	assert.NoError(t, dbClient.SavePageViews([]PageView{
@@ -1003,6 +1158,47 @@ func TestAnalyzer_ReferrerUnknown(t *testing.T) {
 	assert.InDelta(t, 0.5, visitors[0].BounceRate, 0.01)
 }
 
+func TestAnalyzer_Channels(t *testing.T) {
+	cleanupDB()
+	saveSessions(t, [][]Session{
+		{
+			{Sign: 1, VisitorID: 1, Time: time.Now(), Channel: ChannelDirect},
+		},
+		{
+			{Sign: -1, VisitorID: 1, Time: time.Now(), Channel: ChannelDirect},
+			{Sign: 1, VisitorID: 1, Time: time.Now(), Channel: ChannelOrganicSearch},
+			{Sign: 1, VisitorID: 2, Time: time.Now(), Channel: ChannelSocial},
+			{Sign: 1, VisitorID: 3, Time: time.Now(), Channel: ChannelSocial},
+			{Sign: 1, VisitorID: 4, Time: time.Now(), Channel: ChannelDirect},
+			{Sign: 1, VisitorID: 5, Time: time.Now(), Channel: ChannelOrganicSearch},
+			{Sign: 1, VisitorID: 6, Time: time.Now(), Channel: ChannelOrganicSearch},
+		},
+	})
+	time.Sleep(time.Millisecond * 20)
+	analyzer := NewAnalyzer(dbClient)
+	visitors, err := analyzer.Channels(nil)
+	assert.NoError(t, err)
+	assert.Len(t, visitors, 3)
+	assert.Equal(t, ChannelOrganicSearch, visitors[0].Channel)
+	assert.Equal(t, ChannelSocial, visitors[1].Channel)
+	assert.Equal(t, ChannelDirect, visitors[2].Channel)
+	assert.Equal(t, 3, visitors[0].Visitors)
+	assert.Equal(t, 2, visitors[1].Visitors)
+	assert.Equal(t, 1, visitors[2].Visitors)
+	assert.InDelta(t, 0.5, visitors[0].RelativeVisitors, 0.01)
+	assert.InDelta(t, 0.33, visitors[1].RelativeVisitors, 0.01)
+	assert.InDelta(t, 0.1666, visitors[2].RelativeVisitors, 0.01)
+	visitors, err = analyzer.Channels(&Filter{Channel: ChannelSocial})
+	assert.NoError(t, err)
+	assert.Len(t, visitors, 1)
+	assert.Equal(t, ChannelSocial, visitors[0].Channel)
+	assert.Equal(t, 2, visitors[0].Visitors)
+	_, err = analyzer.Channels(getMaxFilter(""))
+	assert.NoError(t, err)
+	_, err = analyzer.Channels(getMaxFilter("event"))
+	assert.NoError(t, err)
+}
+
 func TestAnalyzer_Platform(t *testing.T) {
 	cleanupDB()
 	assert.NoError(t, dbClient.SavePageViews([]PageView{
@@ -1497,25 +1693,25 @@ func TestAnalyzer_AvgTimeOnPage(t *testing.T) {
 	})
 	time.Sleep(time.Millisecond * 20)
 	analyzer := NewAnalyzer(dbClient)
-	byDay, err := analyzer.AvgTimeOnPage(&Filter{Path: "/", From: pastDay(3), To: Today()})
+	byDay, err := analyzer.AvgTimeOnPage(&Filter{Path: "/", From: pastDay(3), To: Today()}, PeriodDay)
 	assert.NoError(t, err)
 	assert.Len(t, byDay, 4)
 	assert.Equal(t, 8, byDay[0].AverageTimeSpentSeconds)
 	assert.Equal(t, 4, byDay[1].AverageTimeSpentSeconds)
 	assert.Equal(t, 7, byDay[2].AverageTimeSpentSeconds)
 	assert.Equal(t, 0, byDay[3].AverageTimeSpentSeconds)
-	byDay, err = analyzer.AvgTimeOnPage(&Filter{Path: "/foo", From: pastDay(3), To: Today()})
+	byDay, err = analyzer.AvgTimeOnPage(&Filter{Path: "/foo", From: pastDay(3), To: Today()}, PeriodDay)
 	assert.NoError(t, err)
 	assert.Len(t, byDay, 0)
-	byDay, err = analyzer.AvgTimeOnPage(&Filter{MaxTimeOnPageSeconds: 5})
+	byDay, err = analyzer.AvgTimeOnPage(&Filter{MaxTimeOnPageSeconds: 5}, PeriodDay)
 	assert.NoError(t, err)
 	assert.Len(t, byDay, 3)
 	assert.Equal(t, 5, byDay[0].AverageTimeSpentSeconds)
 	assert.Equal(t, 4, byDay[1].AverageTimeSpentSeconds)
 	assert.Equal(t, 5, byDay[2].AverageTimeSpentSeconds)
-	_, err = analyzer.AvgTimeOnPage(getMaxFilter(""))
+	_, err = analyzer.AvgTimeOnPage(getMaxFilter(""), PeriodDay)
 	assert.NoError(t, err)
-	_, err = analyzer.AvgTimeOnPage(getMaxFilter("event"))
+	_, err = analyzer.AvgTimeOnPage(getMaxFilter("event"), PeriodDay)
 	assert.NoError(t, err)
 }
 
@@ -1547,6 +1743,50 @@ func TestAnalyzer_CalculateGrowthFloat64(t *testing.T) {
 	assert.InDelta(t, -0.5, growth, 0.001)
 }
 
+func TestAnalyzer_VisitorsPeriod(t *testing.T) {
+	cleanupDB()
+	assert.NoError(t, dbClient.SaveSessions([]Session{
+		{Sign: 1, VisitorID: 1, Time: time.Date(2021, 12, 29, 10, 0, 0, 0, time.UTC), ExitPath: "/"},
+		{Sign: 1, VisitorID: 2, Time: time.Date(2022, 1, 2, 10, 0, 0, 0, time.UTC), ExitPath: "/"},
+		{Sign: 1, VisitorID: 3, Time: time.Date(2022, 1, 4, 10, 0, 0, 0, time.UTC), ExitPath: "/"},
+	}))
+	time.Sleep(time.Millisecond * 20)
+	analyzer := NewAnalyzer(dbClient)
+	filter := &Filter{From: time.Date(2021, 12, 20, 0, 0, 0, 0, time.UTC), To: time.Date(2022, 1, 9, 0, 0, 0, 0, time.UTC)}
+	visitors, err := analyzer.Visitors(filter, PeriodWeek)
+	assert.NoError(t, err)
+	assert.Len(t, visitors, 3)
+	assert.Equal(t, time.Date(2021, 12, 20, 0, 0, 0, 0, time.UTC), visitors[0].Period.UTC())
+	assert.Equal(t, time.Date(2021, 12, 27, 0, 0, 0, 0, time.UTC), visitors[1].Period.UTC())
+	assert.Equal(t, time.Date(2022, 1, 3, 0, 0, 0, 0, time.UTC), visitors[2].Period.UTC())
+	assert.Equal(t, 0, visitors[0].Visitors)
+	assert.Equal(t, 2, visitors[1].Visitors)
+	assert.Equal(t, 1, visitors[2].Visitors)
+	visitors, err = analyzer.Visitors(filter, PeriodMonth)
+	assert.NoError(t, err)
+	assert.Len(t, visitors, 2)
+	assert.Equal(t, time.Date(2021, 12, 1, 0, 0, 0, 0, time.UTC), visitors[0].Period.UTC())
+	assert.Equal(t, time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC), visitors[1].Period.UTC())
+	assert.Equal(t, 1, visitors[0].Visitors)
+	assert.Equal(t, 2, visitors[1].Visitors)
+	visitors, err = analyzer.Visitors(filter, PeriodYear)
+	assert.NoError(t, err)
+	assert.Len(t, visitors, 2)
+	assert.Equal(t, time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC), visitors[0].Period.UTC())
+	assert.Equal(t, time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC), visitors[1].Period.UTC())
+
+	for _, period := range Periods {
+		_, err = analyzer.Visitors(filter, period)
+		assert.NoError(t, err)
+		_, err = analyzer.GroupEvents(filter, period)
+		assert.NoError(t, err)
+		_, err = analyzer.AvgSessionDuration(filter, period)
+		assert.NoError(t, err)
+		_, err = analyzer.AvgTimeOnPage(filter, period)
+		assert.NoError(t, err)
+	}
+}
+
 func TestAnalyzer_Timezone(t *testing.T) {
 	cleanupDB()
 	assert.NoError(t, dbClient.SaveSessions([]Session{
@@ -1561,7 +1801,7 @@ func TestAnalyzer_Timezone(t *testing.T) {
 	}))
 	time.Sleep(time.Millisecond * 20)
 	analyzer := NewAnalyzer(dbClient)
-	visitors, err := analyzer.Visitors(&Filter{From: pastDay(3), To: pastDay(1)})
+	visitors, err := analyzer.Visitors(&Filter{From: pastDay(3), To: pastDay(1)}, PeriodDay)
 	assert.NoError(t, err)
 	assert.Len(t, visitors, 3)
 	assert.Equal(t, 1, visitors[0].Visitors)
@@ -1574,7 +1814,7 @@ func TestAnalyzer_Timezone(t *testing.T) {
 	assert.Equal(t, 1, hours[19].Visitors)
 	timezone, err := time.LoadLocation("Asia/Tokyo")
 	assert.NoError(t, err)
-	visitors, err = analyzer.Visitors(&Filter{From: pastDay(3), To: pastDay(1), Timezone: timezone})
+	visitors, err = analyzer.Visitors(&Filter{From: pastDay(3), To: pastDay(1), Timezone: timezone}, PeriodDay)
 	assert.NoError(t, err)
 	assert.Len(t, visitors, 3)
 	assert.Equal(t, 0, visitors[0].Visitors)
@@ -1925,7 +2165,7 @@ func TestAnalyzer_NoData(t *testing.T) {
 	analyzer := NewAnalyzer(dbClient)
 	_, _, err := analyzer.ActiveVisitors(nil, time.Minute*15)
 	assert.NoError(t, err)
-	_, err = analyzer.Visitors(nil)
+	_, err = analyzer.Visitors(nil, PeriodDay)
 	assert.NoError(t, err)
 	_, err = analyzer.Growth(&Filter{From: pastDay(7), To: Today()})
 	assert.NoError(t, err)
@@ -1953,9 +2193,9 @@ func TestAnalyzer_NoData(t *testing.T) {
 	assert.NoError(t, err)
 	_, err = analyzer.BrowserVersion(nil)
 	assert.NoError(t, err)
-	_, err = analyzer.AvgSessionDuration(nil)
+	_, err = analyzer.AvgSessionDuration(nil, PeriodDay)
 	assert.NoError(t, err)
-	_, err = analyzer.AvgTimeOnPage(nil)
+	_, err = analyzer.AvgTimeOnPage(nil, PeriodDay)
 	assert.NoError(t, err)
 }
 
@@ -1990,6 +2230,27 @@ func getMaxFilter(eventName string) *Filter {
 	}
 }
 
+func saveSiteSearches(t *testing.T) {
+	cleanupDB()
+	assert.NoError(t, dbClient.SavePageViews([]PageView{
+		{VisitorID: 1, SessionID: 1, Time: Today(), Path: "/"},
+		{VisitorID: 1, SessionID: 1, Time: Today().Add(time.Second), Path: "/shop/search", SearchTerm: "shoes"},
+		{VisitorID: 1, SessionID: 1, Time: Today().Add(time.Second * 2), Path: "/product"},
+		{VisitorID: 2, SessionID: 1, Time: Today(), Path: "/search", SearchTerm: "shoes"},
+		{VisitorID: 2, SessionID: 1, Time: Today().Add(time.Second), Path: "/search", SearchTerm: "boots"},
+		{VisitorID: 3, SessionID: 1, Time: Today(), Path: "/blog"},
+		{VisitorID: 3, SessionID: 1, Time: Today().Add(time.Second), Path: "/search", SearchTerm: "boots"},
+	}))
+	saveSessions(t, [][]Session{
+		{
+			{Sign: 1, VisitorID: 1, SessionID: 1, Time: Today().Add(time.Second * 2), Start: Today(), EntryPath: "/", ExitPath: "/product", PageViews: 3},
+			{Sign: 1, VisitorID: 2, SessionID: 1, Time: Today().Add(time.Second), Start: Today(), EntryPath: "/search", ExitPath: "/search", PageViews: 2},
+			{Sign: 1, VisitorID: 3, SessionID: 1, Time: Today().Add(time.Second), Start: Today(), EntryPath: "/blog", ExitPath: "/search", PageViews: 2},
+		},
+	})
+	time.Sleep(time.Millisecond * 20)
+}
+
 func saveSessions(t *testing.T, sessions [][]Session) {
 	for _, entries := range sessions {
 		assert.NoError(t, dbClient.SaveSessions(entries))
@@ -0,0 +1,319 @@
+package omisocial
+
+import (
+	"fmt"
+	"golang.org/x/net/html"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultAndroidAppBaseURL       = "https://play.google.com/store/apps/details"
+	defaultAndroidAppTimeout       = time.Second * 5
+	defaultAndroidAppRetryInterval = time.Hour * 24
+	defaultAndroidAppMaxLookups    = 4
+)
+
+// AndroidAppResolverConfig is the configuration for the AndroidAppResolver.
+type AndroidAppResolverConfig struct {
+	// Store is used to persist resolved apps and to update the referrer names of stored sessions, page views, and events.
+	Store Store
+
+	// BaseURL is the Google Play app details page the package name is appended to as the id query parameter.
+	// Set to https://play.google.com/store/apps/details by default.
+	BaseURL string
+
+	// Timeout is the timeout for a single lookup.
+	// Set to 5 seconds by default.
+	Timeout time.Duration
+
+	// RetryInterval is the time after which a failed lookup is retried.
+	// Set to 24 hours by default.
+	RetryInterval time.Duration
+
+	// MaxLookups is the maximum number of concurrent lookups.
+	// Set to 4 by default.
+	MaxLookups int
+
+	// Logger is the log.Logger used for logging.
+	// The default log will be used printing to os.Stdout with "pirsch" in its prefix in case it is not set.
+	Logger *log.Logger
+}
+
+func (config *AndroidAppResolverConfig) validate() {
+	if config.BaseURL == "" {
+		config.BaseURL = defaultAndroidAppBaseURL
+	}
+
+	if config.Timeout <= 0 {
+		config.Timeout = defaultAndroidAppTimeout
+	}
+
+	if config.RetryInterval <= 0 {
+		config.RetryInterval = defaultAndroidAppRetryInterval
+	}
+
+	if config.MaxLookups <= 0 {
+		config.MaxLookups = defaultAndroidAppMaxLookups
+	}
+
+	if config.Logger == nil {
+		config.Logger = logger
+	}
+}
+
+// AndroidAppResolver resolves android-app:// referrers to the app name and icon from the Google Play Store.
+// Lookups run in the background, so that new sessions are stored without a referrer name at first.
+// Once an app has been resolved, the referrer name and icon of stored sessions, page views, and events are updated
+// and the result is cached in the android_app table.
+type AndroidAppResolver struct {
+	config  AndroidAppResolverConfig
+	client  *http.Client
+	apps    map[string]AndroidApp
+	failed  map[string]time.Time
+	pending map[string]struct{}
+	lookups chan struct{}
+	load    sync.Once
+	m       sync.RWMutex
+}
+
+// NewAndroidAppResolver creates a new AndroidAppResolver for given configuration.
+// The cache is loaded from the store on the first lookup.
+func NewAndroidAppResolver(config AndroidAppResolverConfig) *AndroidAppResolver {
+	config.validate()
+	return &AndroidAppResolver{
+		config:  config,
+		client:  &http.Client{Timeout: config.Timeout},
+		apps:    make(map[string]AndroidApp),
+		failed:  make(map[string]time.Time),
+		pending: make(map[string]struct{}),
+		lookups: make(chan struct{}, config.MaxLookups),
+	}
+}
+
+// Resolve returns the name and icon for given android-app:// referrer if it has been resolved before.
+// Otherwise, an asynchronous lookup is started and empty strings are returned.
+func (resolver *AndroidAppResolver) Resolve(referrer string) (string, string) {
+	packageName := getAndroidAppPackageName(referrer)
+
+	if packageName == "" {
+		return "", ""
+	}
+
+	resolver.load.Do(resolver.loadCache)
+	resolver.m.Lock()
+	defer resolver.m.Unlock()
+
+	if app, found := resolver.apps[packageName]; found {
+		return app.Name, app.Icon
+	}
+
+	if _, found := resolver.pending[packageName]; found {
+		return "", ""
+	}
+
+	if failed, found := resolver.failed[packageName]; found && time.Since(failed) < resolver.config.RetryInterval {
+		return "", ""
+	}
+
+	resolver.pending[packageName] = struct{}{}
+	go resolver.lookup(packageName)
+	return "", ""
+}
+
+// Cached returns the name and icon for given android-app:// referrer if it has been resolved before, without starting a lookup.
+func (resolver *AndroidAppResolver) Cached(referrer string) (string, string) {
+	resolver.m.RLock()
+	defer resolver.m.RUnlock()
+	app := resolver.apps[getAndroidAppPackageName(referrer)]
+	return app.Name, app.Icon
+}
+
+func (resolver *AndroidAppResolver) lookup(packageName string) {
+	resolver.lookups <- struct{}{}
+	defer func() {
+		resolver.m.Lock()
+		delete(resolver.pending, packageName)
+		resolver.m.Unlock()
+		<-resolver.lookups
+	}()
+	name, icon, err := resolver.fetch(packageName)
+	resolver.m.Lock()
+
+	if err != nil {
+		resolver.failed[packageName] = time.Now()
+		resolver.m.Unlock()
+		resolver.config.Logger.Printf("error looking up android app %s: %s", packageName, err)
+		return
+	}
+
+	app := AndroidApp{
+		PackageName: packageName,
+		Name:        shortenString(name, 200),
+		Icon:        shortenString(icon, 2000),
+		Time:        time.Now().UTC(),
+	}
+	resolver.apps[packageName] = app
+	delete(resolver.failed, packageName)
+	resolver.m.Unlock()
+
+	if resolver.config.Store == nil {
+		return
+	}
+
+	if err := resolver.config.Store.SaveAndroidApps([]AndroidApp{app}); err != nil {
+		resolver.config.Logger.Printf("error saving android app %s: %s", packageName, err)
+	}
+
+	referrer := androidAppPrefix + packageName
+
+	if err := resolver.config.Store.UpdateReferrerName([]string{referrer, referrer + "/"}, app.Name, app.Icon); err != nil {
+		resolver.config.Logger.Printf("error updating referrer name for android app %s: %s", packageName, err)
+	}
+}
+
+// fetch reads the app name and icon from the Google Play Store page of given package.
+func (resolver *AndroidAppResolver) fetch(packageName string) (string, string, error) {
+	resp, err := resolver.client.Get(fmt.Sprintf("%s?id=%s", resolver.config.BaseURL, url.QueryEscape(packageName)))
+
+	if err != nil {
+		return "", "", err
+	}
+
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			resolver.config.Logger.Printf("error closing response body for android app %s: %s", packageName, err)
+		}
+	}()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", "", fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+
+	doc, err := html.Parse(resp.Body)
+
+	if err != nil {
+		return "", "", err
+	}
+
+	titleNode := findAndroidAppName(doc)
+
+	if titleNode == nil {
+		return "", "", fmt.Errorf("app name not found")
+	}
+
+	appName := findTextNode(titleNode)
+
+	if appName == nil || strings.TrimSpace(appName.Data) == "" {
+		return "", "", fmt.Errorf("app name not found")
+	}
+
+	icon := ""
+	iconNode := findAndroidAppIcon(doc)
+
+	if iconNode != nil {
+		icon = getHTMLAttribute(iconNode, "src")
+	}
+
+	return strings.TrimSpace(appName.Data), icon, nil
+}
+
+func (resolver *AndroidAppResolver) loadCache() {
+	if resolver.config.Store == nil {
+		return
+	}
+
+	var apps []AndroidApp
+
+	if err := resolver.config.Store.Select(&apps, `SELECT package_name, name, icon, time FROM "android_app" FINAL`); err != nil {
+		resolver.config.Logger.Printf("error loading android apps: %s", err)
+		return
+	}
+
+	resolver.m.Lock()
+	defer resolver.m.Unlock()
+
+	for _, app := range apps {
+		resolver.apps[app.PackageName] = app
+	}
+}
+
+func getAndroidAppPackageName(referrer string) string {
+	if len(referrer) <= len(androidAppPrefix) || !strings.EqualFold(referrer[:len(androidAppPrefix)], androidAppPrefix) {
+		return ""
+	}
+
+	packageName := strings.Trim(referrer[len(androidAppPrefix):], "/")
+
+	if i := strings.IndexAny(packageName, "/?#"); i > 0 {
+		packageName = packageName[:i]
+	}
+
+	return packageName
+}
+
+func findAndroidAppName(node *html.Node) *html.Node {
+	if node.Type == html.ElementNode && node.Data == "h1" {
+		return node
+	}
+
+	for c := node.FirstChild; c != nil; c = c.NextSibling {
+		if n := findAndroidAppName(c); n != nil {
+			return n
+		}
+	}
+
+	return nil
+}
+
+func findAndroidAppIcon(node *html.Node) *html.Node {
+	if node.Type == html.ElementNode && node.Data == "img" && hasHTMLAttribute(node, "itemprop", "image") {
+		return node
+	}
+
+	for c := node.FirstChild; c != nil; c = c.NextSibling {
+		if n := findAndroidAppIcon(c); n != nil {
+			return n
+		}
+	}
+
+	return nil
+}
+
+func findTextNode(node *html.Node) *html.Node {
+	if node.Type == html.TextNode {
+		return node
+	}
+
+	for c := node.FirstChild; c != nil; c = c.NextSibling {
+		if n := findTextNode(c); n != nil {
+			return n
+		}
+	}
+
+	return nil
+}
+
+func hasHTMLAttribute(node *html.Node, key, value string) bool {
+	for _, attr := range node.Attr {
+		if attr.Key == key && attr.Val == value {
+			return true
+		}
+	}
+
+	return false
+}
+
+func getHTMLAttribute(node *html.Node, key string) string {
+	for _, attr := range node.Attr {
+		if attr.Key == key {
+			return attr.Val
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,103 @@
+package omisocial
+
+import (
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestAndroidAppResolver_Resolve(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+
+		switch r.URL.Query().Get("id") {
+		case "com.example.app":
+			w.Write([]byte(`<html><body><img itemprop="image" src="https://example.com/icon.png" /><h1><span>Example App</span></h1></body></html>`))
+		case "com.example.slow":
+			time.Sleep(time.Millisecond * 200)
+			w.Write([]byte(`<html><body><h1>Slow App</h1></body></html>`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	client := NewMockClient()
+	client.Sessions = append(client.Sessions, Session{Referrer: androidAppPrefix + "com.example.app"})
+	client.PageViews = append(client.PageViews, PageView{Referrer: androidAppPrefix + "com.example.app"})
+	resolver := NewAndroidAppResolver(AndroidAppResolverConfig{
+		Store:   client,
+		BaseURL: server.URL + "/store/apps/details",
+		Timeout: time.Millisecond * 50,
+	})
+	name, icon := resolver.Resolve(androidAppPrefix + "com.example.app")
+	assert.Empty(t, name)
+	assert.Empty(t, icon)
+	waitForAndroidApp(resolver, "com.example.app")
+	name, icon = resolver.Resolve(androidAppPrefix + "com.example.app/")
+	assert.Equal(t, "Example App", name)
+	assert.Equal(t, "https://example.com/icon.png", icon)
+	assert.Len(t, client.AndroidApps, 1)
+	assert.Equal(t, "com.example.app", client.AndroidApps[0].PackageName)
+	assert.Equal(t, "Example App", client.Sessions[0].ReferrerName)
+	assert.Equal(t, "https://example.com/icon.png", client.PageViews[0].ReferrerIcon)
+
+	// failed lookups and timeouts are not retried immediately
+	resolver.Resolve(androidAppPrefix + "does-not-exist")
+	resolver.Resolve(androidAppPrefix + "com.example.slow")
+	waitForAndroidApp(resolver, "does-not-exist")
+	waitForAndroidApp(resolver, "com.example.slow")
+	n := atomic.LoadInt32(&requests)
+	name, _ = resolver.Resolve(androidAppPrefix + "does-not-exist")
+	assert.Empty(t, name)
+	name, _ = resolver.Resolve(androidAppPrefix + "com.example.slow")
+	assert.Empty(t, name)
+	assert.Equal(t, n, atomic.LoadInt32(&requests))
+	assert.Len(t, client.AndroidApps, 1)
+}
+
+func TestAndroidAppResolver_Cached(t *testing.T) {
+	resolver := NewAndroidAppResolver(AndroidAppResolverConfig{BaseURL: "http://localhost:0"})
+	resolver.apps["com.example.app"] = AndroidApp{PackageName: "com.example.app", Name: "Example App", Icon: "icon"}
+	name, icon := resolver.Cached(androidAppPrefix + "com.example.app")
+	assert.Equal(t, "Example App", name)
+	assert.Equal(t, "icon", icon)
+	name, icon = resolver.Cached(androidAppPrefix + "com.example.unknown")
+	assert.Empty(t, name)
+	assert.Empty(t, icon)
+	assert.Empty(t, resolver.pending)
+}
+
+func TestGetAndroidAppPackageName(t *testing.T) {
+	assert.Equal(t, "com.example.app", getAndroidAppPackageName("android-app://com.example.app"))
+	assert.Equal(t, "com.example.app", getAndroidAppPackageName("Android-App://com.example.app/"))
+	assert.Equal(t, "com.google.android.gm", getAndroidAppPackageName("android-app://com.google.android.gm/http/mail.google.com"))
+	assert.Empty(t, getAndroidAppPackageName("android-app://"))
+	assert.Empty(t, getAndroidAppPackageName("https://example.com"))
+}
+
+func TestUpdateSessionAndroidApp(t *testing.T) {
+	resolver := NewAndroidAppResolver(AndroidAppResolverConfig{})
+	resolver.apps["com.example.app"] = AndroidApp{PackageName: "com.example.app", Name: "Example App", Icon: "icon"}
+	session := &Session{Referrer: androidAppPrefix + "com.example.app", Start: time.Now(), Time: time.Now()}
+	updateSession(&HitOptions{SessionMaxAge: time.Minute, androidApps: resolver}, session, time.Now(), "/", "")
+	assert.Equal(t, "Example App", session.ReferrerName)
+	assert.Equal(t, "icon", session.ReferrerIcon)
+}
+
+func waitForAndroidApp(resolver *AndroidAppResolver, packageName string) {
+	for i := 0; i < 100; i++ {
+		resolver.m.RLock()
+		_, pending := resolver.pending[packageName]
+		resolver.m.RUnlock()
+
+		if !pending {
+			return
+		}
+
+		time.Sleep(time.Millisecond * 10)
+	}
+}
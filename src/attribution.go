@@ -0,0 +1,282 @@
+package omisocial
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// AttributionFirstTouch credits the first session within the window.
+	AttributionFirstTouch = "first_touch"
+
+	// AttributionLastTouch credits the session the conversion happened in.
+	AttributionLastTouch = "last_touch"
+
+	// AttributionLastNonDirect credits the last session that wasn't direct traffic, or the last session if all of them were.
+	AttributionLastNonDirect = "last_non_direct"
+
+	// AttributionLinear credits all sessions within the window equally.
+	AttributionLinear = "linear"
+
+	// AttributionTimeDecay credits all sessions within the window, halving the credit for every AttributionOptions.HalfLife before the conversion.
+	AttributionTimeDecay = "time_decay"
+
+	defaultAttributionWindow   = time.Hour * 24 * 30
+	defaultAttributionHalfLife = time.Hour * 24 * 7
+)
+
+var (
+	// ErrNoAttributionGoal is returned in case neither a goal event nor path was specified for the attribution.
+	ErrNoAttributionGoal = errors.New("no goal event or path specified")
+
+	// ErrUnknownAttributionModel is returned in case the attribution model is not supported.
+	ErrUnknownAttributionModel = errors.New("unknown attribution model")
+
+	// ErrUnknownAttributionDimension is returned in case the attribution dimension is not supported.
+	ErrUnknownAttributionDimension = errors.New("unknown attribution dimension")
+
+	// attributionDimensions are the session columns conversions can be attributed to.
+	attributionDimensions = []string{
+		"channel",
+		"referrer_name",
+		"utm_source",
+		"utm_medium",
+		"utm_campaign",
+		"utm_content",
+		"utm_term",
+		"otm_source",
+		"otm_medium",
+		"otm_campaign",
+		"otm_position",
+	}
+)
+
+// AttributionOptions configures the attribution model.
+// The goal is set using the Filter.EventName, or the Filter.Path or Filter.PathPattern.
+type AttributionOptions struct {
+	// Model is the attribution model (like AttributionLinear).
+	// Set to AttributionLastNonDirect by default.
+	Model string
+
+	// Dimension is the session field conversions are credited to.
+	// Can be channel, referrer_name, or one of the utm_ and otm_ fields (like utm_campaign).
+	// Set to channel by default.
+	Dimension string
+
+	// Window is the time before a conversion to look back for previous sessions of the visitor.
+	// Set to 30 days by default.
+	Window time.Duration
+
+	// HalfLife is the time after which the credit of a session halves for the AttributionTimeDecay model.
+	// Set to 7 days by default.
+	HalfLife time.Duration
+}
+
+func (options *AttributionOptions) validate() error {
+	if options.Model == "" {
+		options.Model = AttributionLastNonDirect
+	}
+
+	if options.Dimension == "" {
+		options.Dimension = "channel"
+	}
+
+	if options.Window <= 0 {
+		options.Window = defaultAttributionWindow
+	}
+
+	if options.HalfLife <= 0 {
+		options.HalfLife = defaultAttributionHalfLife
+	}
+
+	switch options.Model {
+	case AttributionFirstTouch, AttributionLastTouch, AttributionLastNonDirect, AttributionLinear, AttributionTimeDecay:
+	default:
+		return ErrUnknownAttributionModel
+	}
+
+	if !containsString(attributionDimensions, options.Dimension) {
+		return ErrUnknownAttributionDimension
+	}
+
+	return nil
+}
+
+// attributionTouch is a session of a visitor before a conversion.
+type attributionTouch struct {
+	VisitorID      uint64    `db:"visitor_id"`
+	ConversionID   uint32    `db:"conversion_id"`
+	ConversionTime time.Time `db:"conversion_time"`
+	Start          time.Time
+	Value          string
+	Direct         bool
+}
+
+// Attribution returns the conversions for a goal event or path credited to the sessions of the visitors before converting.
+// All sessions of a converting visitor within the AttributionOptions.Window before the conversion are taken into account
+// and credited by the AttributionOptions.Model. Each session with a goal counts as one conversion.
+// The conversions are returned as fractions for the linear and time decay model.
+func (analyzer *Analyzer) Attribution(filter *Filter, options AttributionOptions) ([]AttributionStats, error) {
+	filter = analyzer.getFilter(filter)
+
+	if filter.EventName == "" && filter.Path == "" && filter.PathPattern == "" {
+		return nil, ErrNoAttributionGoal
+	}
+
+	if err := options.validate(); err != nil {
+		return nil, err
+	}
+
+	args, query := analyzer.attributionQuery(filter, &options)
+	var touches []attributionTouch
+
+	if err := analyzer.store.Select(&touches, query, args...); err != nil {
+		return nil, err
+	}
+
+	return attributeConversions(touches, &options), nil
+}
+
+// attributionQuery returns the query for all sessions before a conversion, ordered by conversion and start time.
+func (analyzer *Analyzer) attributionQuery(filter *Filter, options *AttributionOptions) ([]interface{}, string) {
+	table := "page_view"
+
+	if filter.EventName != "" {
+		table = "event"
+	}
+
+	entryPath, exitPath := filter.EntryPath, filter.ExitPath
+	filter.EntryPath, filter.ExitPath = "", ""
+	args, filterQuery := filter.query()
+	filter.EntryPath, filter.ExitPath = entryPath, exitPath
+	window := int64(options.Window.Seconds())
+	args = append(args, filter.ClientID)
+	var sessionQuery strings.Builder
+	sessionQuery.WriteString("client_id = ? ")
+
+	if !filter.From.IsZero() {
+		args = append(args, filter.From, window)
+		sessionQuery.WriteString(fmt.Sprintf("AND start >= toDateTime(toDate(?), '%s') - INTERVAL ? SECOND ", filter.Timezone.String()))
+	}
+
+	if !filter.To.IsZero() {
+		args = append(args, filter.To)
+		sessionQuery.WriteString(fmt.Sprintf("AND toDate(start, '%s') <= toDate(?) ", filter.Timezone.String()))
+	}
+
+	args = append(args, window)
+	return args, fmt.Sprintf(`SELECT c.visitor_id visitor_id,
+		c.session_id conversion_id,
+		c.time conversion_time,
+		s.start start,
+		s.value value,
+		s.direct direct
+		FROM (
+			SELECT visitor_id, session_id, min(time) time
+			FROM %s
+			WHERE %s
+			GROUP BY visitor_id, session_id
+		) c
+		INNER JOIN (
+			SELECT visitor_id,
+			session_id,
+			any(start) start,
+			any(%s) value,
+			any(channel = '%s' OR (channel = '' AND referrer_name = '' AND utm_source = '' AND otm_source = '')) direct
+			FROM session
+			WHERE %s
+			GROUP BY visitor_id, session_id
+			HAVING sum(sign) > 0
+		) s
+		ON s.visitor_id = c.visitor_id
+		WHERE s.start <= c.time
+		AND s.start >= c.time - INTERVAL ? SECOND
+		ORDER BY visitor_id, conversion_id, conversion_time, start`, table, filterQuery, options.Dimension, ChannelDirect, sessionQuery.String())
+}
+
+// attributeConversions credits the conversions to the touches using the attribution model.
+// The touches must be ordered by visitor, conversion, and start time.
+func attributeConversions(touches []attributionTouch, options *AttributionOptions) []AttributionStats {
+	credits := make(map[string]float64)
+	conversions := 0
+
+	for start := 0; start < len(touches); {
+		end := start + 1
+
+		for end < len(touches) && touches[end].VisitorID == touches[start].VisitorID &&
+			touches[end].ConversionID == touches[start].ConversionID &&
+			touches[end].ConversionTime.Equal(touches[start].ConversionTime) {
+			end++
+		}
+
+		creditTouches(credits, touches[start:end], options)
+		conversions++
+		start = end
+	}
+
+	stats := make([]AttributionStats, 0, len(credits))
+
+	for value, credit := range credits {
+		stats = append(stats, AttributionStats{
+			Value:               value,
+			Conversions:         credit,
+			RelativeConversions: credit / float64(conversions),
+		})
+	}
+
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].Conversions == stats[j].Conversions {
+			return stats[i].Value < stats[j].Value
+		}
+
+		return stats[i].Conversions > stats[j].Conversions
+	})
+	return stats
+}
+
+// creditTouches credits a single conversion to given touches.
+func creditTouches(credits map[string]float64, touches []attributionTouch, options *AttributionOptions) {
+	switch options.Model {
+	case AttributionFirstTouch:
+		credits[touches[0].Value]++
+	case AttributionLastTouch:
+		credits[touches[len(touches)-1].Value]++
+	case AttributionLastNonDirect:
+		touch := touches[len(touches)-1]
+
+		for i := len(touches) - 1; i >= 0; i-- {
+			if !touches[i].Direct {
+				touch = touches[i]
+				break
+			}
+		}
+
+		credits[touch.Value]++
+	case AttributionLinear:
+		for _, touch := range touches {
+			credits[touch.Value] += 1 / float64(len(touches))
+		}
+	case AttributionTimeDecay:
+		weights := make([]float64, len(touches))
+		sum := 0.0
+
+		for i, touch := range touches {
+			age := touch.ConversionTime.Sub(touch.Start)
+
+			if age < 0 {
+				age = 0
+			}
+
+			weights[i] = math.Pow(2, -age.Seconds()/options.HalfLife.Seconds())
+			sum += weights[i]
+		}
+
+		for i, touch := range touches {
+			credits[touch.Value] += weights[i] / sum
+		}
+	}
+}
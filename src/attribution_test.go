@@ -0,0 +1,98 @@
+package omisocial
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestAttributeConversions(t *testing.T) {
+	conversion := time.Date(2021, 11, 20, 12, 0, 0, 0, time.UTC)
+	touches := []attributionTouch{
+		// visitor 1 converts after three sessions
+		{VisitorID: 1, ConversionID: 10, ConversionTime: conversion, Start: conversion.Add(-time.Hour * 24 * 14), Value: ChannelOrganicSearch},
+		{VisitorID: 1, ConversionID: 10, ConversionTime: conversion, Start: conversion.Add(-time.Hour * 24 * 7), Value: ChannelEmail},
+		{VisitorID: 1, ConversionID: 10, ConversionTime: conversion, Start: conversion.Add(-time.Minute), Value: ChannelDirect, Direct: true},
+
+		// visitor 2 converts in the first session
+		{VisitorID: 2, ConversionID: 20, ConversionTime: conversion, Start: conversion.Add(-time.Minute), Value: ChannelSocial},
+	}
+	expected := map[string][]AttributionStats{
+		AttributionFirstTouch: {
+			{Value: ChannelOrganicSearch, Conversions: 1, RelativeConversions: 0.5},
+			{Value: ChannelSocial, Conversions: 1, RelativeConversions: 0.5},
+		},
+		AttributionLastTouch: {
+			{Value: ChannelDirect, Conversions: 1, RelativeConversions: 0.5},
+			{Value: ChannelSocial, Conversions: 1, RelativeConversions: 0.5},
+		},
+		AttributionLastNonDirect: {
+			{Value: ChannelEmail, Conversions: 1, RelativeConversions: 0.5},
+			{Value: ChannelSocial, Conversions: 1, RelativeConversions: 0.5},
+		},
+	}
+
+	for model, stats := range expected {
+		assert.Equal(t, stats, attributeConversions(touches, &AttributionOptions{Model: model}), model)
+	}
+
+	stats := attributeConversions(touches, &AttributionOptions{Model: AttributionLinear})
+	assert.Len(t, stats, 4)
+	assert.Equal(t, ChannelSocial, stats[0].Value)
+	assert.InDelta(t, 1, stats[0].Conversions, 0.0001)
+
+	for _, s := range stats[1:] {
+		assert.InDelta(t, 1.0/3.0, s.Conversions, 0.0001)
+		assert.InDelta(t, 1.0/6.0, s.RelativeConversions, 0.0001)
+	}
+
+	// weights 1/4, 1/2, and ~1 for a half life of 7 days
+	stats = attributeConversions(touches, &AttributionOptions{Model: AttributionTimeDecay, HalfLife: time.Hour * 24 * 7})
+	assert.Len(t, stats, 4)
+	assert.Equal(t, ChannelSocial, stats[0].Value)
+	assert.Equal(t, ChannelDirect, stats[1].Value)
+	assert.Equal(t, ChannelEmail, stats[2].Value)
+	assert.Equal(t, ChannelOrganicSearch, stats[3].Value)
+	assert.InDelta(t, 0.5714, stats[1].Conversions, 0.001)
+	assert.InDelta(t, 0.2857, stats[2].Conversions, 0.001)
+	assert.InDelta(t, 0.1429, stats[3].Conversions, 0.001)
+	total := 0.0
+
+	for _, s := range stats {
+		total += s.Conversions
+	}
+
+	assert.InDelta(t, 2, total, 0.0001)
+	assert.Empty(t, attributeConversions(nil, &AttributionOptions{Model: AttributionLinear}))
+}
+
+func TestAttributeConversionsAllDirect(t *testing.T) {
+	conversion := time.Now().UTC()
+	touches := []attributionTouch{
+		{VisitorID: 1, ConversionID: 1, ConversionTime: conversion, Start: conversion.Add(-time.Hour), Value: ChannelDirect, Direct: true},
+		{VisitorID: 1, ConversionID: 1, ConversionTime: conversion, Start: conversion, Value: "", Direct: true},
+	}
+	stats := attributeConversions(touches, &AttributionOptions{Model: AttributionLastNonDirect})
+	assert.Len(t, stats, 1)
+	assert.Equal(t, "", stats[0].Value)
+	assert.InDelta(t, 1, stats[0].Conversions, 0.0001)
+}
+
+func TestAnalyzer_AttributionOptions(t *testing.T) {
+	analyzer := NewAnalyzer(NewMockClient())
+	_, err := analyzer.Attribution(&Filter{ClientID: 1}, AttributionOptions{})
+	assert.Equal(t, ErrNoAttributionGoal, err)
+	_, err = analyzer.Attribution(&Filter{ClientID: 1, EventName: "purchase"}, AttributionOptions{Model: "unknown"})
+	assert.Equal(t, ErrUnknownAttributionModel, err)
+	_, err = analyzer.Attribution(&Filter{ClientID: 1, Path: "/thank-you"}, AttributionOptions{Dimension: "path; DROP TABLE session"})
+	assert.Equal(t, ErrUnknownAttributionDimension, err)
+	stats, err := analyzer.Attribution(&Filter{ClientID: 1, EventName: "purchase"}, AttributionOptions{Model: AttributionLinear, Dimension: "utm_campaign"})
+	assert.NoError(t, err)
+	assert.Empty(t, stats)
+	options := AttributionOptions{}
+	assert.NoError(t, options.validate())
+	assert.Equal(t, AttributionLastNonDirect, options.Model)
+	assert.Equal(t, "channel", options.Dimension)
+	assert.Equal(t, defaultAttributionWindow, options.Window)
+	assert.Equal(t, defaultAttributionHalfLife, options.HalfLife)
+}
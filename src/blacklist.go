@@ -0,0 +1,238 @@
+package omisocial
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// BlacklistConfig is the configuration for the Blacklists.
+type BlacklistConfig struct {
+	// UserAgentFiles is a list of files containing additional User-Agent keywords used to filter bots.
+	// Files ending with .json must contain an array of strings, all other files are read as plain text with one entry per line.
+	// Empty lines and lines starting with # are ignored.
+	UserAgentFiles []string
+
+	// ReferrerFiles is a list of files containing additional referrer spam hosts.
+	// The format is the same as for UserAgentFiles.
+	ReferrerFiles []string
+
+	// Logger is the log.Logger used for logging.
+	// The default log will be used printing to os.Stdout with "pirsch" in its prefix in case it is not set.
+	Logger *log.Logger
+}
+
+// Blacklists contains the User-Agent keywords and referrer hosts used by IgnoreHit to filter bots and referrer spam.
+// The lists from the configured files are merged with the built-in userAgentBlacklist and referrerBlacklist.
+// They can be reloaded at runtime without interrupting requests, as the lists are swapped atomically.
+type Blacklists struct {
+	config     BlacklistConfig
+	userAgents atomic.Value // *keywordMatcher
+	referrers  atomic.Value // map[string]struct{}
+	modTimes   map[string]time.Time
+	m          sync.Mutex
+	logger     *log.Logger
+}
+
+var blacklists atomic.Value // *Blacklists
+
+func init() {
+	defaultBlacklists, _ := NewBlacklists(BlacklistConfig{})
+	blacklists.Store(defaultBlacklists)
+}
+
+// SetBlacklists sets the Blacklists used by IgnoreHit.
+// This function is concurrency save and can be called at any time.
+func SetBlacklists(b *Blacklists) {
+	if b != nil {
+		blacklists.Store(b)
+	}
+}
+
+func getBlacklists() *Blacklists {
+	return blacklists.Load().(*Blacklists)
+}
+
+// NewBlacklists creates new Blacklists for given configuration and loads all files.
+// Call SetBlacklists to use them for IgnoreHit.
+func NewBlacklists(config BlacklistConfig) (*Blacklists, error) {
+	if config.Logger == nil {
+		config.Logger = logger
+	}
+
+	b := &Blacklists{
+		config:   config,
+		modTimes: make(map[string]time.Time),
+		logger:   config.Logger,
+	}
+
+	if err := b.Reload(); err != nil {
+		return nil, err
+	}
+
+	return b, nil
+}
+
+// Reload reads all configured files and swaps the lists.
+// The previous lists are kept in case one of the files cannot be read.
+func (b *Blacklists) Reload() error {
+	b.m.Lock()
+	defer b.m.Unlock()
+	modTimes := make(map[string]time.Time)
+	userAgents := make([]string, 0, len(userAgentBlacklist))
+	userAgents = append(userAgents, userAgentBlacklist...)
+
+	for _, file := range b.config.UserAgentFiles {
+		entries, modTime, err := readBlacklistFile(file)
+
+		if err != nil {
+			return err
+		}
+
+		userAgents = append(userAgents, entries...)
+		modTimes[file] = modTime
+	}
+
+	referrers := make(map[string]struct{}, len(referrerBlacklist))
+
+	for host := range referrerBlacklist {
+		referrers[host] = struct{}{}
+	}
+
+	for _, file := range b.config.ReferrerFiles {
+		entries, modTime, err := readBlacklistFile(file)
+
+		if err != nil {
+			return err
+		}
+
+		for _, host := range entries {
+			referrers[host] = struct{}{}
+		}
+
+		modTimes[file] = modTime
+	}
+
+	b.userAgents.Store(newKeywordMatcher(userAgents))
+	b.referrers.Store(referrers)
+	b.modTimes = modTimes
+	return nil
+}
+
+// Watch checks the configured files for changes in given interval and reloads the lists if one of them was modified.
+// It returns a function to stop watching.
+func (b *Blacklists) Watch(interval time.Duration) context.CancelFunc {
+	ctx, cancelFunc := context.WithCancel(context.Background())
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				if b.modified() {
+					if err := b.Reload(); err != nil {
+						b.logger.Printf("error reloading blacklists: %s", err)
+					}
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return cancelFunc
+}
+
+// IgnoreUserAgent returns true if given User-Agent contains a bot keyword.
+func (b *Blacklists) IgnoreUserAgent(userAgent string) bool {
+	return b.userAgents.Load().(*keywordMatcher).Match(strings.ToLower(userAgent))
+}
+
+// IgnoreReferrer returns true if given referrer hostname is blacklisted.
+// The hostname is checked with and without its subdomain.
+func (b *Blacklists) IgnoreReferrer(hostname string) bool {
+	referrers := b.referrers.Load().(map[string]struct{})
+	hostname = strings.ToLower(hostname)
+
+	if _, found := referrers[hostname]; found {
+		return true
+	}
+
+	_, found := referrers[stripSubdomain(hostname)]
+	return found
+}
+
+func (b *Blacklists) modified() bool {
+	b.m.Lock()
+	defer b.m.Unlock()
+
+	for file, modTime := range b.modTimes {
+		info, err := os.Stat(file)
+
+		if err != nil {
+			b.logger.Printf("error reading blacklist file %s: %s", file, err)
+			continue
+		}
+
+		if !info.ModTime().Equal(modTime) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// readBlacklistFile reads the entries of given blacklist file in lowercase and returns them together with the modification time.
+func readBlacklistFile(file string) ([]string, time.Time, error) {
+	info, err := os.Stat(file)
+
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+
+	data, err := os.ReadFile(file)
+
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+
+	var entries []string
+
+	if strings.ToLower(filepath.Ext(file)) == ".json" {
+		if err := json.Unmarshal(data, &entries); err != nil {
+			return nil, time.Time{}, err
+		}
+	} else {
+		scanner := bufio.NewScanner(bytes.NewReader(data))
+
+		for scanner.Scan() {
+			entries = append(entries, scanner.Text())
+		}
+
+		if err := scanner.Err(); err != nil {
+			return nil, time.Time{}, err
+		}
+	}
+
+	result := make([]string, 0, len(entries))
+
+	for _, entry := range entries {
+		entry = strings.ToLower(strings.TrimSpace(entry))
+
+		if entry != "" && !strings.HasPrefix(entry, "#") {
+			result = append(result, entry)
+		}
+	}
+
+	return result, info.ModTime(), nil
+}
@@ -0,0 +1,75 @@
+package omisocial
+
+import (
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestBlacklists(t *testing.T) {
+	dir := t.TempDir()
+	userAgentFile := filepath.Join(dir, "user_agents.txt")
+	referrerFile := filepath.Join(dir, "referrers.json")
+	assert.NoError(t, os.WriteFile(userAgentFile, []byte("# comment\n\n  MyTracker \n"), 0644))
+	assert.NoError(t, os.WriteFile(referrerFile, []byte(`["Spam.example.com", "spammer.net"]`), 0644))
+	b, err := NewBlacklists(BlacklistConfig{
+		UserAgentFiles: []string{userAgentFile},
+		ReferrerFiles:  []string{referrerFile},
+	})
+	assert.NoError(t, err)
+	assert.True(t, b.IgnoreUserAgent("Mozilla/5.0 MyTracker/1.0"))
+	assert.True(t, b.IgnoreUserAgent("Googlebot"))
+	assert.False(t, b.IgnoreUserAgent("Mozilla/5.0 Firefox/110.0"))
+	assert.False(t, b.IgnoreUserAgent("# comment"))
+	assert.True(t, b.IgnoreReferrer("spam.example.com"))
+	assert.False(t, b.IgnoreReferrer("example.com"))
+	assert.True(t, b.IgnoreReferrer("sub.spammer.net"))
+	assert.True(t, b.IgnoreReferrer("temp-mail.org"))
+
+	assert.NoError(t, os.WriteFile(userAgentFile, []byte("othertracker"), 0644))
+	assert.NoError(t, b.Reload())
+	assert.False(t, b.IgnoreUserAgent("Mozilla/5.0 MyTracker/1.0"))
+	assert.True(t, b.IgnoreUserAgent("Mozilla/5.0 OtherTracker/1.0"))
+
+	// the previous lists are kept on error
+	assert.NoError(t, os.WriteFile(referrerFile, []byte(`{invalid`), 0644))
+	assert.Error(t, b.Reload())
+	assert.True(t, b.IgnoreUserAgent("Mozilla/5.0 OtherTracker/1.0"))
+	assert.True(t, b.IgnoreReferrer("spammer.net"))
+
+	_, err = NewBlacklists(BlacklistConfig{UserAgentFiles: []string{filepath.Join(dir, "missing.txt")}})
+	assert.Error(t, err)
+}
+
+func TestBlacklistsWatch(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "user_agents.txt")
+	assert.NoError(t, os.WriteFile(file, []byte("mytracker"), 0644))
+	b, err := NewBlacklists(BlacklistConfig{UserAgentFiles: []string{file}})
+	assert.NoError(t, err)
+	cancel := b.Watch(time.Millisecond * 10)
+	defer cancel()
+	assert.False(t, b.IgnoreUserAgent("OtherTracker"))
+	assert.NoError(t, os.WriteFile(file, []byte("othertracker"), 0644))
+	assert.NoError(t, os.Chtimes(file, time.Now(), time.Now().Add(time.Minute)))
+	assert.Eventually(t, func() bool {
+		return b.IgnoreUserAgent("OtherTracker")
+	}, time.Second, time.Millisecond*10)
+}
+
+func TestSetBlacklists(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "user_agents.txt")
+	assert.NoError(t, os.WriteFile(file, []byte("mytracker"), 0644))
+	defaultBlacklists := getBlacklists()
+	defer SetBlacklists(defaultBlacklists)
+	assert.False(t, isBotUserAgent("MyTracker/1.0"))
+	b, err := NewBlacklists(BlacklistConfig{UserAgentFiles: []string{file}})
+	assert.NoError(t, err)
+	SetBlacklists(b)
+	assert.True(t, isBotUserAgent("MyTracker/1.0"))
+	SetBlacklists(nil)
+	assert.True(t, isBotUserAgent("MyTracker/1.0"))
+}
@@ -0,0 +1,280 @@
+package omisocial
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// BotReasonUserAgent is the bot reason for requests with a blacklisted User-Agent header.
+	BotReasonUserAgent = "user_agent"
+
+	// BotReasonRate is the bot reason for visitors sending too many requests.
+	BotReasonRate = "rate"
+
+	// BotReasonCadence is the bot reason for visitors viewing pages faster than humanly possible.
+	BotReasonCadence = "cadence"
+
+	// BotReasonNoScreen is the bot reason for requests without a screen size.
+	BotReasonNoScreen = "no_screen"
+
+	// BotReasonNoLanguage is the bot reason for requests without an Accept-Language header.
+	BotReasonNoLanguage = "no_language"
+
+	// BotReasonDatacenter is the bot reason for requests from an autonomous system of a hosting provider.
+	BotReasonDatacenter = "datacenter"
+
+	defaultBotMaxRequestsPerMinute = 60
+	defaultBotMinPageViewInterval  = time.Second
+	defaultBotMaxFastPageViews     = 3
+	defaultBotScoreThreshold       = 50
+	botDetectorCleanupInterval     = time.Minute
+)
+
+// DefaultBotWeights are the default scores added for each bot reason.
+// A request is considered a bot once the sum of the scores reaches BotDetectorConfig.Threshold.
+// The screen size and Accept-Language header are missing for server-side hits too,
+// so they are supporting signals and cannot reach the default threshold on their own.
+var DefaultBotWeights = map[string]int{
+	BotReasonRate:       100,
+	BotReasonCadence:    60,
+	BotReasonDatacenter: 50,
+	BotReasonNoScreen:   20,
+	BotReasonNoLanguage: 20,
+}
+
+// DefaultDatacenterASNs is a list of autonomous system numbers of large cloud and hosting providers.
+var DefaultDatacenterASNs = []uint32{
+	16509,  // Amazon AWS
+	14618,  // Amazon AWS
+	15169,  // Google
+	396982, // Google Cloud
+	8075,   // Microsoft Azure
+	14061,  // DigitalOcean
+	16276,  // OVH
+	24940,  // Hetzner
+	63949,  // Linode
+	20473,  // Vultr
+	51167,  // Contabo
+	31898,  // Oracle Cloud
+	45102,  // Alibaba Cloud
+	132203, // Tencent Cloud
+}
+
+// ASNLookup looks up the autonomous system number for an IP address.
+type ASNLookup interface {
+	// ASN returns the autonomous system number for given IP, or 0 if it is unknown.
+	ASN(ip string) uint32
+}
+
+// BotDetectorConfig is the optional configuration for the BotDetector.
+type BotDetectorConfig struct {
+	// MaxRequestsPerMinute is the maximum number of requests per visitor and minute.
+	// Set to 60 by default.
+	MaxRequestsPerMinute int
+
+	// MinPageViewInterval is the minimum time between two page views considered humanly possible.
+	// Set to one second by default.
+	MinPageViewInterval time.Duration
+
+	// MaxFastPageViews is the number of page views in a row below the MinPageViewInterval before a visitor is considered a bot.
+	// Set to 3 by default.
+	MaxFastPageViews int
+
+	// Weights overwrites the DefaultBotWeights for the bot reasons.
+	// Set the weight to 0 to disable a check.
+	Weights map[string]int
+
+	// Threshold is the score at which a request is considered to be a bot.
+	// Set to 50 by default.
+	Threshold int
+
+	// Drop drops bot traffic instead of flagging the sessions.
+	// Bot traffic is stored in the bot table in both cases.
+	Drop bool
+
+	// ASNLookup is used to check if a request was sent from a datacenter.
+	// The check is disabled if it isn't set.
+	ASNLookup ASNLookup
+
+	// DatacenterASNs is the list of autonomous system numbers considered to be datacenters.
+	// Set to DefaultDatacenterASNs by default.
+	DatacenterASNs []uint32
+}
+
+func (config *BotDetectorConfig) validate() {
+	if config.MaxRequestsPerMinute <= 0 {
+		config.MaxRequestsPerMinute = defaultBotMaxRequestsPerMinute
+	}
+
+	if config.MinPageViewInterval <= 0 {
+		config.MinPageViewInterval = defaultBotMinPageViewInterval
+	}
+
+	if config.MaxFastPageViews <= 0 {
+		config.MaxFastPageViews = defaultBotMaxFastPageViews
+	}
+
+	if config.Weights == nil {
+		config.Weights = DefaultBotWeights
+	}
+
+	if config.Threshold <= 0 {
+		config.Threshold = defaultBotScoreThreshold
+	}
+
+	if config.DatacenterASNs == nil {
+		config.DatacenterASNs = DefaultDatacenterASNs
+	}
+}
+
+// BotDetector scores requests by the behaviour of the visitor to detect bots sending the User-Agent of a regular browser.
+// The BotDetector keeps track of the requests per visitor in memory and is safe for concurrent use.
+type BotDetector struct {
+	config         BotDetectorConfig
+	datacenterASNs map[uint32]struct{}
+	visitors       map[uint64]*botVisitor
+	lastCleanup    time.Time
+	m              sync.Mutex
+}
+
+type botVisitor struct {
+	windowStart   time.Time
+	requests      int
+	lastPageView  time.Time
+	fastPageViews int
+}
+
+// NewBotDetector creates a new BotDetector for given configuration.
+// Pass nil to use the defaults.
+func NewBotDetector(config *BotDetectorConfig) *BotDetector {
+	if config == nil {
+		config = &BotDetectorConfig{}
+	}
+
+	config.validate()
+	datacenterASNs := make(map[uint32]struct{}, len(config.DatacenterASNs))
+
+	for _, asn := range config.DatacenterASNs {
+		datacenterASNs[asn] = struct{}{}
+	}
+
+	return &BotDetector{
+		config:         *config,
+		datacenterASNs: datacenterASNs,
+		visitors:       make(map[uint64]*botVisitor),
+		lastCleanup:    time.Now().UTC(),
+	}
+}
+
+// Drop returns true if bot traffic should be dropped instead of being flagged.
+func (detector *BotDetector) Drop() bool {
+	return detector.config.Drop
+}
+
+// Score returns the bot score and the reason with the highest weight for given request and visitor fingerprint.
+// The screen size is passed separately, as it's set in the HitOptions. Set pageView to false for events.
+func (detector *BotDetector) Score(r *http.Request, fingerprint uint64, screenWidth, screenHeight uint16, pageView bool) (int, string) {
+	now := time.Now().UTC()
+	score := 0
+	reason := ""
+	add := func(r string) {
+		weight := detector.config.Weights[r]
+
+		if weight > 0 {
+			score += weight
+
+			if reason == "" || weight > detector.config.Weights[reason] {
+				reason = r
+			}
+		}
+	}
+
+	rate, cadence := detector.track(fingerprint, now, pageView)
+
+	if rate {
+		add(BotReasonRate)
+	}
+
+	if cadence {
+		add(BotReasonCadence)
+	}
+
+	if detector.config.ASNLookup != nil {
+		if _, found := detector.datacenterASNs[detector.config.ASNLookup.ASN(getIP(r))]; found {
+			add(BotReasonDatacenter)
+		}
+	}
+
+	if screenWidth == 0 || screenHeight == 0 {
+		add(BotReasonNoScreen)
+	}
+
+	if strings.TrimSpace(r.Header.Get("Accept-Language")) == "" {
+		add(BotReasonNoLanguage)
+	}
+
+	return score, reason
+}
+
+// Check returns the bot reason for given request, or an empty string if it is not considered a bot.
+// See Score for details.
+func (detector *BotDetector) Check(r *http.Request, fingerprint uint64, screenWidth, screenHeight uint16, pageView bool) string {
+	score, reason := detector.Score(r, fingerprint, screenWidth, screenHeight, pageView)
+
+	if score >= detector.config.Threshold {
+		return reason
+	}
+
+	return ""
+}
+
+// track counts the request for given visitor and returns whether the request rate and page view cadence have been exceeded.
+func (detector *BotDetector) track(fingerprint uint64, now time.Time, pageView bool) (bool, bool) {
+	detector.m.Lock()
+	defer detector.m.Unlock()
+	detector.cleanup(now)
+	visitor, found := detector.visitors[fingerprint]
+
+	if !found || now.Sub(visitor.windowStart) > time.Minute {
+		if visitor == nil {
+			visitor = new(botVisitor)
+			detector.visitors[fingerprint] = visitor
+		}
+
+		visitor.windowStart = now
+		visitor.requests = 0
+	}
+
+	visitor.requests++
+
+	if pageView {
+		if !visitor.lastPageView.IsZero() && now.Sub(visitor.lastPageView) < detector.config.MinPageViewInterval {
+			visitor.fastPageViews++
+		} else {
+			visitor.fastPageViews = 0
+		}
+
+		visitor.lastPageView = now
+	}
+
+	return visitor.requests > detector.config.MaxRequestsPerMinute,
+		visitor.fastPageViews >= detector.config.MaxFastPageViews
+}
+
+// cleanup removes visitors that have been inactive for more than a minute.
+func (detector *BotDetector) cleanup(now time.Time) {
+	if now.Sub(detector.lastCleanup) < botDetectorCleanupInterval {
+		return
+	}
+
+	for fingerprint, visitor := range detector.visitors {
+		if now.Sub(visitor.windowStart) > time.Minute && now.Sub(visitor.lastPageView) > time.Minute {
+			delete(detector.visitors, fingerprint)
+		}
+	}
+
+	detector.lastCleanup = now
+}
@@ -0,0 +1,101 @@
+package omisocial
+
+import (
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+type asnLookupMock struct {
+	asn uint32
+}
+
+func (lookup *asnLookupMock) ASN(string) uint32 {
+	return lookup.asn
+}
+
+func TestBotDetectorConfig_Validate(t *testing.T) {
+	config := &BotDetectorConfig{}
+	config.validate()
+	assert.Equal(t, defaultBotMaxRequestsPerMinute, config.MaxRequestsPerMinute)
+	assert.Equal(t, defaultBotMinPageViewInterval, config.MinPageViewInterval)
+	assert.Equal(t, defaultBotMaxFastPageViews, config.MaxFastPageViews)
+	assert.Equal(t, DefaultBotWeights, config.Weights)
+	assert.Equal(t, defaultBotScoreThreshold, config.Threshold)
+	assert.Equal(t, DefaultDatacenterASNs, config.DatacenterASNs)
+}
+
+func TestBotDetector_Score(t *testing.T) {
+	detector := NewBotDetector(&BotDetectorConfig{
+		ASNLookup: &asnLookupMock{asn: 16509},
+	})
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	score, reason := detector.Score(req, 1, 0, 0, false)
+	assert.Equal(t, 90, score)
+	assert.Equal(t, BotReasonDatacenter, reason)
+	req.Header.Set("Accept-Language", "vi-VN,vi;q=0.9")
+	score, reason = detector.Score(req, 1, 1920, 1080, false)
+	assert.Equal(t, 50, score)
+	assert.Equal(t, BotReasonDatacenter, reason)
+	detector = NewBotDetector(nil)
+	score, reason = detector.Score(req, 1, 1920, 1080, false)
+	assert.Equal(t, 0, score)
+	assert.Empty(t, reason)
+}
+
+func TestBotDetector_Check(t *testing.T) {
+	detector := NewBotDetector(nil)
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	assert.Empty(t, detector.Check(req, 1, 0, 0, true))
+	req.Header.Set("Accept-Language", "en")
+	assert.Empty(t, detector.Check(req, 2, 0, 0, true))
+	assert.Empty(t, detector.Check(req, 3, 1920, 1080, true))
+	detector = NewBotDetector(&BotDetectorConfig{Threshold: 40})
+	req.Header.Del("Accept-Language")
+	assert.Equal(t, BotReasonNoScreen, detector.Check(req, 4, 0, 0, true))
+	assert.Empty(t, detector.Check(req, 5, 1920, 1080, true))
+}
+
+func TestBotDetector_CheckRate(t *testing.T) {
+	detector := NewBotDetector(&BotDetectorConfig{MaxRequestsPerMinute: 5})
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Accept-Language", "en")
+
+	for i := 0; i < 5; i++ {
+		assert.Empty(t, detector.Check(req, 1, 1920, 1080, false))
+	}
+
+	assert.Equal(t, BotReasonRate, detector.Check(req, 1, 1920, 1080, false))
+	assert.Empty(t, detector.Check(req, 2, 1920, 1080, false))
+}
+
+func TestBotDetector_CheckCadence(t *testing.T) {
+	detector := NewBotDetector(&BotDetectorConfig{MaxFastPageViews: 2, MinPageViewInterval: time.Millisecond * 50})
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Accept-Language", "en")
+	assert.Empty(t, detector.Check(req, 1, 1920, 1080, true))
+	assert.Empty(t, detector.Check(req, 1, 1920, 1080, true))
+	assert.Equal(t, BotReasonCadence, detector.Check(req, 1, 1920, 1080, true))
+
+	// events don't count towards the cadence
+	assert.Empty(t, detector.Check(req, 2, 1920, 1080, true))
+	assert.Empty(t, detector.Check(req, 2, 1920, 1080, false))
+	assert.Empty(t, detector.Check(req, 2, 1920, 1080, false))
+	time.Sleep(time.Millisecond * 60)
+	assert.Empty(t, detector.Check(req, 2, 1920, 1080, true))
+}
+
+func TestBotDetector_Cleanup(t *testing.T) {
+	detector := NewBotDetector(nil)
+	now := time.Now().UTC()
+	detector.track(1, now.Add(-time.Minute*3), true)
+	detector.track(2, now, true)
+	assert.Len(t, detector.visitors, 2)
+	detector.cleanup(now)
+	assert.Len(t, detector.visitors, 2)
+	detector.lastCleanup = now.Add(-botDetectorCleanupInterval)
+	detector.cleanup(now)
+	assert.Len(t, detector.visitors, 1)
+}
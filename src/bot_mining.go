@@ -0,0 +1,194 @@
+package omisocial
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	// BotCandidateHighSessions is the reason for User-Agents creating an unusual amount of sessions.
+	BotCandidateHighSessions = "high_sessions"
+
+	// BotCandidateSinglePage is the reason for User-Agents never viewing more than one page per session.
+	BotCandidateSinglePage = "single_page"
+
+	// BotCandidateUnparseable is the reason for User-Agents the browser or operating system could not be extracted for.
+	BotCandidateUnparseable = "unparseable"
+
+	defaultBotMiningMinSessions            = 10
+	defaultBotMiningHighSessions           = 500
+	defaultBotMiningHighSessionsPerVisitor = 20
+	defaultBotMiningMinReasons             = 2
+)
+
+// botKeywordIgnoreProducts is a list of product names (in lowercase) sent by regular browsers that are not used as blacklist keywords.
+var botKeywordIgnoreProducts = []string{
+	"mozilla",
+	"applewebkit",
+	"gecko",
+	"khtml",
+	"like",
+	"version",
+	"mobile",
+	"safari",
+	"chrome",
+	"firefox",
+	"edg",
+	"opr",
+}
+
+// BotMiningConfig is the optional configuration for Analyzer.BotCandidates.
+type BotMiningConfig struct {
+	// MinSessions is the minimum number of sessions for a User-Agent to be considered.
+	// Set to 10 by default.
+	MinSessions int
+
+	// HighSessions is the number of sessions considered to be unusually high for a single User-Agent.
+	// Set to 500 by default.
+	HighSessions int
+
+	// HighSessionsPerVisitor is the number of sessions per visitor considered to be unusually high.
+	// Set to 20 by default.
+	HighSessionsPerVisitor int
+
+	// MinReasons is the number of reasons a User-Agent must match to be proposed as a candidate.
+	// Set to 2 by default.
+	MinReasons int
+}
+
+func (config *BotMiningConfig) validate() {
+	if config.MinSessions <= 0 {
+		config.MinSessions = defaultBotMiningMinSessions
+	}
+
+	if config.HighSessions <= 0 {
+		config.HighSessions = defaultBotMiningHighSessions
+	}
+
+	if config.HighSessionsPerVisitor <= 0 {
+		config.HighSessionsPerVisitor = defaultBotMiningHighSessionsPerVisitor
+	}
+
+	if config.MinReasons <= 0 {
+		config.MinReasons = defaultBotMiningMinReasons
+	}
+}
+
+// BotCandidates reads the User-Agents stored for new sessions and returns those with suspicious patterns.
+// The results are candidates for the userAgentBlacklist and must be reviewed before being added.
+// User-Agents that are already blacklisted are skipped. Pass nil for the config to use the defaults.
+func (analyzer *Analyzer) BotCandidates(filter *Filter, config *BotMiningConfig) ([]BotCandidate, error) {
+	if config == nil {
+		config = &BotMiningConfig{}
+	}
+
+	config.validate()
+	stats, err := analyzer.userAgentSessions(analyzer.getFilter(filter), config.MinSessions)
+
+	if err != nil {
+		return nil, err
+	}
+
+	candidates := make([]BotCandidate, 0)
+
+	for _, s := range stats {
+		if isBotUserAgent(s.UserAgent) {
+			continue
+		}
+
+		reasons := getBotCandidateReasons(&s, config)
+
+		if len(reasons) >= config.MinReasons {
+			candidates = append(candidates, BotCandidate{
+				UserAgentSessionStats: s,
+				Reasons:               reasons,
+				Keyword:               getBotKeyword(s.UserAgent),
+			})
+		}
+	}
+
+	return candidates, nil
+}
+
+func (analyzer *Analyzer) userAgentSessions(filter *Filter, minSessions int) ([]UserAgentSessionStats, error) {
+	args, timeQuery := filter.queryTime()
+	innerArgs, innerTimeQuery := filter.queryTime()
+	args = append(innerArgs, args...)
+	args = append(args, minSessions)
+	query := fmt.Sprintf(`SELECT user_agent,
+		count(*) sessions,
+		uniq(ua.visitor_id) visitors,
+		countIf(s.page_views > 1) multi_page_sessions
+		FROM user_agent ua
+		LEFT JOIN (
+			SELECT visitor_id,
+			session_id,
+			sum(page_views*sign) page_views
+			FROM session
+			WHERE %s
+			GROUP BY visitor_id, session_id
+		) s
+		ON ua.visitor_id = s.visitor_id AND ua.session_id = s.session_id
+		WHERE %s
+		GROUP BY user_agent
+		HAVING sessions >= ?
+		ORDER BY sessions DESC, user_agent
+		%s%s`, innerTimeQuery, timeQuery, filter.withLimit(), filter.withOffset())
+	var stats []UserAgentSessionStats
+
+	if err := analyzer.store.Select(&stats, query, args...); err != nil {
+		return nil, err
+	}
+
+	return stats, nil
+}
+
+func getBotCandidateReasons(stats *UserAgentSessionStats, config *BotMiningConfig) []string {
+	reasons := make([]string, 0, 3)
+
+	if stats.Sessions >= config.HighSessions ||
+		(stats.Visitors > 0 && stats.Sessions/stats.Visitors >= config.HighSessionsPerVisitor) {
+		reasons = append(reasons, BotCandidateHighSessions)
+	}
+
+	if stats.MultiPageSessions == 0 {
+		reasons = append(reasons, BotCandidateSinglePage)
+	}
+
+	ua := ParseUserAgent(stats.UserAgent)
+
+	if ua.Browser == "" || ua.OS == "" {
+		reasons = append(reasons, BotCandidateUnparseable)
+	}
+
+	return reasons
+}
+
+// getBotKeyword proposes a blacklist keyword for given User-Agent.
+// It returns the first product name not sent by regular browsers, or else the full User-Agent (in lowercase).
+// Products listed in the system information (like "compatible; Foobot/1.0") are checked after all other products.
+func getBotKeyword(userAgent string) string {
+	system, products := parseUserAgent(userAgent)
+
+	for _, entry := range system {
+		if strings.ContainsRune(entry, uaProductVersionDelimiter) && !strings.Contains(entry, "://") {
+			products = append(products, entry)
+		}
+	}
+
+	for _, product := range products {
+		name := strings.ToLower(product)
+
+		if i := strings.IndexRune(name, uaProductVersionDelimiter); i > -1 {
+			name = name[:i]
+		}
+
+		name = strings.Trim(name, "[]();,")
+
+		if len(name) > 2 && !Contains(botKeywordIgnoreProducts, name) {
+			return name
+		}
+	}
+
+	return strings.ToLower(strings.TrimSpace(userAgent))
+}
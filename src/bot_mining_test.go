@@ -0,0 +1,46 @@
+package omisocial
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestGetBotCandidateReasons(t *testing.T) {
+	config := &BotMiningConfig{}
+	config.validate()
+	input := []UserAgentSessionStats{
+		{UserAgent: "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/91.0.4472.124 Safari/537.36", Sessions: 1000, Visitors: 900, MultiPageSessions: 400},
+		{UserAgent: "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/91.0.4472.124 Safari/537.36", Sessions: 1000, Visitors: 10, MultiPageSessions: 0},
+		{UserAgent: "FooFetcher/1.2", Sessions: 20, Visitors: 20, MultiPageSessions: 0},
+		{UserAgent: "FooFetcher/1.2", Sessions: 20, Visitors: 20, MultiPageSessions: 3},
+	}
+	expected := [][]string{
+		{BotCandidateHighSessions},
+		{BotCandidateHighSessions, BotCandidateSinglePage},
+		{BotCandidateSinglePage, BotCandidateUnparseable},
+		{BotCandidateUnparseable},
+	}
+
+	for i, in := range input {
+		assert.Equal(t, expected[i], getBotCandidateReasons(&in, config))
+	}
+}
+
+func TestGetBotKeyword(t *testing.T) {
+	input := []string{
+		"FooFetcher/1.2",
+		"Mozilla/5.0 (compatible; FooFetcher/1.2; +http://example.com)",
+		"Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/91.0.4472.124 Safari/537.36 FooFetcher/1.2",
+		"Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/91.0.4472.124 Safari/537.36",
+	}
+	expected := []string{
+		"foofetcher",
+		"foofetcher",
+		"foofetcher",
+		"mozilla/5.0 (x11; linux x86_64) applewebkit/537.36 (khtml, like gecko) chrome/91.0.4472.124 safari/537.36",
+	}
+
+	for i, in := range input {
+		assert.Equal(t, expected[i], getBotKeyword(in))
+	}
+}
@@ -0,0 +1,309 @@
+package omisocial
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"sync/atomic"
+)
+
+const (
+	// ChannelDirect is the channel for sessions without referrer or campaign parameters.
+	ChannelDirect = "Direct"
+
+	// ChannelOrganicSearch is the channel for unpaid traffic from search engines.
+	ChannelOrganicSearch = "Organic Search"
+
+	// ChannelPaidSearch is the channel for search ads.
+	ChannelPaidSearch = "Paid Search"
+
+	// ChannelSocial is the channel for traffic from social networks and messengers.
+	ChannelSocial = "Social"
+
+	// ChannelEmail is the channel for traffic from newsletters and webmail clients.
+	ChannelEmail = "Email"
+
+	// ChannelReferral is the channel for traffic from all other websites.
+	ChannelReferral = "Referral"
+
+	// ChannelAffiliate is the channel for traffic from affiliate partners.
+	ChannelAffiliate = "Affiliate"
+
+	// ChannelDisplay is the channel for display and banner ads.
+	ChannelDisplay = "Display"
+)
+
+var (
+	searchEngineHosts = []string{
+		"google.*",
+		"bing.com",
+		"yahoo.*",
+		"duckduckgo.com",
+		"baidu.com",
+		"yandex.*",
+		"ecosia.org",
+		"qwant.com",
+		"naver.com",
+		"seznam.cz",
+		"coccoc.com",
+		"startpage.com",
+		"search.brave.com",
+	}
+	searchEngineSources = []string{
+		"google",
+		"bing",
+		"yahoo",
+		"duckduckgo",
+		"baidu",
+		"yandex",
+		"ecosia",
+		"qwant",
+		"naver",
+		"seznam",
+		"coccoc",
+	}
+	socialHosts = []string{
+		"facebook.com",
+		"fb.me",
+		"instagram.com",
+		"messenger.com",
+		"twitter.com",
+		"t.co",
+		"x.com",
+		"linkedin.com",
+		"lnkd.in",
+		"pinterest.*",
+		"reddit.com",
+		"tiktok.com",
+		"youtube.com",
+		"youtu.be",
+		"vk.com",
+		"t.me",
+		"telegram.org",
+		"whatsapp.com",
+		"wa.me",
+		"zalo.me",
+		"tumblr.com",
+		"quora.com",
+		"discord.com",
+		"snapchat.com",
+		"threads.net",
+	}
+	socialSources = []string{
+		"facebook",
+		"fb",
+		"instagram",
+		"ig",
+		"messenger",
+		"twitter",
+		"x",
+		"linkedin",
+		"pinterest",
+		"reddit",
+		"tiktok",
+		"youtube",
+		"vk",
+		"telegram",
+		"whatsapp",
+		"zalo",
+	}
+	emailHosts = []string{
+		"mail.google.com",
+		"outlook.live.com",
+		"outlook.office.com",
+		"outlook.office365.com",
+		"mail.yahoo.com",
+		"mail.yandex.ru",
+		"mail.proton.me",
+		"mail.zoho.com",
+	}
+	emailMediums = []string{
+		"email",
+		"e-mail",
+		"e_mail",
+		"e mail",
+		"newsletter",
+	}
+)
+
+// DefaultChannelRules are the default rules used to classify sessions into channels.
+// The first matching rule determines the channel.
+var DefaultChannelRules = []ChannelRule{
+	{Channel: ChannelPaidSearch, ClickIDs: []string{"gclid", "gclsrc", "wbraid", "gbraid", "msclkid"}},
+	{Channel: ChannelDisplay, ClickIDs: []string{"dclid"}},
+	{Channel: ChannelPaidSearch, Mediums: []string{"cpc", "ppc", "paidsearch", "paid-search", "paid_search", "sem"}},
+	{Channel: ChannelDisplay, Mediums: []string{"display", "banner", "cpm", "expandable", "interstitial"}},
+	{Channel: ChannelAffiliate, Mediums: []string{"affiliate", "affiliates", "partner"}},
+	{Channel: ChannelEmail, Mediums: emailMediums},
+	{Channel: ChannelEmail, Sources: emailMediums},
+	{Channel: ChannelSocial, Mediums: []string{"social", "social-network", "social-media", "social_media", "sm", "paid-social", "paidsocial"}},
+	{Channel: ChannelSocial, ClickIDs: []string{"fbclid", "ttclid", "twclid", "li_fat_id"}},
+	{Channel: ChannelOrganicSearch, Mediums: []string{"organic"}},
+	{Channel: ChannelReferral, Mediums: []string{"referral", "link"}},
+	{Channel: ChannelEmail, Hosts: emailHosts},
+	{Channel: ChannelSocial, Hosts: socialHosts},
+	{Channel: ChannelSocial, Sources: socialSources},
+	{Channel: ChannelOrganicSearch, Hosts: searchEngineHosts},
+	{Channel: ChannelOrganicSearch, Sources: searchEngineSources},
+}
+
+// ChannelRule classifies sessions into a channel.
+// All conditions that are set must match, a condition matches if any of its values matches.
+// Values are compared case-insensitive.
+type ChannelRule struct {
+	// Channel is the channel for matching sessions.
+	Channel string
+
+	// Mediums matches the utm_medium or otm_medium.
+	Mediums []string
+
+	// Sources matches the utm_source or otm_source, or the referrer name in case it isn't a URL.
+	Sources []string
+
+	// Hosts matches the referrer hostname, including subdomains.
+	// Entries ending with ".*" match all top-level domains (google.* matches google.com and google.com.vn for example).
+	Hosts []string
+
+	// ClickIDs matches if one of the query parameters is set on the page URL (like gclid).
+	ClickIDs []string
+}
+
+type channelParams struct {
+	referrerHost string
+	referrerName string
+	source       string
+	medium       string
+	query        url.Values
+}
+
+var channelRules atomic.Value // []ChannelRule
+
+func init() {
+	SetChannelRules(DefaultChannelRules)
+}
+
+// SetChannelRules sets the rules used to classify new sessions into channels.
+// Sessions not matching any rule are classified as Referral if there is a referrer or campaign parameter, and as Direct otherwise.
+// This function is concurrency save and can be called at any time.
+func SetChannelRules(rules []ChannelRule) {
+	normalized := make([]ChannelRule, len(rules))
+
+	for i, rule := range rules {
+		normalized[i] = ChannelRule{
+			Channel:  rule.Channel,
+			Mediums:  toLowerStrings(rule.Mediums),
+			Sources:  toLowerStrings(rule.Sources),
+			Hosts:    toLowerStrings(rule.Hosts),
+			ClickIDs: rule.ClickIDs,
+		}
+	}
+
+	channelRules.Store(normalized)
+}
+
+func getChannel(r *http.Request, pageURL, referrer, referrerName string, utm utmParams, otm otmParams) string {
+	params := channelParams{
+		referrerName: strings.ToLower(referrerName),
+		source:       strings.ToLower(firstNonEmpty(utm.source, otm.source)),
+		medium:       strings.ToLower(firstNonEmpty(utm.medium, otm.medium)),
+		query:        r.URL.Query(),
+	}
+
+	if u, err := url.Parse(referrer); err == nil {
+		params.referrerHost = strings.ToLower(u.Hostname())
+	}
+
+	if u, err := url.Parse(pageURL); err == nil && len(u.Query()) > 0 {
+		params.query = u.Query()
+	}
+
+	for _, rule := range channelRules.Load().([]ChannelRule) {
+		if rule.match(&params) {
+			return rule.Channel
+		}
+	}
+
+	if params.referrerHost != "" || params.referrerName != "" || params.source != "" || params.medium != "" {
+		return ChannelReferral
+	}
+
+	return ChannelDirect
+}
+
+func (rule *ChannelRule) match(params *channelParams) bool {
+	if len(rule.Mediums) == 0 && len(rule.Sources) == 0 && len(rule.Hosts) == 0 && len(rule.ClickIDs) == 0 {
+		return false
+	}
+
+	if len(rule.Mediums) > 0 && !containsString(rule.Mediums, params.medium) {
+		return false
+	}
+
+	if len(rule.Sources) > 0 &&
+		!containsString(rule.Sources, params.source) &&
+		(params.referrerHost != "" || !containsString(rule.Sources, params.referrerName)) {
+		return false
+	}
+
+	if len(rule.Hosts) > 0 && !matchAnyHost(rule.Hosts, params.referrerHost) && !matchAnyHost(rule.Hosts, params.source) {
+		return false
+	}
+
+	if len(rule.ClickIDs) > 0 {
+		found := false
+
+		for _, param := range rule.ClickIDs {
+			if params.query.Get(param) != "" {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			return false
+		}
+	}
+
+	return true
+}
+
+// matchAnyHost returns true if given hostname matches one of the host patterns (see ChannelRule.Hosts).
+func matchAnyHost(hosts []string, hostname string) bool {
+	if hostname == "" {
+		return false
+	}
+
+	for _, host := range hosts {
+		if strings.HasSuffix(host, ".*") {
+			name := host[:len(host)-1]
+
+			if strings.HasPrefix(hostname, name) || strings.Contains(hostname, "."+name) {
+				return true
+			}
+		} else if hostname == host || strings.HasSuffix(hostname, "."+host) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func toLowerStrings(list []string) []string {
+	lower := make([]string, len(list))
+
+	for i, str := range list {
+		lower[i] = strings.ToLower(str)
+	}
+
+	return lower
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,88 @@
+package omisocial
+
+import (
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestGetChannel(t *testing.T) {
+	input := []struct {
+		url          string
+		pageURL      string
+		referrer     string
+		referrerName string
+	}{
+		{"/", "", "", ""},
+		{"/?gclid=abc", "", "https://www.google.com/", "www.google.com"},
+		{"/", "https://example.com/?gclid=abc&utm_medium=social", "", ""},
+		{"/?dclid=abc", "", "", ""},
+		{"/?utm_source=google&utm_medium=CPC", "", "", "google"},
+		{"/?utm_source=partner&utm_medium=affiliate", "", "", "partner"},
+		{"/?utm_source=newsletter", "", "", "newsletter"},
+		{"/?otm_source=mailchimp&otm_medium=email", "", "", "mailchimp"},
+		{"/?utm_medium=banner", "", "", ""},
+		{"/?fbclid=abc", "", "https://l.facebook.com/", "l.facebook.com"},
+		{"/", "", "https://m.facebook.com/", "m.facebook.com"},
+		{"/", "", "https://zalo.me/", "zalo.me"},
+		{"/?utm_source=Facebook", "", "", "Facebook"},
+		{"/", "", "https://www.google.com.vn/", "www.google.com.vn"},
+		{"/", "", "https://coccoc.com/search", "coccoc.com"},
+		{"/", "", "https://mail.google.com/", "mail.google.com"},
+		{"/", "", "https://example.com/", "example.com"},
+		{"/?utm_source=unknown", "", "", "unknown"},
+		{"/?utm_source=facebook&utm_medium=referral", "", "", "facebook"},
+	}
+	expected := []string{
+		ChannelDirect,
+		ChannelPaidSearch,
+		ChannelPaidSearch,
+		ChannelDisplay,
+		ChannelPaidSearch,
+		ChannelAffiliate,
+		ChannelEmail,
+		ChannelEmail,
+		ChannelDisplay,
+		ChannelSocial,
+		ChannelSocial,
+		ChannelSocial,
+		ChannelSocial,
+		ChannelOrganicSearch,
+		ChannelOrganicSearch,
+		ChannelEmail,
+		ChannelReferral,
+		ChannelReferral,
+		ChannelReferral,
+	}
+
+	for i, in := range input {
+		r := httptest.NewRequest(http.MethodGet, in.url, nil)
+		assert.Equal(t, expected[i], getChannel(r, in.pageURL, in.referrer, in.referrerName, getUTMParams(r), getOTMParams(r)), in)
+	}
+}
+
+func TestSetChannelRules(t *testing.T) {
+	defer SetChannelRules(DefaultChannelRules)
+	SetChannelRules(append([]ChannelRule{
+		{Channel: "Marketplace", Hosts: []string{"Shopee.*", "tiki.vn"}},
+		{Channel: "Push", Mediums: []string{"push"}, Sources: []string{"onesignal"}},
+	}, DefaultChannelRules...))
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	assert.Equal(t, "Marketplace", getChannel(r, "", "https://shopee.vn/product", "shopee.vn", utmParams{}, otmParams{}))
+	assert.Equal(t, "Marketplace", getChannel(r, "", "https://m.tiki.vn/", "m.tiki.vn", utmParams{}, otmParams{}))
+	assert.Equal(t, "Push", getChannel(r, "", "", "", utmParams{source: "OneSignal", medium: "push"}, otmParams{}))
+	assert.Equal(t, ChannelReferral, getChannel(r, "", "", "", utmParams{medium: "push"}, otmParams{}))
+	assert.Equal(t, ChannelOrganicSearch, getChannel(r, "", "https://www.bing.com/", "www.bing.com", utmParams{}, otmParams{}))
+}
+
+func TestMatchAnyHost(t *testing.T) {
+	hosts := []string{"google.*", "t.co"}
+	assert.True(t, matchAnyHost(hosts, "google.com"))
+	assert.True(t, matchAnyHost(hosts, "www.google.co.uk"))
+	assert.True(t, matchAnyHost(hosts, "t.co"))
+	assert.False(t, matchAnyHost(hosts, "notgoogle.com"))
+	assert.False(t, matchAnyHost(hosts, "mygoogle.example.com"))
+	assert.False(t, matchAnyHost(hosts, "at.co"))
+	assert.False(t, matchAnyHost(hosts, ""))
+}
@@ -5,6 +5,7 @@ import (
 	_ "github.com/ClickHouse/clickhouse-go"
 
 	"database/sql"
+	"fmt"
 	"log"
 	"os"
 	"time"
@@ -50,10 +51,10 @@ func (client *Client) SavePageViews(pageViews []PageView) error {
 	}
 
 	query, err := tx.Prepare(`INSERT INTO "page_view" (client_id, visitor_id, session_id, time, duration_seconds,
-		path, title, language, country_code, city, referrer, referrer_name, referrer_icon, os, os_version,
-		browser, browser_version, desktop, mobile, screen_width, screen_height, screen_class,
-		utm_source, utm_medium, utm_campaign, utm_content, utm_term, otm_source, otm_medium, otm_campaign, otm_position) 
-		VALUES (?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?)`)
+		path, title, language, country_code, city, city_geoname_id, region, postal_code, latitude, longitude, time_zone, asn, as_organization, referrer, referrer_name, referrer_icon, channel, os, os_version,
+		browser, browser_version, desktop, mobile, webview, new_visitor, device_type, device_vendor, device_model, bot_reason, screen_width, screen_height, screen_class,
+		utm_source, utm_medium, utm_campaign, utm_content, utm_term, otm_source, otm_medium, otm_campaign, otm_position, search_term) 
+		VALUES (?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?)`)
 
 	if err != nil {
 		return err
@@ -72,15 +73,30 @@ func (client *Client) SavePageViews(pageViews []PageView) error {
 			pageView.Language,
 			pageView.CountryCode,
 			pageView.City,
+			pageView.CityGeoNameID,
+			pageView.Region,
+			pageView.PostalCode,
+			pageView.Latitude,
+			pageView.Longitude,
+			pageView.TimeZone,
+			pageView.ASN,
+			pageView.ASOrganization,
 			pageView.Referrer,
 			pageView.ReferrerName,
 			pageView.ReferrerIcon,
+			pageView.Channel,
 			pageView.OS,
 			pageView.OSVersion,
 			pageView.Browser,
 			pageView.BrowserVersion,
 			client.boolean(pageView.Desktop),
 			client.boolean(pageView.Mobile),
+			client.boolean(pageView.WebView),
+			client.boolean(pageView.NewVisitor),
+			pageView.DeviceType,
+			pageView.DeviceVendor,
+			pageView.DeviceModel,
+			pageView.BotReason,
 			pageView.ScreenWidth,
 			pageView.ScreenHeight,
 			pageView.ScreenClass,
@@ -93,6 +109,7 @@ func (client *Client) SavePageViews(pageViews []PageView) error {
 			pageView.OTMMedium,
 			pageView.OTMCampaign,
 			pageView.OTMPosition,
+			pageView.SearchTerm,
 		)
 
 		if err != nil {
@@ -120,10 +137,10 @@ func (client *Client) SaveSessions(sessions []Session) error {
 	}
 
 	query, err := tx.Prepare(`INSERT INTO "session" (sign, client_id, visitor_id, session_id, time, start, duration_seconds,
-		entry_path, exit_path, page_views, is_bounce, entry_title, exit_title, language, country_code, city, referrer, referrer_name, referrer_icon, os, os_version,
-		browser, browser_version, desktop, mobile, screen_width, screen_height, screen_class,
+		entry_path, exit_path, page_views, is_bounce, entry_title, exit_title, language, country_code, city, city_geoname_id, region, postal_code, latitude, longitude, time_zone, asn, as_organization, referrer, referrer_name, referrer_icon, channel, os, os_version,
+		browser, browser_version, desktop, mobile, webview, new_visitor, device_type, device_vendor, device_model, bot_reason, screen_width, screen_height, screen_class,
 		utm_source, utm_medium, utm_campaign, utm_content, utm_term, otm_source, otm_medium, otm_campaign, otm_position) 
-		VALUES (?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?)`)
+		VALUES (?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?)`)
 
 	if err != nil {
 		return err
@@ -147,15 +164,30 @@ func (client *Client) SaveSessions(sessions []Session) error {
 			session.Language,
 			session.CountryCode,
 			session.City,
+			session.CityGeoNameID,
+			session.Region,
+			session.PostalCode,
+			session.Latitude,
+			session.Longitude,
+			session.TimeZone,
+			session.ASN,
+			session.ASOrganization,
 			session.Referrer,
 			session.ReferrerName,
 			session.ReferrerIcon,
+			session.Channel,
 			session.OS,
 			session.OSVersion,
 			session.Browser,
 			session.BrowserVersion,
 			client.boolean(session.Desktop),
 			client.boolean(session.Mobile),
+			client.boolean(session.WebView),
+			client.boolean(session.NewVisitor),
+			session.DeviceType,
+			session.DeviceVendor,
+			session.DeviceModel,
+			session.BotReason,
 			session.ScreenWidth,
 			session.ScreenHeight,
 			session.ScreenClass,
@@ -195,9 +227,9 @@ func (client *Client) SaveEvents(events []Event) error {
 	}
 
 	query, err := tx.Prepare(`INSERT INTO "event" (client_id, visitor_id, time, session_id, event_name, event_meta_keys, event_meta_values, duration_seconds,
-		path, title, language, country_code, city, referrer, referrer_name, referrer_icon, os, os_version,
-		browser, browser_version, desktop, mobile, screen_width, screen_height, screen_class,
-		utm_source, utm_medium, utm_campaign, utm_content, utm_term) VALUES (?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?)`)
+		path, title, language, country_code, city, city_geoname_id, region, postal_code, latitude, longitude, time_zone, asn, as_organization, referrer, referrer_name, referrer_icon, channel, os, os_version,
+		browser, browser_version, desktop, mobile, webview, new_visitor, device_type, device_vendor, device_model, bot_reason, screen_width, screen_height, screen_class,
+		utm_source, utm_medium, utm_campaign, utm_content, utm_term, search_term) VALUES (?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?)`)
 
 	if err != nil {
 		return err
@@ -217,15 +249,30 @@ func (client *Client) SaveEvents(events []Event) error {
 			event.Language,
 			event.CountryCode,
 			event.City,
+			event.CityGeoNameID,
+			event.Region,
+			event.PostalCode,
+			event.Latitude,
+			event.Longitude,
+			event.TimeZone,
+			event.ASN,
+			event.ASOrganization,
 			event.Referrer,
 			event.ReferrerName,
 			event.ReferrerIcon,
+			event.Channel,
 			event.OS,
 			event.OSVersion,
 			event.Browser,
 			event.BrowserVersion,
 			client.boolean(event.Desktop),
 			client.boolean(event.Mobile),
+			client.boolean(event.WebView),
+			client.boolean(event.NewVisitor),
+			event.DeviceType,
+			event.DeviceVendor,
+			event.DeviceModel,
+			event.BotReason,
 			event.ScreenWidth,
 			event.ScreenHeight,
 			event.ScreenClass,
@@ -233,7 +280,8 @@ func (client *Client) SaveEvents(events []Event) error {
 			event.UTMMedium,
 			event.UTMCampaign,
 			event.UTMContent,
-			event.UTMTerm)
+			event.UTMTerm,
+			event.SearchTerm)
 
 		if err != nil {
 			if e := tx.Rollback(); e != nil {
@@ -259,14 +307,14 @@ func (client *Client) SaveUserAgents(userAgents []UserAgent) error {
 		return err
 	}
 
-	query, err := tx.Prepare(`INSERT INTO "user_agent" (time, user_agent) VALUES (?,?)`)
+	query, err := tx.Prepare(`INSERT INTO "user_agent" (time, user_agent, client_id, visitor_id, session_id) VALUES (?,?,?,?,?)`)
 
 	if err != nil {
 		return err
 	}
 
 	for _, ua := range userAgents {
-		_, err := query.Exec(ua.Time, ua.UserAgent)
+		_, err := query.Exec(ua.Time, ua.UserAgent, ua.ClientID, ua.VisitorID, ua.SessionID)
 
 		if err != nil {
 			if e := tx.Rollback(); e != nil {
@@ -284,6 +332,133 @@ func (client *Client) SaveUserAgents(userAgents []UserAgent) error {
 	return nil
 }
 
+// SaveBotHits implements the Store interface.
+func (client *Client) SaveBotHits(hits []BotHit) error {
+	tx, err := client.Beginx()
+
+	if err != nil {
+		return err
+	}
+
+	query, err := tx.Prepare(`INSERT INTO "bot" (client_id, visitor_id, time, bot_reason, user_agent, country_code, path) VALUES (?,?,?,?,?,?,?)`)
+
+	if err != nil {
+		return err
+	}
+
+	for _, hit := range hits {
+		_, err := query.Exec(hit.ClientID,
+			hit.VisitorID,
+			hit.Time,
+			hit.Reason,
+			hit.UserAgent,
+			hit.CountryCode,
+			hit.Path)
+
+		if err != nil {
+			if e := tx.Rollback(); e != nil {
+				client.logger.Printf("error rolling back transaction to save bot hits: %s", err)
+			}
+
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// SaveAndroidApps implements the Store interface.
+func (client *Client) SaveAndroidApps(apps []AndroidApp) error {
+	tx, err := client.Beginx()
+
+	if err != nil {
+		return err
+	}
+
+	query, err := tx.Prepare(`INSERT INTO "android_app" (package_name, name, icon, time) VALUES (?,?,?,?)`)
+
+	if err != nil {
+		return err
+	}
+
+	for _, app := range apps {
+		_, err := query.Exec(app.PackageName,
+			app.Name,
+			app.Icon,
+			app.Time)
+
+		if err != nil {
+			if e := tx.Rollback(); e != nil {
+				client.logger.Printf("error rolling back transaction to save android apps: %s", err)
+			}
+
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// SaveSegments implements the Store interface.
+func (client *Client) SaveSegments(segments []SavedSegment) error {
+	tx, err := client.Beginx()
+
+	if err != nil {
+		return err
+	}
+
+	query, err := tx.Prepare(`INSERT INTO "saved_segment" (client_id, id, name, filter, deleted, time) VALUES (?,?,?,?,?,?)`)
+
+	if err != nil {
+		return err
+	}
+
+	for _, segment := range segments {
+		_, err := query.Exec(segment.ClientID,
+			segment.ID,
+			segment.Name,
+			string(segment.Filter),
+			client.boolean(segment.Deleted),
+			segment.Time)
+
+		if err != nil {
+			if e := tx.Rollback(); e != nil {
+				client.logger.Printf("error rolling back transaction to save segments: %s", err)
+			}
+
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// UpdateReferrerName implements the Store interface.
+// The update is executed as a mutation, so it might take a while until the changes are visible.
+func (client *Client) UpdateReferrerName(referrer []string, name, icon string) error {
+	for _, table := range []string{"session", "page_view", "event"} {
+		query := fmt.Sprintf(`ALTER TABLE "%s" UPDATE referrer_name = ?, referrer_icon = ? WHERE referrer IN (?) AND referrer_name = ''`, table)
+
+		if _, err := client.Exec(query, name, icon, referrer); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 // Session implements the Store interface.
 func (client *Client) Session(clientID, fingerprint uint64, maxAge time.Time) (*Session, error) {
 	query := `SELECT * FROM session WHERE client_id = ? AND visitor_id = ? AND time > ? ORDER BY time DESC LIMIT 1`
@@ -299,6 +474,19 @@ func (client *Client) Session(clientID, fingerprint uint64, maxAge time.Time) (*
 	return session, nil
 }
 
+// HasSession implements the Store interface.
+func (client *Client) HasSession(clientID, fingerprint uint64, maxAge time.Time) (bool, error) {
+	query := `SELECT count(*) FROM (SELECT 1 FROM session WHERE client_id = ? AND visitor_id = ? AND time > ? LIMIT 1)`
+	count := 0
+
+	if err := client.DB.Get(&count, query, clientID, fingerprint, maxAge); err != nil {
+		client.logger.Printf("error looking up session: %s", err)
+		return false, err
+	}
+
+	return count > 0, nil
+}
+
 // Count implements the Store interface.
 func (client *Client) Count(query string, args ...interface{}) (int, error) {
 	count := 0
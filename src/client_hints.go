@@ -0,0 +1,240 @@
+package omisocial
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const (
+	headerSecCHUA                = "Sec-CH-UA"
+	headerSecCHUAMobile          = "Sec-CH-UA-Mobile"
+	headerSecCHUAPlatform        = "Sec-CH-UA-Platform"
+	headerSecCHUAPlatformVersion = "Sec-CH-UA-Platform-Version"
+	headerSecCHUAModel           = "Sec-CH-UA-Model"
+)
+
+// AcceptClientHints is the value for the Accept-CH response header.
+// It asks Chromium based browsers to send the User-Agent Client Hints used to improve the detection of the OS and browser.
+// Sec-CH-UA, Sec-CH-UA-Mobile and Sec-CH-UA-Platform are sent by default, the others must be requested.
+var AcceptClientHints = strings.Join([]string{
+	headerSecCHUA,
+	headerSecCHUAMobile,
+	headerSecCHUAPlatform,
+	headerSecCHUAPlatformVersion,
+	headerSecCHUAModel,
+}, ", ")
+
+// clientHintsBrands maps the brands sent in the Sec-CH-UA header to browsers.
+// Chromium is listed by all Chromium based browsers and is therefore only used if no other brand is known.
+var clientHintsBrands = map[string]string{
+	"Google Chrome":    BrowserChrome,
+	"Microsoft Edge":   BrowserEdge,
+	"Opera":            BrowserOpera,
+	"Samsung Internet": BrowserSamsung,
+	"Yandex":           BrowserYandex,
+}
+
+// clientHintsWebViewBrand is the brand sent by the Android system webview used by in-app browsers.
+const clientHintsWebViewBrand = "Android WebView"
+
+// clientHintsPlatforms maps the Sec-CH-UA-Platform header to operating systems.
+var clientHintsPlatforms = map[string]string{
+	"Windows":     OSWindows,
+	"macOS":       OSMac,
+	"Linux":       OSLinux,
+	"Android":     OSAndroid,
+	"iOS":         OSiOS,
+	"Chrome OS":   OSChromeOS,
+	"Chromium OS": OSChromeOS,
+}
+
+// clientHints contains the information extracted from the User-Agent Client Hints headers.
+// See https://wicg.github.io/ua-client-hints/ for details.
+type clientHints struct {
+	browser         string
+	browserVersion  string
+	os              string
+	platformVersion string
+	mobile          bool
+	model           string
+	webView         bool
+}
+
+// SetAcceptClientHints sets the Accept-CH header on given response.
+// Call this from the endpoint receiving the tracking requests, so that browsers send all client hints on subsequent requests.
+func SetAcceptClientHints(w http.ResponseWriter) {
+	w.Header().Set("Accept-CH", AcceptClientHints)
+}
+
+// UserAgentFromRequest parses the User-Agent header for given request and returns the extracted information.
+// The User-Agent Client Hints are preferred over the User-Agent header, as Chromium based browsers freeze the OS version
+// and device information in the User-Agent string.
+func UserAgentFromRequest(r *http.Request) UserAgent {
+	userAgent := ParseUserAgent(r.UserAgent())
+	hints := getClientHints(r)
+
+	if hints.os != "" {
+		fallback := ""
+
+		if hints.os == userAgent.OS {
+			fallback = userAgent.OSVersion
+		}
+
+		userAgent.OS = hints.os
+		userAgent.OSVersion = getClientHintsOSVersion(hints.os, hints.platformVersion, fallback)
+	}
+
+	if hints.browser != "" && hints.browser != userAgent.Browser {
+		userAgent.Browser = hints.browser
+		userAgent.BrowserVersion = hints.browserVersion
+	}
+
+	if hints.mobile {
+		userAgent.Mobile = true
+	}
+
+	if hints.model != "" {
+		userAgent.Vendor = getDeviceVendor(hints.model)
+		userAgent.Model = hints.model
+	}
+
+	if hints.webView {
+		userAgent.WebView = true
+	}
+
+	return userAgent
+}
+
+func getClientHints(r *http.Request) clientHints {
+	hints := clientHints{
+		mobile: r.Header.Get(headerSecCHUAMobile) == "?1",
+		model:  unquoteClientHint(r.Header.Get(headerSecCHUAModel)),
+	}
+	hints.browser, hints.browserVersion, hints.webView = getClientHintsBrowser(r.Header.Get(headerSecCHUA))
+	hints.os = clientHintsPlatforms[unquoteClientHint(r.Header.Get(headerSecCHUAPlatform))]
+	hints.platformVersion = unquoteClientHint(r.Header.Get(headerSecCHUAPlatformVersion))
+	return hints
+}
+
+// getClientHintsBrowser returns the browser, version, and whether it's a webview for given Sec-CH-UA header.
+// The header is a list of brands and significant versions, like: "Chromium";v="110", "Not A(Brand";v="24", "Google Chrome";v="110".
+// Unknown and made up (GREASE) brands are ignored.
+func getClientHintsBrowser(header string) (string, string, bool) {
+	browser, browserVersion, chromiumVersion := "", "", ""
+	webView := false
+
+	for _, entry := range splitClientHints(header, ',') {
+		params := splitClientHints(entry, ';')
+		brand := unquoteClientHint(params[0])
+		version := ""
+
+		for _, param := range params[1:] {
+			param = strings.TrimSpace(param)
+
+			if strings.HasPrefix(param, "v=") {
+				version = getClientHintsBrowserVersion(unquoteClientHint(param[2:]))
+			}
+		}
+
+		if b, ok := clientHintsBrands[brand]; ok && browser == "" {
+			browser, browserVersion = b, version
+		} else if brand == clientHintsWebViewBrand {
+			webView = true
+		} else if brand == "Chromium" {
+			chromiumVersion = version
+		}
+	}
+
+	// in-app browsers using the webview are detected from the User-Agent header instead
+	if browser == "" && chromiumVersion != "" && !webView {
+		return BrowserChrome, chromiumVersion, false
+	}
+
+	return browser, browserVersion, webView
+}
+
+// getClientHintsBrowserVersion formats the significant version to match the versions extracted from the User-Agent header.
+func getClientHintsBrowserVersion(version string) string {
+	if _, err := strconv.Atoi(version); err != nil {
+		return ""
+	}
+
+	return version + ".0"
+}
+
+// getClientHintsOSVersion maps the Sec-CH-UA-Platform-Version header to the OS version.
+// The version from the User-Agent header is returned as a fallback in case the header is not set.
+func getClientHintsOSVersion(os, version, fallback string) string {
+	if version == "" {
+		return fallback
+	}
+
+	switch os {
+	case OSWindows:
+		return getClientHintsWindowsVersion(version)
+	case OSMac, OSiOS:
+		return getOSVersion(version, 2)
+	}
+
+	return trimOSVersion(getOSVersion(version, 2))
+}
+
+// getClientHintsWindowsVersion maps the Windows platform version to the product version.
+// https://learn.microsoft.com/en-us/microsoft-edge/web-platform/how-to-detect-win11
+func getClientHintsWindowsVersion(version string) string {
+	parts := strings.Split(version, ".")
+	major, err := strconv.Atoi(parts[0])
+
+	if err != nil {
+		return ""
+	}
+
+	if major >= 13 {
+		return "11"
+	} else if major > 0 {
+		return "10"
+	}
+
+	if len(parts) > 1 {
+		switch parts[1] {
+		case "1":
+			return "7"
+		case "2", "3":
+			return "8"
+		}
+	}
+
+	return ""
+}
+
+// trimOSVersion removes trailing zeros from a version number, so that 13.0.0 becomes 13.
+func trimOSVersion(version string) string {
+	for strings.HasSuffix(version, ".0") {
+		version = version[:len(version)-2]
+	}
+
+	return version
+}
+
+// splitClientHints splits a structured header list by given separator, ignoring separators in quoted strings.
+func splitClientHints(header string, sep rune) []string {
+	parts := make([]string, 0, 3)
+	quoted := false
+	start := 0
+
+	for i, r := range header {
+		if r == '"' {
+			quoted = !quoted
+		} else if r == sep && !quoted {
+			parts = append(parts, header[start:i])
+			start = i + 1
+		}
+	}
+
+	return append(parts, header[start:])
+}
+
+func unquoteClientHint(value string) string {
+	return strings.TrimSpace(strings.Trim(strings.TrimSpace(value), `"`))
+}
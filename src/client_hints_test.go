@@ -0,0 +1,139 @@
+package omisocial
+
+import (
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestUserAgentFromRequest(t *testing.T) {
+	// reduced User-Agent, the OS version is frozen to Windows 10
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/110.0.0.0 Safari/537.36 Edg/110.0.1587.57")
+	req.Header.Set("Sec-CH-UA", `"Chromium";v="110", "Not A(Brand";v="24", "Microsoft Edge";v="110"`)
+	req.Header.Set("Sec-CH-UA-Mobile", "?0")
+	req.Header.Set("Sec-CH-UA-Platform", `"Windows"`)
+	req.Header.Set("Sec-CH-UA-Platform-Version", `"15.0.0"`)
+	ua := UserAgentFromRequest(req)
+	assert.Equal(t, OSWindows, ua.OS)
+	assert.Equal(t, "11", ua.OSVersion)
+	assert.Equal(t, BrowserEdge, ua.Browser)
+	assert.Equal(t, "110.0", ua.BrowserVersion)
+	assert.True(t, ua.IsDesktop())
+	assert.False(t, ua.IsMobile())
+	assert.Empty(t, ua.Model)
+
+	// reduced User-Agent, the Android version and model are frozen
+	req = httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("User-Agent", "Mozilla/5.0 (Linux; Android 10; K) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/110.0.0.0 Mobile Safari/537.36")
+	req.Header.Set("Sec-CH-UA", `"Chromium";v="110", "Not A(Brand";v="24", "Google Chrome";v="110"`)
+	req.Header.Set("Sec-CH-UA-Mobile", "?1")
+	req.Header.Set("Sec-CH-UA-Platform", `"Android"`)
+	req.Header.Set("Sec-CH-UA-Platform-Version", `"13.0.0"`)
+	req.Header.Set("Sec-CH-UA-Model", `"SM-A536E"`)
+	ua = UserAgentFromRequest(req)
+	assert.Equal(t, OSAndroid, ua.OS)
+	assert.Equal(t, "13", ua.OSVersion)
+	assert.Equal(t, BrowserChrome, ua.Browser)
+	assert.Equal(t, "110.0", ua.BrowserVersion)
+	assert.False(t, ua.IsDesktop())
+	assert.True(t, ua.IsMobile())
+	assert.Equal(t, "Samsung", ua.Vendor)
+	assert.Equal(t, "SM-A536E", ua.Model)
+
+	// no client hints
+	req = httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("User-Agent", "Mozilla/5.0 (Macintosh; Intel Mac OS X 10.15; rv:79.0) Gecko/20100101 Firefox/79.0")
+	ua = UserAgentFromRequest(req)
+	assert.Equal(t, OSMac, ua.OS)
+	assert.Equal(t, "10.15", ua.OSVersion)
+	assert.Equal(t, BrowserFirefox, ua.Browser)
+	assert.Equal(t, "79.0", ua.BrowserVersion)
+
+	// low entropy hints only
+	req = httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("User-Agent", "Mozilla/5.0 (X11; CrOS x86_64 14541.0.0) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/110.0.0.0 Safari/537.36")
+	req.Header.Set("Sec-CH-UA", `"Chromium";v="110", "Not;A=Brand";v="24"`)
+	req.Header.Set("Sec-CH-UA-Platform", `"Chrome OS"`)
+	ua = UserAgentFromRequest(req)
+	assert.Equal(t, OSChromeOS, ua.OS)
+	assert.Empty(t, ua.OSVersion)
+	assert.Equal(t, BrowserChrome, ua.Browser)
+	assert.Equal(t, "110.0", ua.BrowserVersion)
+	assert.True(t, ua.IsDesktop())
+
+	// in-app browsers are not overwritten by the webview brand
+	req = httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("User-Agent", "Mozilla/5.0 (Linux; Android 12; SM-A515F Build/SP1A.210812.016; wv) AppleWebKit/537.36 (KHTML, like Gecko) Version/4.0 Chrome/110.0.5481.153 Mobile Safari/537.36 [FB_IAB/FB4A;FBAV/403.0.0.27.81;]")
+	req.Header.Set("Sec-CH-UA", `"Chromium";v="110", "Android WebView";v="110", "Not A(Brand";v="24"`)
+	req.Header.Set("Sec-CH-UA-Mobile", "?1")
+	req.Header.Set("Sec-CH-UA-Platform", `"Android"`)
+	ua = UserAgentFromRequest(req)
+	assert.Equal(t, OSAndroid, ua.OS)
+	assert.Equal(t, "12", ua.OSVersion)
+	assert.Equal(t, BrowserFacebook, ua.Browser)
+	assert.Equal(t, "403.0", ua.BrowserVersion)
+	assert.True(t, ua.WebView)
+}
+
+func TestSetAcceptClientHints(t *testing.T) {
+	w := httptest.NewRecorder()
+	SetAcceptClientHints(w)
+	assert.Equal(t, "Sec-CH-UA, Sec-CH-UA-Mobile, Sec-CH-UA-Platform, Sec-CH-UA-Platform-Version, Sec-CH-UA-Model", w.Header().Get("Accept-CH"))
+}
+
+func TestGetClientHintsBrowser(t *testing.T) {
+	input := []string{
+		"",
+		`"Chromium";v="110", "Not A(Brand";v="24", "Google Chrome";v="110"`,
+		`"Not_A Brand";v="99", "Microsoft Edge";v="109", "Chromium";v="109"`,
+		`"Opera";v="95", "Chromium";v="109", "Not;A=Brand";v="24"`,
+		`"Chromium";v="108", "Not?A_Brand";v="8"`,
+		`"Brave";v="110", "Chromium";v="110", "Not A(Brand";v="24"`,
+		`"Not A(Brand";v="24"`,
+		`"Chromium";v="106", "Samsung Internet";v="20", "Not;A=Brand";v="99"`,
+		`"Chromium";v="110", "Android WebView";v="110", "Not A(Brand";v="24"`,
+	}
+	expected := []struct {
+		browser string
+		version string
+		webView bool
+	}{
+		{"", "", false},
+		{BrowserChrome, "110.0", false},
+		{BrowserEdge, "109.0", false},
+		{BrowserOpera, "95.0", false},
+		{BrowserChrome, "108.0", false},
+		{BrowserChrome, "110.0", false},
+		{"", "", false},
+		{BrowserSamsung, "20.0", false},
+		{"", "", true},
+	}
+
+	for i, in := range input {
+		browser, version, webView := getClientHintsBrowser(in)
+		assert.Equal(t, expected[i].browser, browser)
+		assert.Equal(t, expected[i].version, version)
+		assert.Equal(t, expected[i].webView, webView)
+	}
+}
+
+func TestGetClientHintsOSVersion(t *testing.T) {
+	assert.Equal(t, "11", getClientHintsOSVersion(OSWindows, "15.0.0", "10"))
+	assert.Equal(t, "11", getClientHintsOSVersion(OSWindows, "13.0.0", "10"))
+	assert.Equal(t, "10", getClientHintsOSVersion(OSWindows, "10.0.0", "10"))
+	assert.Equal(t, "10", getClientHintsOSVersion(OSWindows, "1.0.0", "10"))
+	assert.Equal(t, "8", getClientHintsOSVersion(OSWindows, "0.3.0", "10"))
+	assert.Equal(t, "7", getClientHintsOSVersion(OSWindows, "0.1.0", "10"))
+	assert.Equal(t, "13.2.1", getClientHintsOSVersion(OSMac, "13.2.1", "10.15.7"))
+	assert.Equal(t, "12", getClientHintsOSVersion(OSAndroid, "12.0.0", "10"))
+	assert.Equal(t, "8.1", getClientHintsOSVersion(OSAndroid, "8.1.0", "10"))
+	assert.Equal(t, "10", getClientHintsOSVersion(OSAndroid, "", "10"))
+}
+
+func TestSplitClientHints(t *testing.T) {
+	assert.Equal(t, []string{""}, splitClientHints("", ','))
+	assert.Equal(t, []string{`"a";v="1"`, ` "b;c,d";v="2"`}, splitClientHints(`"a";v="1", "b;c,d";v="2"`, ','))
+	assert.Equal(t, []string{`"b;c,d"`, `v="2"`}, splitClientHints(`"b;c,d";v="2"`, ';'))
+}
@@ -11,6 +11,9 @@ type ClientMock struct {
 	Sessions      []Session
 	Events        []Event
 	UserAgents    []UserAgent
+	BotHits       []BotHit
+	AndroidApps   []AndroidApp
+	Segments      []SavedSegment
 	ReturnSession *Session
 	m             sync.Mutex
 }
@@ -18,10 +21,13 @@ type ClientMock struct {
 // NewMockClient returns a new mock client.
 func NewMockClient() *ClientMock {
 	return &ClientMock{
-		PageViews:  make([]PageView, 0),
-		Sessions:   make([]Session, 0),
-		Events:     make([]Event, 0),
-		UserAgents: make([]UserAgent, 0),
+		PageViews:   make([]PageView, 0),
+		Sessions:    make([]Session, 0),
+		Events:      make([]Event, 0),
+		UserAgents:  make([]UserAgent, 0),
+		BotHits:     make([]BotHit, 0),
+		AndroidApps: make([]AndroidApp, 0),
+		Segments:    make([]SavedSegment, 0),
 	}
 }
 
@@ -57,6 +63,59 @@ func (client *ClientMock) SaveUserAgents(userAgents []UserAgent) error {
 	return nil
 }
 
+// SaveBotHits implements the Store interface.
+func (client *ClientMock) SaveBotHits(hits []BotHit) error {
+	client.m.Lock()
+	defer client.m.Unlock()
+	client.BotHits = append(client.BotHits, hits...)
+	return nil
+}
+
+// SaveAndroidApps implements the Store interface.
+func (client *ClientMock) SaveAndroidApps(apps []AndroidApp) error {
+	client.m.Lock()
+	defer client.m.Unlock()
+	client.AndroidApps = append(client.AndroidApps, apps...)
+	return nil
+}
+
+// SaveSegments implements the Store interface.
+func (client *ClientMock) SaveSegments(segments []SavedSegment) error {
+	client.m.Lock()
+	defer client.m.Unlock()
+	client.Segments = append(client.Segments, segments...)
+	return nil
+}
+
+// UpdateReferrerName implements the Store interface.
+func (client *ClientMock) UpdateReferrerName(referrer []string, name, icon string) error {
+	client.m.Lock()
+	defer client.m.Unlock()
+
+	for i := range client.Sessions {
+		if client.Sessions[i].ReferrerName == "" && containsString(referrer, client.Sessions[i].Referrer) {
+			client.Sessions[i].ReferrerName = name
+			client.Sessions[i].ReferrerIcon = icon
+		}
+	}
+
+	for i := range client.PageViews {
+		if client.PageViews[i].ReferrerName == "" && containsString(referrer, client.PageViews[i].Referrer) {
+			client.PageViews[i].ReferrerName = name
+			client.PageViews[i].ReferrerIcon = icon
+		}
+	}
+
+	for i := range client.Events {
+		if client.Events[i].ReferrerName == "" && containsString(referrer, client.Events[i].Referrer) {
+			client.Events[i].ReferrerName = name
+			client.Events[i].ReferrerIcon = icon
+		}
+	}
+
+	return nil
+}
+
 // Session implements the Store interface.
 func (client *ClientMock) Session(uint64, uint64, time.Time) (*Session, error) {
 	if client.ReturnSession != nil {
@@ -66,6 +125,11 @@ func (client *ClientMock) Session(uint64, uint64, time.Time) (*Session, error) {
 	return nil, nil
 }
 
+// HasSession implements the Store interface.
+func (client *ClientMock) HasSession(uint64, uint64, time.Time) (bool, error) {
+	return client.ReturnSession != nil, nil
+}
+
 // Count implements the Store interface.
 func (client *ClientMock) Count(string, ...interface{}) (int, error) {
 	return 0, nil
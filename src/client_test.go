@@ -188,6 +188,29 @@ func TestClient_Session(t *testing.T) {
 	assert.Equal(t, uint16(3), session.PageViews)
 }
 
+func TestClient_HasSession(t *testing.T) {
+	cleanupDB()
+	now := time.Now().UTC()
+	assert.NoError(t, dbClient.SaveSessions([]Session{
+		{
+			Sign:      1,
+			ClientID:  1,
+			VisitorID: 1,
+			Time:      now.Add(-time.Hour),
+			SessionID: rand.Uint32(),
+		},
+	}))
+	found, err := dbClient.HasSession(1, 1, now.Add(-time.Hour*2))
+	assert.NoError(t, err)
+	assert.True(t, found)
+	found, err = dbClient.HasSession(1, 1, now.Add(-time.Minute))
+	assert.NoError(t, err)
+	assert.False(t, found)
+	found, err = dbClient.HasSession(1, 2, now.Add(-time.Hour*2))
+	assert.NoError(t, err)
+	assert.False(t, found)
+}
+
 func TestClient_GetNoError(t *testing.T) {
 	cleanupDB()
 	var session Session
@@ -0,0 +1,290 @@
+package omisocial
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+const (
+	// DeviceDesktop is the device type for desktop computers and laptops.
+	DeviceDesktop = "desktop"
+
+	// DeviceMobile is the device type for smartphones.
+	DeviceMobile = "mobile"
+
+	// DeviceTablet is the device type for tablets.
+	DeviceTablet = "tablet"
+
+	// DeviceTV is the device type for smart TVs and streaming devices.
+	DeviceTV = "tv"
+
+	// DeviceConsole is the device type for gaming consoles.
+	DeviceConsole = "console"
+
+	// DeviceBot is the device type for bots and crawlers, including sessions flagged by the BotDetector.
+	DeviceBot = "bot"
+
+	// VendorApple is the device vendor for iPhones, iPads, and iPods.
+	VendorApple = "Apple"
+
+	// used to guess the device type from the screen size if the User-Agent doesn't tell
+	maxMobileScreenShortSide = 600
+	maxTabletScreenLongSide  = 1194
+)
+
+var (
+	// deviceTypes is a list of all known device types.
+	deviceTypes = []string{
+		DeviceDesktop,
+		DeviceMobile,
+		DeviceTablet,
+		DeviceTV,
+		DeviceConsole,
+		DeviceBot,
+	}
+
+	// deviceConsoleKeywords is a list of User-Agent substrings (in lowercase) identifying gaming consoles.
+	deviceConsoleKeywords = []string{
+		"playstation",
+		"xbox",
+		"nintendo",
+	}
+
+	// deviceTVKeywords is a list of User-Agent substrings (in lowercase) identifying smart TVs and streaming devices.
+	deviceTVKeywords = []string{
+		"smarttv",
+		"smart-tv",
+		"smart tv",
+		"googletv",
+		"android tv",
+		"appletv",
+		"apple tv",
+		"hbbtv",
+		"netcast",
+		"web0s",
+		"bravia",
+		"crkey",
+		"roku",
+		"aftb",
+		"aftm",
+		"aftt",
+		"afts",
+		"; tv",
+	}
+
+	// deviceTabletKeywords is a list of User-Agent substrings (in lowercase) identifying tablets.
+	deviceTabletKeywords = []string{
+		"ipad",
+		"tablet",
+		"kindle",
+		"silk/",
+		"playbook",
+	}
+)
+
+// deviceVendorPrefixes maps model name prefixes (in lowercase) to the device vendor.
+// The list is checked in order, so that more specific prefixes must be listed first.
+var deviceVendorPrefixes = []struct {
+	prefix string
+	vendor string
+}{
+	{"samsung", "Samsung"},
+	{"sm-", "Samsung"},
+	{"gt-", "Samsung"},
+	{"sch-", "Samsung"},
+	{"sgh-", "Samsung"},
+	{"redmi", "Xiaomi"},
+	{"poco", "Xiaomi"},
+	{"xiaomi", "Xiaomi"},
+	{"mi ", "Xiaomi"},
+	{"oppo", "OPPO"},
+	{"cph", "OPPO"},
+	{"pch", "OPPO"},
+	{"realme", "realme"},
+	{"rmx", "realme"},
+	{"vivo", "vivo"},
+	{"oneplus", "OnePlus"},
+	{"huawei", "Huawei"},
+	{"honor", "Honor"},
+	{"pixel", "Google"},
+	{"nexus", "Google"},
+	{"moto", "Motorola"},
+	{"nokia", "Nokia"},
+	{"lm-", "LG"},
+	{"lg-", "LG"},
+	{"sony", "Sony"},
+	{"asus", "Asus"},
+	{"infinix", "Infinix"},
+	{"tecno", "Tecno"},
+	{"itel", "itel"},
+	{"vsmart", "Vsmart"},
+	{"kindle", "Amazon"},
+}
+
+// deviceVendorModelNumbers matches model numbers (in lowercase) without a vendor name to the device vendor.
+// They are checked after the deviceVendorPrefixes and must match the whole model.
+var deviceVendorModelNumbers = []struct {
+	pattern *regexp.Regexp
+	vendor  string
+}{
+	{regexp.MustCompile(`^m?2\d(0[1-9]|1[0-2])[0-9a-z]{2,6}[a-z]$`), "Xiaomi"}, // year and month of release, like 2201117TG or M2101K6G
+	{regexp.MustCompile(`^v[12]\d{3}[a-z]?$`), "vivo"},                         // like V2027 or V1901A
+	{regexp.MustCompile(`^xt\d{4}(-\d{1,2})?$`), "Motorola"},                   // like XT2041-3
+	{regexp.MustCompile(`^kf[a-z]{2,4}$`), "Amazon"},                           // Kindle Fire, like KFTT or KFMUWI
+}
+
+// DeviceType returns the device type for the user agent and screen size (in pixels, optional).
+// The User-Agent is preferred, the screen size is used as a fallback in case the device could not be identified.
+// An empty string is returned if the device type is unknown.
+func (ua *UserAgent) DeviceType(screenWidth, screenHeight uint16) string {
+	userAgent := strings.ToLower(ua.UserAgent)
+
+	if isBotUserAgent(userAgent) {
+		return DeviceBot
+	} else if containsAny(userAgent, deviceConsoleKeywords) {
+		return DeviceConsole
+	} else if containsAny(userAgent, deviceTVKeywords) {
+		return DeviceTV
+	} else if containsAny(userAgent, deviceTabletKeywords) {
+		return DeviceTablet
+	}
+
+	// Android tablets don't send "Mobile" in the User-Agent header or Sec-CH-UA-Mobile client hint
+	if ua.OS == OSAndroid && !ua.Mobile && !strings.Contains(userAgent, "mobile") {
+		return DeviceTablet
+	}
+
+	if ua.IsMobile() {
+		return DeviceMobile
+	} else if ua.IsDesktop() {
+		return DeviceDesktop
+	}
+
+	return getDeviceTypeFromScreen(screenWidth, screenHeight)
+}
+
+func getDeviceTypeFromScreen(width, height uint16) string {
+	if width == 0 || height == 0 {
+		return ""
+	}
+
+	shortSide, longSide := width, height
+
+	if shortSide > longSide {
+		shortSide, longSide = longSide, shortSide
+	}
+
+	if shortSide < maxMobileScreenShortSide {
+		return DeviceMobile
+	} else if longSide <= maxTabletScreenLongSide {
+		return DeviceTablet
+	}
+
+	return DeviceDesktop
+}
+
+// getDevice returns the device vendor and model from the system information of the User-Agent header.
+// Android devices send the model (like "SM-A515F Build/SP1A.210812.016"), iOS devices only send the device family.
+func getDevice(system []string, os string) (string, string) {
+	switch os {
+	case OSiOS:
+		for _, sys := range system {
+			if sys == "iPhone" || sys == "iPad" || strings.HasPrefix(sys, "iPod") {
+				return VendorApple, strings.Fields(sys)[0]
+			}
+		}
+	case OSAndroid:
+		model := getAndroidModel(system)
+		return getDeviceVendor(model), strings.TrimPrefix(strings.TrimPrefix(model, "SAMSUNG "), "Samsung ")
+	}
+
+	return "", ""
+}
+
+func getAndroidModel(system []string) string {
+	android := false
+
+	for _, sys := range system {
+		if strings.HasPrefix(sys, "Android") {
+			android = true
+		} else if android && !ignoreAndroidModel(sys) {
+			if i := strings.Index(sys, "Build/"); i > -1 {
+				sys = sys[:i]
+			}
+
+			return strings.TrimSpace(sys)
+		}
+	}
+
+	return ""
+}
+
+// ignoreAndroidModel returns true for system entries that follow the Android version, but are not a model name.
+// "K" is sent by browsers freezing the User-Agent, the model is sent through client hints instead.
+func ignoreAndroidModel(sys string) bool {
+	switch sys {
+	case "K", "U", "wv", "Mobile", "Tablet", "Linux":
+		return true
+	}
+
+	// language tags like "en-US" or "vi_VN"
+	if len(sys) == 5 && (sys[2] == '-' || sys[2] == '_') && unicode.IsLower(rune(sys[0])) {
+		return true
+	}
+
+	return strings.HasPrefix(sys, "Build/") || strings.HasPrefix(sys, "rv:")
+}
+
+// getDeviceVendor returns the vendor for given device model, or else an empty string.
+func getDeviceVendor(model string) string {
+	if model == "" {
+		return ""
+	}
+
+	if model == "iPhone" || model == "iPad" || model == "iPod" {
+		return VendorApple
+	}
+
+	model = strings.ToLower(model)
+
+	for _, vendor := range deviceVendorPrefixes {
+		if strings.HasPrefix(model, vendor.prefix) {
+			return vendor.vendor
+		}
+	}
+
+	for _, vendor := range deviceVendorModelNumbers {
+		if vendor.pattern.MatchString(model) {
+			return vendor.vendor
+		}
+	}
+
+	return ""
+}
+
+// deviceTypeFields returns the column names used for the device types in the platform statistics.
+func deviceTypeFields() []string {
+	fields := make([]string, 0, len(deviceTypes))
+
+	for _, deviceType := range deviceTypes {
+		fields = append(fields, "device_"+deviceType)
+	}
+
+	return fields
+}
+
+// isBotUserAgent returns true if given User-Agent contains a bot keyword.
+func isBotUserAgent(userAgent string) bool {
+	return getBlacklists().IgnoreUserAgent(userAgent)
+}
+
+func containsAny(str string, keywords []string) bool {
+	for _, keyword := range keywords {
+		if strings.Contains(str, keyword) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,128 @@
+package omisocial
+
+import (
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestUserAgent_DeviceType(t *testing.T) {
+	input := []struct {
+		ua     string
+		width  uint16
+		height uint16
+	}{
+		{"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/110.0.0.0 Safari/537.36", 1920, 1080},
+		{"Mozilla/5.0 (Linux; Android 13; SM-S908E) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/110.0.0.0 Mobile Safari/537.36", 0, 0},
+		{"Mozilla/5.0 (iPhone; CPU iPhone OS 16_3 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/16.3 Mobile/15E148 Safari/604.1", 390, 844},
+		{"Mozilla/5.0 (iPad; CPU OS 16_3 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/16.3 Mobile/15E148 Safari/604.1", 820, 1180},
+		{"Mozilla/5.0 (Linux; Android 12; SM-X700) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/110.0.0.0 Safari/537.36", 0, 0},
+		{"Mozilla/5.0 (SMART-TV; LINUX; Tizen 6.0) AppleWebKit/537.36 (KHTML, like Gecko) 76.0.3809.146/6.0 TV Safari/537.36", 1920, 1080},
+		{"Mozilla/5.0 (Linux; Android 9; BRAVIA 4K UR2 Build/PTT1.190515.001.S52) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/110.0.0.0 Safari/537.36", 0, 0},
+		{"Mozilla/5.0 (PlayStation; PlayStation 5/2.26) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/13.0 Safari/605.1.15", 0, 0},
+		{"Mozilla/5.0 (Windows NT 10.0; Win64; x64; Xbox; Xbox One) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/110.0.0.0 Safari/537.36 Edge/44.18363.8131", 0, 0},
+		{"Mozilla/5.0 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)", 0, 0},
+		{"Mozilla/5.0 (Unknown) Unknown/1.0", 0, 0},
+		{"Mozilla/5.0 (Unknown) Unknown/1.0", 375, 667},
+		{"Mozilla/5.0 (Unknown) Unknown/1.0", 1024, 768},
+		{"Mozilla/5.0 (Unknown) Unknown/1.0", 2560, 1440},
+	}
+	expected := []string{
+		DeviceDesktop,
+		DeviceMobile,
+		DeviceMobile,
+		DeviceTablet,
+		DeviceTablet,
+		DeviceTV,
+		DeviceTV,
+		DeviceConsole,
+		DeviceConsole,
+		DeviceBot,
+		"",
+		DeviceMobile,
+		DeviceTablet,
+		DeviceDesktop,
+	}
+
+	for i, in := range input {
+		ua := ParseUserAgent(in.ua)
+		assert.Equal(t, expected[i], ua.DeviceType(in.width, in.height), in.ua)
+	}
+}
+
+func TestUserAgent_DeviceTypeClientHints(t *testing.T) {
+	// the reduced User-Agent of Android tablets is identical to phones, except for the "Mobile" token
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("User-Agent", "Mozilla/5.0 (Linux; Android 10; K) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/110.0.0.0 Mobile Safari/537.36")
+	req.Header.Set("Sec-CH-UA-Mobile", "?1")
+	req.Header.Set("Sec-CH-UA-Platform", `"Android"`)
+	ua := UserAgentFromRequest(req)
+	assert.Equal(t, DeviceMobile, ua.DeviceType(0, 0))
+	req.Header.Set("User-Agent", "Mozilla/5.0 (Linux; Android 10; K) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/110.0.0.0 Safari/537.36")
+	req.Header.Set("Sec-CH-UA-Mobile", "?0")
+	ua = UserAgentFromRequest(req)
+	assert.Equal(t, DeviceTablet, ua.DeviceType(0, 0))
+}
+
+func TestGetDevice(t *testing.T) {
+	input := []string{
+		"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/110.0.0.0 Safari/537.36",
+		"Mozilla/5.0 (Linux; Android 12; SM-A515F Build/SP1A.210812.016; wv) AppleWebKit/537.36 (KHTML, like Gecko) Version/4.0 Chrome/110.0.5481.153 Mobile Safari/537.36",
+		"Mozilla/5.0 (Linux; Android 13; SAMSUNG SM-S908E) AppleWebKit/537.36 (KHTML, like Gecko) SamsungBrowser/20.0 Chrome/106.0.5249.126 Mobile Safari/537.36",
+		"Mozilla/5.0 (Linux; Android 10; Redmi Note 8 Build/QKQ1.200114.002; wv) AppleWebKit/537.36 (KHTML, like Gecko) Version/4.0 Chrome/110.0.5481.153 Mobile Safari/537.36",
+		"Mozilla/5.0 (Linux; Android 12; CPH2205 Build/SP1A.210812.016; wv) AppleWebKit/537.36 (KHTML, like Gecko) Version/4.0 Chrome/110.0.5481.153 Mobile Safari/537.36",
+		"Mozilla/5.0 (Linux; U; Android 10; en-US; RMX2185 Build/QP1A.190711.020) AppleWebKit/537.36 (KHTML, like Gecko) Version/4.0 Chrome/78.0.3904.108 UCBrowser/13.4.0.1306 Mobile Safari/537.36",
+		"Mozilla/5.0 (Linux; Android 13; Pixel 7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/110.0.0.0 Mobile Safari/537.36",
+		"Mozilla/5.0 (Linux; Android 10; K) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/110.0.0.0 Mobile Safari/537.36",
+		"Mozilla/5.0 (iPhone; CPU iPhone OS 16_3 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/16.3 Mobile/15E148 Safari/604.1",
+		"Mozilla/5.0 (iPad; CPU OS 16_3 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/16.3 Mobile/15E148 Safari/604.1",
+	}
+	expected := []struct {
+		vendor string
+		model  string
+	}{
+		{"", ""},
+		{"Samsung", "SM-A515F"},
+		{"Samsung", "SM-S908E"},
+		{"Xiaomi", "Redmi Note 8"},
+		{"OPPO", "CPH2205"},
+		{"realme", "RMX2185"},
+		{"Google", "Pixel 7"},
+		{"", ""},
+		{VendorApple, "iPhone"},
+		{VendorApple, "iPad"},
+	}
+
+	for i, in := range input {
+		ua := ParseUserAgent(in)
+		assert.Equal(t, expected[i].vendor, ua.Vendor, in)
+		assert.Equal(t, expected[i].model, ua.Model, in)
+	}
+}
+
+func TestGetDeviceVendor(t *testing.T) {
+	expected := map[string]string{
+		"2201117TG":  "Xiaomi",
+		"23021RAAEG": "Xiaomi",
+		"M2101K6G":   "Xiaomi",
+		"V2027":      "vivo",
+		"V1901A":     "vivo",
+		"XT2041-3":   "Motorola",
+		"KFTT":       "Amazon",
+		"KFMUWI":     "Amazon",
+		"M2":         "",
+		"22":         "",
+		"2345":       "",
+		"V10":        "",
+		"V20 ThinQ":  "",
+		"XT":         "",
+		"XTouch 5":   "",
+		"KF":         "",
+		"KFC-Tab1":   "",
+	}
+
+	for model, vendor := range expected {
+		assert.Equal(t, vendor, getDeviceVendor(model), model)
+	}
+}
@@ -19,11 +19,135 @@ const (
 	// Unknown filters for an unknown (empty) value.
 	// This is a synonym for "null".
 	Unknown = "null"
+
+	// ComparePrevious compares to the period of the same length right before the selected period or day.
+	ComparePrevious = "previous"
+
+	// CompareYear compares to the same period or day one year before.
+	CompareYear = "year"
+
+	// CompareCustom compares to the period set by Filter.CompareFrom and Filter.CompareTo.
+	CompareCustom = "custom"
+
+	// FilterIn matches any of the condition values.
+	FilterIn = "in"
+
+	// FilterNotIn matches none of the condition values.
+	FilterNotIn = "not_in"
+
+	// FilterContains matches if the field contains any of the condition values (case-insensitive).
+	FilterContains = "contains"
+
+	// FilterNotContains matches if the field contains none of the condition values (case-insensitive).
+	FilterNotContains = "not_contains"
+
+	// FilterPrefix matches if the field starts with any of the condition values.
+	FilterPrefix = "prefix"
+
+	// FilterRegex matches if the field matches any of the (ClickHouse supported) regex patterns.
+	FilterRegex = "regex"
+
+	// FilterNotRegex matches if the field matches none of the (ClickHouse supported) regex patterns.
+	FilterNotRegex = "not_regex"
+
+	// SegmentVisitor restricts the results to visitors who did (not) trigger the event or visit the path in the selected period.
+	SegmentVisitor = "visitor"
+
+	// SegmentSession restricts the results to sessions that did (not) trigger the event or visit the path.
+	SegmentSession = "session"
+)
+
+var (
+	// conditionFields are the fields that can be used in a Condition and the columns they are compared to.
+	conditionFields = map[string]string{
+		"path":            "path",
+		"title":           "title",
+		"language":        "language",
+		"country_code":    "country_code",
+		"city":            "city",
+		"region":          "region",
+		"postal_code":     "postal_code",
+		"time_zone":       "time_zone",
+		"asn":             "if(asn = 0, '', toString(asn))",
+		"as_organization": "as_organization",
+		"referrer":        "referrer",
+		"referrer_name":   "referrer_name",
+		"channel":         "channel",
+		"os":              "os",
+		"os_version":      "os_version",
+		"browser":         "browser",
+		"browser_version": "browser_version",
+		"device_type":     "device_type",
+		"device_vendor":   "device_vendor",
+		"device_model":    "device_model",
+		"screen_class":    "screen_class",
+		"utm_source":      "utm_source",
+		"utm_medium":      "utm_medium",
+		"utm_campaign":    "utm_campaign",
+		"utm_content":     "utm_content",
+		"utm_term":        "utm_term",
+	}
+
+	// conditionTextFields are the fields that can be used with the text operators (like FilterContains).
+	conditionTextFields = []string{
+		"path",
+		"title",
+		"referrer",
+		"referrer_name",
+		"utm_source",
+		"utm_medium",
+		"utm_campaign",
+		"utm_content",
+		"utm_term",
+	}
+
+	// conditionPageFields are the fields stored for page views and events, but not sessions.
+	conditionPageFields = []string{
+		"path",
+		"title",
+	}
 )
 
 // NullClient is a placeholder for no client (0).
 var NullClient = int64(0)
 
+// Condition filters a field for multiple values or using a text operator.
+type Condition struct {
+	// Field is the name of the field to filter (like country_code or utm_source).
+	// Can be path, title, language, country_code, city, region, postal_code, time_zone, asn, as_organization,
+	// referrer, referrer_name, channel, os, os_version, browser, browser_version, device_type, device_vendor, device_model,
+	// screen_class, or one of the utm_ fields.
+	Field string
+
+	// Operator is the comparison (like FilterIn).
+	// The text operators (FilterContains, FilterNotContains, FilterPrefix, FilterRegex, and FilterNotRegex)
+	// can only be used for the path, title, referrer, referrer_name, and utm_ fields.
+	Operator string
+
+	// Values are the values the field is compared to.
+	// The condition matches if any of the values match, or none of them for the negated operators.
+	// To compare to none/unknown/empty, set a value to "null" (case-insensitive) for FilterIn and FilterNotIn.
+	Values []string
+}
+
+// Segment restricts the results to visitors or sessions by what they did in the selected period.
+// Unlike the Filter.EventName and Filter.Path, a segment doesn't change the table a report is based on,
+// so that the sessions of visitors who triggered an event can be analyzed for example.
+type Segment struct {
+	// Scope is SegmentVisitor or SegmentSession.
+	// Set to SegmentVisitor by default.
+	Scope string
+
+	// EventName is the event that must have been triggered.
+	EventName string
+
+	// Path is the path that must have been visited, or the path the event must have been triggered on if EventName is set too.
+	Path string
+
+	// Exclude inverts the segment to visitors or sessions that did not trigger the event or visit the path.
+	Exclude bool
+}
+
 // Filter are all fields that can be used to filter the result sets.
 // Fields can be inverted by adding a "!" in front of the string.
 // To compare to none/unknown/empty, set the value to "null" (case-insensitive).
@@ -47,6 +171,18 @@ type Filter struct {
 	// Start is the start date and time of the selected period.
 	Start time.Time
 
+	// Compare sets the period the results are compared to (ComparePrevious, CompareYear, or CompareCustom).
+	// Breakdown reports (pages, referrers, countries, ...) add the visitor count of the comparison period and the change per row,
+	// Analyzer.Visitors adds the comparison series aligned to the selected period.
+	// The comparison requires the From and To or Day to be set and is ignored otherwise.
+	Compare string
+
+	// CompareFrom is the start date of the comparison period for CompareCustom.
+	CompareFrom time.Time
+
+	// CompareTo is the end date of the comparison period for CompareCustom.
+	CompareTo time.Time
+
 	// Path filters for the path.
 	// Note that if this and PathPattern are both set, Path will be preferred.
 	Path string
@@ -75,12 +211,30 @@ type Filter struct {
 	// City filters for the city name.
 	City string
 
+	// Region filters for the region (subdivision) name.
+	Region string
+
+	// PostalCode filters for the postal code.
+	PostalCode string
+
+	// TimeZone filters for the time zone of the visitor's location.
+	TimeZone string
+
+	// ASN filters for the autonomous system number.
+	ASN string
+
+	// ASOrganization filters for the organization the autonomous system is registered to.
+	ASOrganization string
+
 	// Referrer filters for the full referrer.
 	Referrer string
 
 	// ReferrerName filters for the referrer name.
 	ReferrerName string
 
+	// Channel filters for the traffic channel (like Organic Search, see ChannelRule).
+	Channel string
+
 	// OS filters for the operating system.
 	OS string
 
@@ -96,6 +250,28 @@ type Filter struct {
 	// Platform filters for the platform (desktop, mobile, unknown).
 	Platform string
 
+	// DeviceType filters for the device type (desktop, mobile, tablet, tv, console, bot).
+	DeviceType string
+
+	// DeviceVendor filters for the device vendor.
+	DeviceVendor string
+
+	// DeviceModel filters for the device model.
+	DeviceModel string
+
+	// BotReason filters for the reason traffic has been flagged as bot traffic.
+	// Flagged sessions are excluded by default, unless IncludeBots is set or a BotReason is given.
+	BotReason string
+
+	// IncludeBots includes sessions flagged as bot traffic (see BotDetector) in the results.
+	IncludeBots bool
+
+	// WebView filters for in-app browsers and embedded webviews ("true" or "false").
+	WebView string
+
+	// NewVisitor filters for new ("true") or returning ("false") visitors (see HitOptions.ReturningVisitorMaxAge).
+	NewVisitor string
+
 	// ScreenClass filters for the screen class.
 	ScreenClass string
 
@@ -117,16 +293,35 @@ type Filter struct {
 	// EventName filters for an event by its name.
 	EventName string
 
+	// Conditions are additional filters for multiple values (like country_code in vn, th, id)
+	// or text operators (like utm_source contains facebook), combined with the other fields.
+	// Invalid conditions are ignored.
+	Conditions []Condition
+
+	// Segments restrict the results to visitors or sessions that did (not) trigger an event or visit a path.
+	// Segments without an event name and path are ignored.
+	Segments []Segment
+
 	// EventMetaKey filters for an event meta key.
 	// This must be used together with an EventName.
 	EventMetaKey string
 
+	// Locale sets the locale (like "vi") used to add country, city, and language names to the geo and language reports.
+	// The names are looked up using the Localizer set by SetLocalizer and are left empty if not set.
+	Locale string
+
 	// Limit limits the number of results. Less or equal to zero means no limit.
 	Limit int
 
 	// Offset the number of result.
 	Offset int
 
+	// Other adds a row aggregating all results past the Limit (and Offset) to breakdowns (like Analyzer.Countries),
+	// so that the relative values add up. The row is appended last, flagged as Other, and the grouped fields are set to their zero value (like an empty string).
+	// This has no effect without a Limit, when comparing periods, or for page breakdowns (like Analyzer.Pages),
+	// as visitors viewing more than one page cannot be summed up.
+	Other bool
+
 	// IncludeTitle indicates that the Analyzer.Pages, Analyzer.EntryPages, and Analyzer.ExitPages should contain the page title.
 	IncludeTitle bool
 
@@ -191,11 +386,91 @@ func (filter *Filter) validate() {
 		filter.PathPattern = ""
 	}
 
+	if len(filter.Conditions) > 0 {
+		conditions := make([]Condition, 0, len(filter.Conditions))
+
+		for _, condition := range filter.Conditions {
+			if condition.valid() {
+				conditions = append(conditions, condition)
+			}
+		}
+
+		filter.Conditions = conditions
+	}
+
+	if len(filter.Segments) > 0 {
+		segments := make([]Segment, 0, len(filter.Segments))
+
+		for _, segment := range filter.Segments {
+			if segment.EventName != "" || segment.Path != "" {
+				if segment.Scope != SegmentSession {
+					segment.Scope = SegmentVisitor
+				}
+
+				segments = append(segments, segment)
+			}
+		}
+
+		filter.Segments = segments
+	}
+
 	if filter.Limit < 0 {
 		filter.Limit = 0
 	}
 }
 
+// comparisonFilter returns a copy of the filter for the comparison period set by Filter.Compare.
+// It returns nil in case no comparison was requested or the period can't be compared.
+func (filter *Filter) comparisonFilter() *Filter {
+	return filter.comparePeriod(filter.Compare)
+}
+
+// comparePeriod returns a copy of the filter for given comparison mode, without limit and offset.
+func (filter *Filter) comparePeriod(compare string) *Filter {
+	if filter.Day.IsZero() && (filter.From.IsZero() || filter.To.IsZero()) {
+		return nil
+	}
+
+	previous := *filter
+	previous.Compare = ""
+	previous.CompareFrom = time.Time{}
+	previous.CompareTo = time.Time{}
+	previous.Start = time.Time{}
+	previous.Limit = 0
+	previous.Offset = 0
+
+	switch compare {
+	case ComparePrevious:
+		if filter.Day.IsZero() {
+			days := filter.To.Sub(filter.From)
+			previous.To = filter.From.Add(-time.Hour * 24)
+			previous.From = previous.To.Add(-days)
+		} else {
+			previous.Day = filter.Day.Add(-time.Hour * 24)
+		}
+	case CompareYear:
+		if filter.Day.IsZero() {
+			previous.From = filter.From.AddDate(-1, 0, 0)
+			previous.To = filter.To.AddDate(-1, 0, 0)
+		} else {
+			previous.Day = filter.Day.AddDate(-1, 0, 0)
+		}
+	case CompareCustom:
+		if filter.CompareFrom.IsZero() || filter.CompareTo.IsZero() {
+			return nil
+		}
+
+		previous.From = filter.CompareFrom
+		previous.To = filter.CompareTo
+		previous.Day = time.Time{}
+	default:
+		return nil
+	}
+
+	previous.validate()
+	return &previous
+}
+
 func (filter *Filter) table() string {
 	if filter.EventName != "" || filter.eventFilter {
 		return "event"
@@ -247,12 +522,22 @@ func (filter *Filter) queryFields() ([]interface{}, string) {
 	filter.appendQuery(&queryFields, &args, "language", filter.Language)
 	filter.appendQuery(&queryFields, &args, "country_code", filter.Country)
 	filter.appendQuery(&queryFields, &args, "city", filter.City)
+	filter.appendQuery(&queryFields, &args, "region", filter.Region)
+	filter.appendQuery(&queryFields, &args, "postal_code", filter.PostalCode)
+	filter.appendQuery(&queryFields, &args, "time_zone", filter.TimeZone)
+	filter.appendQuery(&queryFields, &args, "if(asn = 0, '', toString(asn))", filter.ASN)
+	filter.appendQuery(&queryFields, &args, "as_organization", filter.ASOrganization)
 	filter.appendQuery(&queryFields, &args, "referrer", filter.Referrer)
 	filter.appendQuery(&queryFields, &args, "referrer_name", filter.ReferrerName)
+	filter.appendQuery(&queryFields, &args, "channel", filter.Channel)
 	filter.appendQuery(&queryFields, &args, "os", filter.OS)
 	filter.appendQuery(&queryFields, &args, "os_version", filter.OSVersion)
 	filter.appendQuery(&queryFields, &args, "browser", filter.Browser)
 	filter.appendQuery(&queryFields, &args, "browser_version", filter.BrowserVersion)
+	filter.appendQuery(&queryFields, &args, "device_type", filter.DeviceType)
+	filter.appendQuery(&queryFields, &args, "device_vendor", filter.DeviceVendor)
+	filter.appendQuery(&queryFields, &args, "device_model", filter.DeviceModel)
+	filter.appendQuery(&queryFields, &args, "bot_reason", filter.botReason())
 	filter.appendQuery(&queryFields, &args, "screen_class", filter.ScreenClass)
 	filter.appendQuery(&queryFields, &args, "utm_source", filter.UTMSource)
 	filter.appendQuery(&queryFields, &args, "utm_medium", filter.UTMMedium)
@@ -260,7 +545,11 @@ func (filter *Filter) queryFields() ([]interface{}, string) {
 	filter.appendQuery(&queryFields, &args, "utm_content", filter.UTMContent)
 	filter.appendQuery(&queryFields, &args, "utm_term", filter.UTMTerm)
 	filter.appendQuery(&queryFields, &args, "event_name", filter.EventName)
+	filter.queryConditions(&queryFields, &args, false)
+	filter.querySegments(&queryFields, &args)
 	filter.queryPlatform(&queryFields)
+	filter.queryBoolean(&queryFields, "webview", filter.WebView)
+	filter.queryBoolean(&queryFields, "new_visitor", filter.NewVisitor)
 	filter.queryPathPattern(&queryFields, &args)
 	return args, strings.Join(queryFields, "AND ")
 }
@@ -271,9 +560,96 @@ func (filter *Filter) queryPageOrEvent() ([]interface{}, string) {
 	filter.appendQuery(&queryFields, &args, "path", filter.Path)
 	filter.appendQuery(&queryFields, &args, "event_name", filter.EventName)
 	filter.queryPathPattern(&queryFields, &args)
+	filter.queryConditions(&queryFields, &args, true)
 	return args, strings.Join(queryFields, "AND ")
 }
 
+func (filter *Filter) queryConditions(queryFields *[]string, args *[]interface{}, pageOnly bool) {
+	for i := range filter.Conditions {
+		if !pageOnly || containsString(conditionPageFields, filter.Conditions[i].Field) {
+			*queryFields = append(*queryFields, filter.queryCondition(&filter.Conditions[i], args))
+		}
+	}
+}
+
+func (filter *Filter) queryCondition(condition *Condition, args *[]interface{}) string {
+	column := conditionFields[condition.Field]
+
+	if condition.Operator == FilterIn || condition.Operator == FilterNotIn {
+		operator := "IN"
+
+		if condition.Operator == FilterNotIn {
+			operator = "NOT IN"
+		}
+
+		placeholders := make([]string, 0, len(condition.Values))
+
+		for _, value := range condition.Values {
+			*args = append(*args, filter.nullValue(value))
+			placeholders = append(placeholders, "?")
+		}
+
+		return fmt.Sprintf("%s %s (%s) ", column, operator, strings.Join(placeholders, ","))
+	}
+
+	var comparison, join string
+
+	switch condition.Operator {
+	case FilterContains:
+		comparison, join = "positionCaseInsensitive(%s, ?) > 0", " OR "
+	case FilterNotContains:
+		comparison, join = "positionCaseInsensitive(%s, ?) = 0", " AND "
+	case FilterPrefix:
+		comparison, join = "startsWith(%s, ?)", " OR "
+	case FilterRegex:
+		comparison, join = "match(%s, ?) = 1", " OR "
+	case FilterNotRegex:
+		comparison, join = "match(%s, ?) = 0", " AND "
+	}
+
+	comparisons := make([]string, 0, len(condition.Values))
+
+	for _, value := range condition.Values {
+		*args = append(*args, value)
+		comparisons = append(comparisons, fmt.Sprintf(comparison, column))
+	}
+
+	return fmt.Sprintf("(%s) ", strings.Join(comparisons, join))
+}
+
+func (filter *Filter) querySegments(queryFields *[]string, args *[]interface{}) {
+	for _, segment := range filter.Segments {
+		timeArgs, timeQuery := filter.queryTime()
+		*args = append(*args, timeArgs...)
+		table := "page_view"
+
+		if segment.EventName != "" {
+			table = "event"
+			*args = append(*args, segment.EventName)
+			timeQuery += "AND event_name = ? "
+		}
+
+		if segment.Path != "" {
+			*args = append(*args, segment.Path)
+			timeQuery += "AND path = ? "
+		}
+
+		fields := "visitor_id"
+
+		if segment.Scope == SegmentSession {
+			fields = "visitor_id, session_id"
+		}
+
+		operator := "IN"
+
+		if segment.Exclude {
+			operator = "NOT IN"
+		}
+
+		*queryFields = append(*queryFields, fmt.Sprintf("(%s) %s (SELECT %s FROM %s WHERE %s) ", fields, operator, fields, table, timeQuery))
+	}
+}
+
 func (filter *Filter) queryPlatform(queryFields *[]string) {
 	if filter.Platform != "" {
 		if strings.HasPrefix(filter.Platform, "!") {
@@ -298,14 +674,31 @@ func (filter *Filter) queryPlatform(queryFields *[]string) {
 	}
 }
 
+func (filter *Filter) queryBoolean(queryFields *[]string, field, value string) {
+	if value != "" {
+		value = strings.ToLower(value)
+		invert := strings.HasPrefix(value, "!")
+
+		if invert {
+			value = value[1:]
+		}
+
+		if (value == "true") != invert {
+			*queryFields = append(*queryFields, fmt.Sprintf("%s = 1 ", field))
+		} else {
+			*queryFields = append(*queryFields, fmt.Sprintf("%s = 0 ", field))
+		}
+	}
+}
+
 func (filter Filter) queryPathPattern(queryFields *[]string, args *[]interface{}) {
 	if filter.PathPattern != "" {
 		if strings.HasPrefix(filter.PathPattern, "!") {
 			*args = append(*args, filter.PathPattern[1:])
-			*queryFields = append(*queryFields, `match("path", ?) = 0`)
+			*queryFields = append(*queryFields, `match("path", ?) = 0 `)
 		} else {
 			*args = append(*args, filter.PathPattern)
-			*queryFields = append(*queryFields, `match("path", ?) = 1`)
+			*queryFields = append(*queryFields, `match("path", ?) = 1 `)
 		}
 	}
 }
@@ -323,12 +716,22 @@ func (filter *Filter) fields() string {
 	filter.appendField(&fields, "language", filter.Language)
 	filter.appendField(&fields, "country_code", filter.Country)
 	filter.appendField(&fields, "city", filter.City)
+	filter.appendField(&fields, "region", filter.Region)
+	filter.appendField(&fields, "postal_code", filter.PostalCode)
+	filter.appendField(&fields, "time_zone", filter.TimeZone)
+	filter.appendField(&fields, "asn", filter.ASN)
+	filter.appendField(&fields, "as_organization", filter.ASOrganization)
 	filter.appendField(&fields, "referrer", filter.Referrer)
 	filter.appendField(&fields, "referrer_name", filter.ReferrerName)
+	filter.appendField(&fields, "channel", filter.Channel)
 	filter.appendField(&fields, "os", filter.OS)
 	filter.appendField(&fields, "os_version", filter.OSVersion)
 	filter.appendField(&fields, "browser", filter.Browser)
 	filter.appendField(&fields, "browser_version", filter.BrowserVersion)
+	filter.appendField(&fields, "device_type", filter.DeviceType)
+	filter.appendField(&fields, "device_vendor", filter.DeviceVendor)
+	filter.appendField(&fields, "device_model", filter.DeviceModel)
+	filter.appendField(&fields, "bot_reason", filter.botReason())
 	filter.appendField(&fields, "screen_class", filter.ScreenClass)
 	filter.appendField(&fields, "utm_source", filter.UTMSource)
 	filter.appendField(&fields, "utm_medium", filter.UTMMedium)
@@ -354,13 +757,63 @@ func (filter *Filter) fields() string {
 		}
 	}
 
+	if filter.WebView != "" {
+		fields = append(fields, "webview")
+	}
+
+	if filter.NewVisitor != "" {
+		fields = append(fields, "new_visitor")
+	}
+
 	if filter.Path == "" && filter.PathPattern != "" {
 		fields = append(fields, "path")
 	}
 
+	for _, condition := range filter.Conditions {
+		if !containsString(fields, condition.Field) {
+			fields = append(fields, condition.Field)
+		}
+	}
+
+	// session_id is always selected together with the fields
+	if len(filter.Segments) > 0 {
+		fields = append(fields, "visitor_id")
+	}
+
 	return strings.Join(fields, ",")
 }
 
+// pageFilter returns whether the filter contains fields that are stored for page views and events, but not sessions.
+func (filter *Filter) pageFilter() bool {
+	return filter.Path != "" || filter.PathPattern != "" || len(filter.pageConditions()) > 0
+}
+
+// pageConditions returns the conditions for fields that are stored for page views and events, but not sessions.
+func (filter *Filter) pageConditions() []Condition {
+	conditions := make([]Condition, 0)
+
+	for _, condition := range filter.Conditions {
+		if containsString(conditionPageFields, condition.Field) {
+			conditions = append(conditions, condition)
+		}
+	}
+
+	return conditions
+}
+
+// sessionConditions returns the conditions for fields that are stored for sessions.
+func (filter *Filter) sessionConditions() []Condition {
+	conditions := make([]Condition, 0, len(filter.Conditions))
+
+	for _, condition := range filter.Conditions {
+		if !containsString(conditionPageFields, condition.Field) {
+			conditions = append(conditions, condition)
+		}
+	}
+
+	return conditions
+}
+
 func (filter *Filter) appendField(fields *[]string, field, value string) {
 	if value != "" {
 		*fields = append(*fields, field)
@@ -375,6 +828,15 @@ func (filter *Filter) withFill() ([]interface{}, string) {
 	return nil, ""
 }
 
+// days returns the number of days in the filter range, or zero if the range is open.
+func (filter *Filter) days() int {
+	if filter.From.IsZero() || filter.To.IsZero() {
+		return 0
+	}
+
+	return int(filter.To.Sub(filter.From).Hours()/24) + 1
+}
+
 func (filter *Filter) withLimit() string {
 	if filter.Limit > 0 {
 		return fmt.Sprintf("LIMIT %d ", filter.Limit)
@@ -414,6 +876,15 @@ func (filter *Filter) appendQuery(queryFields *[]string, args *[]interface{}, fi
 	}
 }
 
+// botReason returns the BotReason to filter for, excluding flagged sessions by default.
+func (filter *Filter) botReason() string {
+	if filter.BotReason == "" && !filter.IncludeBots {
+		return "null"
+	}
+
+	return filter.BotReason
+}
+
 func (filter *Filter) nullValue(value string) string {
 	if strings.ToLower(value) == "null" {
 		return ""
@@ -433,3 +904,18 @@ func (filter *Filter) boolean(b bool) int8 {
 
 	return 0
 }
+
+func (condition *Condition) valid() bool {
+	if _, found := conditionFields[condition.Field]; !found || len(condition.Values) == 0 {
+		return false
+	}
+
+	switch condition.Operator {
+	case FilterIn, FilterNotIn:
+		return true
+	case FilterContains, FilterNotContains, FilterPrefix, FilterRegex, FilterNotRegex:
+		return containsString(conditionTextFields, condition.Field)
+	default:
+		return false
+	}
+}
@@ -39,6 +39,39 @@ func TestFilter_Validate(t *testing.T) {
 	assert.Equal(t, "pattern", filter.PathPattern)
 }
 
+func TestFilter_ComparePeriod(t *testing.T) {
+	filter := NewFilter(NullClient)
+	filter.From = pastDay(6)
+	filter.To = pastDay(4)
+	filter.Limit = 10
+	filter.Offset = 5
+	filter.validate()
+	assert.Nil(t, filter.comparisonFilter())
+	previous := filter.comparePeriod(ComparePrevious)
+	assert.Equal(t, pastDay(9), previous.From)
+	assert.Equal(t, pastDay(7), previous.To)
+	assert.Zero(t, previous.Limit)
+	assert.Zero(t, previous.Offset)
+	assert.Empty(t, previous.Compare)
+	previous = filter.comparePeriod(CompareYear)
+	assert.Equal(t, pastDay(6).AddDate(-1, 0, 0), previous.From)
+	assert.Equal(t, pastDay(4).AddDate(-1, 0, 0), previous.To)
+	assert.Nil(t, filter.comparePeriod(CompareCustom))
+	filter.Compare = CompareCustom
+	filter.CompareFrom = pastDay(20)
+	filter.CompareTo = pastDay(30)
+	previous = filter.comparisonFilter()
+	assert.Equal(t, pastDay(30), previous.From)
+	assert.Equal(t, pastDay(20), previous.To)
+	assert.Nil(t, filter.comparePeriod("unknown"))
+	filter = NewFilter(NullClient)
+	filter.Day = pastDay(2)
+	filter.validate()
+	assert.Equal(t, pastDay(3), filter.comparePeriod(ComparePrevious).Day)
+	assert.Equal(t, pastDay(2).AddDate(-1, 0, 0), filter.comparePeriod(CompareYear).Day)
+	assert.Nil(t, NewFilter(NullClient).comparePeriod(ComparePrevious))
+}
+
 func TestFilter_Table(t *testing.T) {
 	filter := NewFilter(NullClient)
 	assert.Equal(t, "session", filter.table())
@@ -67,6 +100,7 @@ func TestFilter_QueryTime(t *testing.T) {
 
 func TestFilter_QueryFields(t *testing.T) {
 	filter := NewFilter(NullClient)
+	filter.IncludeBots = true
 	filter.Path = "/"
 	filter.EntryPath = "/entry"
 	filter.ExitPath = "/exit"
@@ -118,6 +152,7 @@ func TestFilter_QueryFields(t *testing.T) {
 
 func TestFilter_QueryFieldsInvert(t *testing.T) {
 	filter := NewFilter(NullClient)
+	filter.IncludeBots = true
 	filter.Path = "!/"
 	filter.EntryPath = "!/entry"
 	filter.ExitPath = "!/exit"
@@ -169,6 +204,7 @@ func TestFilter_QueryFieldsInvert(t *testing.T) {
 
 func TestFilter_QueryFieldsNull(t *testing.T) {
 	filter := NewFilter(NullClient)
+	filter.IncludeBots = true
 	filter.Path = "null"
 	filter.EntryPath = "null"
 	filter.ExitPath = "null"
@@ -212,16 +248,19 @@ func TestFilter_QueryFieldsNull(t *testing.T) {
 
 func TestFilter_QueryFieldsPlatform(t *testing.T) {
 	filter := NewFilter(NullClient)
+	filter.IncludeBots = true
 	filter.Platform = PlatformDesktop
 	args, query := filter.queryFields()
 	assert.Len(t, args, 0)
 	assert.Equal(t, "desktop = 1 ", query)
 	filter = NewFilter(NullClient)
+	filter.IncludeBots = true
 	filter.Platform = PlatformMobile
 	args, query = filter.queryFields()
 	assert.Len(t, args, 0)
 	assert.Equal(t, "mobile = 1 ", query)
 	filter = NewFilter(NullClient)
+	filter.IncludeBots = true
 	filter.Platform = PlatformUnknown
 	args, query = filter.queryFields()
 	assert.Len(t, args, 0)
@@ -232,16 +271,19 @@ func TestFilter_QueryFieldsPlatform(t *testing.T) {
 
 func TestFilter_QueryFieldsPlatformInvert(t *testing.T) {
 	filter := NewFilter(NullClient)
+	filter.IncludeBots = true
 	filter.Platform = "!" + PlatformDesktop
 	args, query := filter.queryFields()
 	assert.Len(t, args, 0)
 	assert.Equal(t, "desktop != 1 ", query)
 	filter = NewFilter(NullClient)
+	filter.IncludeBots = true
 	filter.Platform = "!" + PlatformMobile
 	args, query = filter.queryFields()
 	assert.Len(t, args, 0)
 	assert.Equal(t, "mobile != 1 ", query)
 	filter = NewFilter(NullClient)
+	filter.IncludeBots = true
 	filter.Platform = "!" + PlatformUnknown
 	args, query = filter.queryFields()
 	assert.Len(t, args, 0)
@@ -250,22 +292,170 @@ func TestFilter_QueryFieldsPlatformInvert(t *testing.T) {
 	assert.Contains(t, query, "(desktop = 1 OR mobile = 1)")
 }
 
+func TestFilter_QueryFieldsWebView(t *testing.T) {
+	filter := NewFilter(NullClient)
+	filter.IncludeBots = true
+	filter.WebView = "true"
+	args, query := filter.queryFields()
+	assert.Len(t, args, 0)
+	assert.Equal(t, "webview = 1 ", query)
+	filter.WebView = "false"
+	args, query = filter.queryFields()
+	assert.Len(t, args, 0)
+	assert.Equal(t, "webview = 0 ", query)
+	filter.WebView = "!true"
+	_, query = filter.queryFields()
+	assert.Equal(t, "webview = 0 ", query)
+	filter.WebView = "!false"
+	_, query = filter.queryFields()
+	assert.Equal(t, "webview = 1 ", query)
+}
+
+func TestFilter_QueryFieldsNewVisitor(t *testing.T) {
+	filter := NewFilter(NullClient)
+	filter.IncludeBots = true
+	filter.NewVisitor = "true"
+	args, query := filter.queryFields()
+	assert.Len(t, args, 0)
+	assert.Equal(t, "new_visitor = 1 ", query)
+	filter.NewVisitor = "!true"
+	_, query = filter.queryFields()
+	assert.Equal(t, "new_visitor = 0 ", query)
+	filter.WebView = "false"
+	_, query = filter.queryFields()
+	assert.Equal(t, "webview = 0 AND new_visitor = 0 ", query)
+	assert.Equal(t, "webview,new_visitor", filter.fields())
+}
+
+func TestFilter_QueryFieldsGeo(t *testing.T) {
+	filter := NewFilter(NullClient)
+	filter.IncludeBots = true
+	filter.Region = "England"
+	filter.PostalCode = "!EC1A"
+	filter.TimeZone = "Europe/London"
+	filter.ASN = "16509"
+	filter.ASOrganization = "null"
+	args, query := filter.queryFields()
+	assert.Equal(t, []interface{}{"England", "EC1A", "Europe/London", "16509", ""}, args)
+	assert.Equal(t, "region = ? AND postal_code != ? AND time_zone = ? AND if(asn = 0, '', toString(asn)) = ? AND as_organization = ? ", query)
+	filter = NewFilter(NullClient)
+	filter.IncludeBots = true
+	filter.ASN = "null"
+	args, query = filter.queryFields()
+	assert.Equal(t, []interface{}{""}, args)
+	assert.Equal(t, "if(asn = 0, '', toString(asn)) = ? ", query)
+}
+
 func TestFilter_QueryFieldsPathPattern(t *testing.T) {
 	filter := NewFilter(NullClient)
+	filter.IncludeBots = true
 	filter.PathPattern = "/some/pattern"
 	args, query := filter.queryFields()
 	assert.Len(t, args, 1)
 	assert.Equal(t, "/some/pattern", args[0])
-	assert.Equal(t, `match("path", ?) = 1`, query)
+	assert.Equal(t, `match("path", ?) = 1 `, query)
 }
 
 func TestFilter_QueryFieldsPathPatternInvert(t *testing.T) {
 	filter := NewFilter(NullClient)
+	filter.IncludeBots = true
 	filter.PathPattern = "!/some/pattern"
 	args, query := filter.queryFields()
 	assert.Len(t, args, 1)
 	assert.Equal(t, "/some/pattern", args[0])
-	assert.Equal(t, `match("path", ?) = 0`, query)
+	assert.Equal(t, `match("path", ?) = 0 `, query)
+}
+
+func TestFilter_QueryFieldsConditions(t *testing.T) {
+	filter := NewFilter(NullClient)
+	filter.IncludeBots = true
+	filter.Conditions = []Condition{
+		{Field: "country_code", Operator: FilterIn, Values: []string{"vn", "th", "null"}},
+		{Field: "asn", Operator: FilterNotIn, Values: []string{"2516"}},
+		{Field: "utm_source", Operator: FilterContains, Values: []string{"facebook", "zalo"}},
+		{Field: "referrer", Operator: FilterNotContains, Values: []string{"spam"}},
+		{Field: "title", Operator: FilterPrefix, Values: []string{"Blog"}},
+		{Field: "utm_campaign", Operator: FilterRegex, Values: []string{"^sale_"}},
+		{Field: "path", Operator: FilterNotRegex, Values: []string{"^/admin", "^/login"}},
+	}
+	filter.validate()
+	args, query := filter.queryFields()
+	assert.Equal(t, []interface{}{"vn", "th", "", "2516", "facebook", "zalo", "spam", "Blog", "^sale_", "^/admin", "^/login"}, args)
+	assert.Equal(t, "country_code IN (?,?,?) AND "+
+		"if(asn = 0, '', toString(asn)) NOT IN (?) AND "+
+		"(positionCaseInsensitive(utm_source, ?) > 0 OR positionCaseInsensitive(utm_source, ?) > 0) AND "+
+		"(positionCaseInsensitive(referrer, ?) = 0) AND "+
+		"(startsWith(title, ?)) AND "+
+		"(match(utm_campaign, ?) = 1) AND "+
+		"(match(path, ?) = 0 AND match(path, ?) = 0) ", query)
+	args, query = filter.queryPageOrEvent()
+	assert.Equal(t, []interface{}{"Blog", "^/admin", "^/login"}, args)
+	assert.Equal(t, "(startsWith(title, ?)) AND (match(path, ?) = 0 AND match(path, ?) = 0) ", query)
+	assert.Equal(t, "country_code,asn,utm_source,referrer,title,utm_campaign,path", filter.fields())
+	assert.True(t, filter.pageFilter())
+	assert.Len(t, filter.pageConditions(), 2)
+	assert.Len(t, filter.sessionConditions(), 5)
+}
+
+func TestFilter_ValidateConditions(t *testing.T) {
+	filter := NewFilter(NullClient)
+	filter.Conditions = []Condition{
+		{Field: "country_code", Operator: FilterIn, Values: []string{"vn"}},
+		{Field: "country_code", Operator: FilterContains, Values: []string{"v"}},
+		{Field: "country_code", Operator: FilterIn},
+		{Field: "unknown", Operator: FilterIn, Values: []string{"vn"}},
+		{Field: "utm_source", Operator: "unknown", Values: []string{"vn"}},
+	}
+	filter.validate()
+	assert.Len(t, filter.Conditions, 1)
+	assert.Equal(t, "country_code", filter.Conditions[0].Field)
+	assert.False(t, filter.pageFilter())
+}
+
+func TestFilter_QueryFieldsSegments(t *testing.T) {
+	filter := NewFilter(NullClient)
+	filter.IncludeBots = true
+	filter.Day = pastDay(2)
+	filter.Segments = []Segment{
+		{EventName: "purchase"},
+		{Scope: SegmentSession, Path: "/pricing", Exclude: true},
+		{EventName: "signup", Path: "/register"},
+		{Scope: SegmentSession},
+	}
+	filter.validate()
+	assert.Len(t, filter.Segments, 3)
+	assert.Equal(t, SegmentVisitor, filter.Segments[0].Scope)
+	args, query := filter.queryFields()
+	assert.Equal(t, []interface{}{
+		NullClient, filter.Day, "purchase",
+		NullClient, filter.Day, "/pricing",
+		NullClient, filter.Day, "signup", "/register",
+	}, args)
+	assert.Equal(t, "(visitor_id) IN (SELECT visitor_id FROM event WHERE client_id = ? AND toDate(time, 'UTC') = toDate(?) AND event_name = ? ) AND "+
+		"(visitor_id, session_id) NOT IN (SELECT visitor_id, session_id FROM page_view WHERE client_id = ? AND toDate(time, 'UTC') = toDate(?) AND path = ? ) AND "+
+		"(visitor_id) IN (SELECT visitor_id FROM event WHERE client_id = ? AND toDate(time, 'UTC') = toDate(?) AND event_name = ? AND path = ? ) ", query)
+	assert.Equal(t, "visitor_id", filter.fields())
+	assert.Equal(t, "session", filter.table())
+}
+
+func TestFilter_QueryFieldsBots(t *testing.T) {
+	filter := NewFilter(NullClient)
+	args, query := filter.queryFields()
+	assert.Len(t, args, 1)
+	assert.Empty(t, args[0])
+	assert.Equal(t, "bot_reason = ? ", query)
+	assert.Equal(t, "bot_reason", filter.fields())
+	filter.BotReason = BotReasonRate
+	args, query = filter.queryFields()
+	assert.Len(t, args, 1)
+	assert.Equal(t, BotReasonRate, args[0])
+	assert.Equal(t, "bot_reason = ? ", query)
+	filter.BotReason = ""
+	filter.IncludeBots = true
+	args, query = filter.queryFields()
+	assert.Len(t, args, 0)
+	assert.Empty(t, query)
+	assert.Empty(t, filter.fields())
 }
 
 func TestFilter_QueryPageOrEvent(t *testing.T) {
@@ -297,7 +487,13 @@ func TestFilter_QueryPageOrEventInvert(t *testing.T) {
 	assert.Len(t, args, 2)
 	assert.Equal(t, "event", args[0])
 	assert.Equal(t, "pattern", args[1])
-	assert.Equal(t, `event_name != ? AND match("path", ?) = 0`, query)
+	assert.Equal(t, `event_name != ? AND match("path", ?) = 0 `, query)
+	filter.Conditions = []Condition{{Field: "title", Operator: FilterContains, Values: []string{"blog"}}}
+	filter.validate()
+	args, query = filter.queryPageOrEvent()
+	assert.Len(t, args, 3)
+	assert.Equal(t, "blog", args[2])
+	assert.Equal(t, `event_name != ? AND match("path", ?) = 0 AND (positionCaseInsensitive(title, ?) > 0) `, query)
 }
 
 func TestFilter_QueryPageOrEventNull(t *testing.T) {
@@ -345,13 +541,21 @@ func TestFilter_Fields(t *testing.T) {
 	filter.Language = "en"
 	filter.Country = "jp"
 	filter.City = "Tokyo"
+	filter.Region = "Tokyo"
+	filter.ASN = "2516"
 	filter.Referrer = "ref"
 	filter.ReferrerName = "refname"
+	filter.Channel = ChannelSocial
 	filter.OS = OSWindows
 	filter.OSVersion = "10"
 	filter.Browser = BrowserEdge
 	filter.BrowserVersion = "89"
 	filter.Platform = PlatformUnknown
+	filter.DeviceType = DeviceTablet
+	filter.DeviceVendor = "Samsung"
+	filter.DeviceModel = "SM-X700"
+	filter.BotReason = "null"
+	filter.WebView = "true"
 	filter.ScreenClass = "XXL"
 	filter.UTMSource = "source"
 	filter.UTMMedium = "medium"
@@ -361,11 +565,11 @@ func TestFilter_Fields(t *testing.T) {
 	filter.validate()
 
 	// exit_path not included
-	assert.Equal(t, "path,entry_path,exit_path,language,country_code,city,referrer,referrer_name,os,os_version,browser,browser_version,screen_class,utm_source,utm_medium,utm_campaign,utm_content,utm_term,desktop,mobile", filter.fields())
+	assert.Equal(t, "path,entry_path,exit_path,language,country_code,city,region,asn,referrer,referrer_name,channel,os,os_version,browser,browser_version,device_type,device_vendor,device_model,bot_reason,screen_class,utm_source,utm_medium,utm_campaign,utm_content,utm_term,desktop,mobile,webview", filter.fields())
 
 	filter.validate()
 	filter.EventName = "event"
-	assert.Equal(t, "path,language,country_code,city,referrer,referrer_name,os,os_version,browser,browser_version,screen_class,utm_source,utm_medium,utm_campaign,utm_content,utm_term,event_name,desktop,mobile", filter.fields())
+	assert.Equal(t, "path,language,country_code,city,region,asn,referrer,referrer_name,channel,os,os_version,browser,browser_version,device_type,device_vendor,device_model,bot_reason,screen_class,utm_source,utm_medium,utm_campaign,utm_content,utm_term,event_name,desktop,mobile,webview", filter.fields())
 }
 
 func pastDay(n int) time.Time {
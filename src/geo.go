@@ -0,0 +1,64 @@
+package omisocial
+
+// GeoResolver maps IPs to their geo location.
+// GeoDB implements it for MaxMind and DB-IP databases (mmdb), IP2LocationDB for IP2Location (LITE) databases (BIN).
+type GeoResolver interface {
+	// Resolve looks up the location for given IP.
+	// Fields the database doesn't provide or that are unknown are left empty.
+	Resolve(ip string) GeoLocation
+}
+
+// geoResolver returns nil in case given GeoResolver is a nil GeoDB or IP2LocationDB.
+// Otherwise, the typed nil would be treated as an enabled resolver.
+func geoResolver(resolver GeoResolver) GeoResolver {
+	switch db := resolver.(type) {
+	case *GeoDB:
+		if db == nil {
+			return nil
+		}
+	case *IP2LocationDB:
+		if db == nil {
+			return nil
+		}
+	}
+
+	return resolver
+}
+
+// GeoLocation is the location for an IP address.
+type GeoLocation struct {
+	// CountryCode is the ISO country code in lowercase.
+	CountryCode string
+
+	// City is the (English) city name.
+	City string
+
+	// CityGeoNameID is the GeoNames ID of the city, which can be used to look up localized names (see Localizer).
+	CityGeoNameID uint32
+
+	// Region is the (English) name of the subdivision (state, province, ...).
+	Region string
+
+	// PostalCode is the postal or ZIP code.
+	PostalCode string
+
+	// Latitude and Longitude are the approximate coordinates.
+	Latitude  float64
+	Longitude float64
+
+	// TimeZone is the IANA time zone name for MaxMind and DB-IP databases, or the UTC offset (like +01:00) for IP2Location.
+	TimeZone string
+
+	// ASN is the autonomous system number and ASOrganization the organization it is registered to.
+	ASN            uint32
+	ASOrganization string
+}
+
+// shorten limits the length of all strings to fit the database columns.
+func (location *GeoLocation) shorten() {
+	location.City = shortenString(location.City, 100)
+	location.Region = shortenString(location.Region, 100)
+	location.PostalCode = shortenString(location.PostalCode, 20)
+	location.TimeZone = shortenString(location.TimeZone, 50)
+	location.ASOrganization = shortenString(location.ASOrganization, 200)
+}
@@ -28,6 +28,10 @@ type GeoDBConfig struct {
 	// See GeoLite2Filename for the required filename.
 	File string
 
+	// ASNFile is the optional path to a GeoLite2 ASN or DB-IP ASN Lite database file.
+	// The ASN and organization will be left empty if it isn't set.
+	ASNFile string
+
 	// Logger is the log.Logger used for logging.
 	// Note that this will log the IP address and should therefore only be used for debugging.
 	// Set it to nil to disable logging for GeoDB.
@@ -35,11 +39,43 @@ type GeoDBConfig struct {
 }
 
 // GeoDB maps IPs to their geo location based on MaxMinds GeoLite2 or GeoIP2 database.
+// DB-IP City Lite databases can be used as well, as they use the same format.
 type GeoDB struct {
 	db     *maxminddb.Reader
+	asn    *maxminddb.Reader
 	logger *log.Logger
 }
 
+type geoDBRecord struct {
+	Country struct {
+		ISOCode string `maxminddb:"iso_code"`
+	} `maxminddb:"country"`
+	City struct {
+		GeoNameID uint32 `maxminddb:"geoname_id"`
+		Names     struct {
+			En string `maxminddb:"en"`
+		} `maxminddb:"names"`
+	} `maxminddb:"city"`
+	Subdivisions []struct {
+		Names struct {
+			En string `maxminddb:"en"`
+		} `maxminddb:"names"`
+	} `maxminddb:"subdivisions"`
+	Postal struct {
+		Code string `maxminddb:"code"`
+	} `maxminddb:"postal"`
+	Location struct {
+		Latitude  float64 `maxminddb:"latitude"`
+		Longitude float64 `maxminddb:"longitude"`
+		TimeZone  string  `maxminddb:"time_zone"`
+	} `maxminddb:"location"`
+}
+
+type geoDBASNRecord struct {
+	ASN          uint32 `maxminddb:"autonomous_system_number"`
+	Organization string `maxminddb:"autonomous_system_organization"`
+}
+
 // NewGeoDB creates a new GeoDB for given database file.
 // The file is loaded into memory, therefore it's not necessary to close the reader (see oschwald/maxminddb-golang documentatio).
 // The database should be updated on a regular basis.
@@ -56,8 +92,25 @@ func NewGeoDB(config GeoDBConfig) (*GeoDB, error) {
 		return nil, err
 	}
 
+	var asn *maxminddb.Reader
+
+	if config.ASNFile != "" {
+		data, err := os.ReadFile(config.ASNFile)
+
+		if err != nil {
+			return nil, err
+		}
+
+		asn, err = maxminddb.FromBytes(data)
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	return &GeoDB{
 		db:     db,
+		asn:    asn,
 		logger: config.Logger,
 	}, nil
 }
@@ -66,36 +119,85 @@ func NewGeoDB(config GeoDBConfig) (*GeoDB, error) {
 // If the IP is invalid it will return an empty string.
 // The country code is returned in lowercase.
 func (db *GeoDB) CountryCodeAndCity(ip string) (string, string) {
-	parsedIP := net.ParseIP(ip)
+	location := db.Resolve(ip)
+	return location.CountryCode, location.City
+}
+
+// Resolve implements the GeoResolver interface.
+func (db *GeoDB) Resolve(ip string) GeoLocation {
+	parsedIP := db.parseIP(ip)
 
 	if parsedIP == nil {
+		return GeoLocation{}
+	}
+
+	var record geoDBRecord
+
+	if err := db.db.Lookup(parsedIP, &record); err != nil {
 		if db.logger != nil {
-			db.logger.Printf("error parsing IP address %s", ip)
+			db.logger.Printf("error looking up IP address %s", parsedIP)
 		}
 
-		return "", ""
+		return GeoLocation{}
 	}
 
-	record := struct {
-		Country struct {
-			ISOCode string `maxminddb:"iso_code"`
-		} `maxminddb:"country"`
-		City struct {
-			Names struct {
-				En string `maxminddb:"en"`
-			} `maxminddb:"names"`
-		} `maxminddb:"city"`
-	}{}
+	location := GeoLocation{
+		CountryCode:   strings.ToLower(record.Country.ISOCode),
+		City:          record.City.Names.En,
+		CityGeoNameID: record.City.GeoNameID,
+		PostalCode:    record.Postal.Code,
+		Latitude:      record.Location.Latitude,
+		Longitude:     record.Location.Longitude,
+		TimeZone:      record.Location.TimeZone,
+	}
 
-	if err := db.db.Lookup(parsedIP, &record); err != nil {
+	if len(record.Subdivisions) > 0 {
+		location.Region = record.Subdivisions[0].Names.En
+	}
+
+	location.ASN, location.ASOrganization = db.lookupASN(parsedIP)
+	return location
+}
+
+// ASN implements the ASNLookup interface.
+// It returns 0 if no ASN database has been configured.
+func (db *GeoDB) ASN(ip string) uint32 {
+	parsedIP := db.parseIP(ip)
+
+	if parsedIP == nil {
+		return 0
+	}
+
+	asn, _ := db.lookupASN(parsedIP)
+	return asn
+}
+
+func (db *GeoDB) lookupASN(ip net.IP) (uint32, string) {
+	if db.asn == nil {
+		return 0, ""
+	}
+
+	var record geoDBASNRecord
+
+	if err := db.asn.Lookup(ip, &record); err != nil {
 		if db.logger != nil {
-			db.logger.Printf("error looking up IP address %s", parsedIP)
+			db.logger.Printf("error looking up ASN for IP address %s", ip)
 		}
 
-		return "", ""
+		return 0, ""
+	}
+
+	return record.ASN, record.Organization
+}
+
+func (db *GeoDB) parseIP(ip string) net.IP {
+	parsedIP := net.ParseIP(ip)
+
+	if parsedIP == nil && db.logger != nil {
+		db.logger.Printf("error parsing IP address %s", ip)
 	}
 
-	return strings.ToLower(record.Country.ISOCode), record.City.Names.En
+	return parsedIP
 }
 
 // GetGeoLite2 downloads and unpacks the MaxMind GeoLite2 database.
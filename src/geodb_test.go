@@ -31,3 +31,21 @@ func TestGeoDB_CountryCode(t *testing.T) {
 	assert.Equal(t, "gb", countryCode)
 	assert.Equal(t, "London", city)
 }
+
+func TestGeoDB_Resolve(t *testing.T) {
+	db, err := NewGeoDB(GeoDBConfig{
+		File: filepath.Join("geodb/GeoIP2-City-Test.mmdb"),
+	})
+	assert.NoError(t, err)
+	location := db.Resolve("81.2.69.142")
+	assert.Equal(t, "gb", location.CountryCode)
+	assert.Equal(t, "London", location.City)
+	assert.Equal(t, "England", location.Region)
+	assert.Equal(t, uint32(2643743), location.CityGeoNameID)
+	assert.Equal(t, "Europe/London", location.TimeZone)
+	assert.NotZero(t, location.Latitude)
+	assert.NotZero(t, location.Longitude)
+	assert.Zero(t, location.ASN)
+	assert.Zero(t, db.ASN("81.2.69.142"))
+	assert.Equal(t, GeoLocation{}, db.Resolve("invalid"))
+}
@@ -0,0 +1,444 @@
+package omisocial
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/oschwald/maxminddb-golang"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	geoLite2ChecksumPermalink  = "https://download.maxmind.com/app/geoip_download?edition_id=GeoLite2-City&license_key=LICENSE_KEY&suffix=tar.gz.sha256"
+	defaultGeoDBUpdateInterval = time.Hour * 24
+	defaultGeoDBDatabaseType   = "City"
+	defaultGeoDBUpdateTimeout  = time.Minute * 10
+)
+
+var (
+	// ErrNoGeoDBLicenseKey is returned by NewGeoDBUpdater in case neither the URL nor the LicenseKey is set.
+	ErrNoGeoDBLicenseKey = errors.New("a license key is required to download the GeoDB from MaxMind")
+)
+
+// GeoDBUpdaterConfig is the configuration for the GeoDBUpdater.
+type GeoDBUpdaterConfig struct {
+	// Path is the directory the database is stored in (as GeoLite2Filename).
+	Path string
+
+	// URL is the download URL for the database.
+	// The file can either be a tarball containing the mmdb file (like the ones provided by MaxMind) or the plain mmdb file.
+	// Set it to use a local mirror or a different provider. The MaxMind GeoLite2 permalink is used by default.
+	URL string
+
+	// ChecksumURL is the optional download URL for the SHA256 checksum of the file at URL.
+	// The checksum file must start with the hex encoded checksum, followed by anything else (like the filename).
+	// The MaxMind GeoLite2 checksum permalink is used by default if the URL isn't set.
+	ChecksumURL string
+
+	// LicenseKey is the MaxMind license key used to download the database.
+	// It replaces the LICENSE_KEY placeholder in the URL and ChecksumURL.
+	LicenseKey string
+
+	// ASNFile is passed on to the GeoDBConfig when the database is loaded.
+	ASNFile string
+
+	// DatabaseType is the expected database type in the mmdb metadata (like "GeoLite2-City").
+	// The check is case-insensitive and matches if the type contains the string. Set to "City" by default.
+	DatabaseType string
+
+	// Interval is the time between two updates. Set to 24 hours by default.
+	Interval time.Duration
+
+	// Timeout is the maximum time for a single download. Set to 10 minutes by default.
+	Timeout time.Duration
+
+	// Tracker is the Tracker the database is set for after a successful update (using Tracker.SetGeoDB).
+	Tracker *Tracker
+
+	// Logger is the log.Logger used for logging.
+	// The default log will be used printing to os.Stdout with "pirsch" in its prefix in case it is not set.
+	Logger *log.Logger
+}
+
+func (config *GeoDBUpdaterConfig) validate() error {
+	if config.URL == "" {
+		if config.LicenseKey == "" {
+			return ErrNoGeoDBLicenseKey
+		}
+
+		config.URL = geoLite2Permalink
+
+		if config.ChecksumURL == "" {
+			config.ChecksumURL = geoLite2ChecksumPermalink
+		}
+	}
+
+	config.URL = strings.Replace(config.URL, geoLite2LicenseKey, config.LicenseKey, 1)
+	config.ChecksumURL = strings.Replace(config.ChecksumURL, geoLite2LicenseKey, config.LicenseKey, 1)
+
+	if config.DatabaseType == "" {
+		config.DatabaseType = defaultGeoDBDatabaseType
+	}
+
+	if config.Interval <= 0 {
+		config.Interval = defaultGeoDBUpdateInterval
+	}
+
+	if config.Timeout <= 0 {
+		config.Timeout = defaultGeoDBUpdateTimeout
+	}
+
+	if config.Logger == nil {
+		config.Logger = logger
+	}
+
+	return nil
+}
+
+// GeoDBStatus is the state of the GeoDBUpdater, which can be used for health checks.
+type GeoDBStatus struct {
+	// LastCheck is the time of the last update attempt.
+	LastCheck time.Time `json:"last_check"`
+
+	// LastUpdate is the time the database was last replaced by a download.
+	// It is zero if the database has only been loaded from disk so far.
+	LastUpdate time.Time `json:"last_update"`
+
+	// BuildTime is the build date of the database in use.
+	BuildTime time.Time `json:"build_time"`
+
+	// DatabaseType is the type of the database in use.
+	DatabaseType string `json:"database_type"`
+
+	// Error is the error of the last update attempt, or empty if it succeeded.
+	Error string `json:"error"`
+}
+
+// GeoDBUpdater downloads the GeoDB on a regular basis and swaps it in without restarting the Tracker.
+// The database is downloaded to a temporary file and only replaces the existing database once it has been validated.
+type GeoDBUpdater struct {
+	config     GeoDBUpdaterConfig
+	client     *http.Client
+	status     GeoDBStatus
+	m          sync.RWMutex
+	updateLock sync.Mutex
+}
+
+// NewGeoDBUpdater creates a new GeoDBUpdater for given configuration.
+// ErrNoGeoDBLicenseKey is returned in case the database would be downloaded from MaxMind without a license key.
+// Call Start to run the updates.
+func NewGeoDBUpdater(config GeoDBUpdaterConfig) (*GeoDBUpdater, error) {
+	if err := config.validate(); err != nil {
+		return nil, err
+	}
+
+	return &GeoDBUpdater{
+		config: config,
+		client: &http.Client{Timeout: config.Timeout},
+	}, nil
+}
+
+// Start loads the existing database (if any) and updates it in the configured interval.
+// The first update runs right away if there is no database yet. It returns a function to stop the updates.
+func (updater *GeoDBUpdater) Start() context.CancelFunc {
+	ctx, cancelFunc := context.WithCancel(context.Background())
+	exists := updater.load() == nil
+
+	go func() {
+		if !exists {
+			updater.update()
+		}
+
+		ticker := time.NewTicker(updater.config.Interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				updater.update()
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return cancelFunc
+}
+
+// Update downloads, validates, and swaps the database.
+// Concurrent calls are executed one after another.
+func (updater *GeoDBUpdater) Update() error {
+	updater.updateLock.Lock()
+	defer updater.updateLock.Unlock()
+
+	if err := os.MkdirAll(updater.config.Path, 0755); err != nil {
+		return err
+	}
+
+	download, err := os.CreateTemp(updater.config.Path, "geodb-download-*")
+
+	if err != nil {
+		return err
+	}
+
+	defer updater.remove(download.Name())
+	checksum, err := updater.download(updater.config.URL, download)
+
+	if closeErr := download.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if err := updater.verifyChecksum(checksum); err != nil {
+		return err
+	}
+
+	mmdb, err := updater.extract(download.Name())
+
+	if err != nil {
+		return err
+	}
+
+	defer updater.remove(mmdb)
+
+	if _, err := updater.validate(mmdb); err != nil {
+		return err
+	}
+
+	// rename is atomic, so that the database is never left in a broken state
+	if err := os.Rename(mmdb, updater.file()); err != nil {
+		return err
+	}
+
+	if err := updater.load(); err != nil {
+		return err
+	}
+
+	updater.m.Lock()
+	defer updater.m.Unlock()
+	updater.status.LastUpdate = time.Now().UTC()
+	return nil
+}
+
+// Status returns the current state of the updater.
+func (updater *GeoDBUpdater) Status() GeoDBStatus {
+	updater.m.RLock()
+	defer updater.m.RUnlock()
+	return updater.status
+}
+
+func (updater *GeoDBUpdater) update() {
+	err := updater.Update()
+	updater.m.Lock()
+	defer updater.m.Unlock()
+	updater.status.LastCheck = time.Now().UTC()
+
+	if err != nil {
+		updater.config.Logger.Printf("error updating GeoDB: %s", err)
+		updater.status.Error = err.Error()
+	} else {
+		updater.status.Error = ""
+	}
+}
+
+// load reads the database from disk and sets it for the Tracker.
+func (updater *GeoDBUpdater) load() error {
+	metadata, err := updater.validate(updater.file())
+
+	if err != nil {
+		return err
+	}
+
+	db, err := NewGeoDB(GeoDBConfig{
+		File:    updater.file(),
+		ASNFile: updater.config.ASNFile,
+	})
+
+	if err != nil {
+		return err
+	}
+
+	if updater.config.Tracker != nil {
+		updater.config.Tracker.SetGeoDB(db)
+	}
+
+	updater.m.Lock()
+	defer updater.m.Unlock()
+	updater.status.BuildTime = time.Unix(int64(metadata.BuildEpoch), 0).UTC()
+	updater.status.DatabaseType = metadata.DatabaseType
+	return nil
+}
+
+// download writes the file at given URL to w and returns its SHA256 checksum.
+func (updater *GeoDBUpdater) download(url string, w io.Writer) ([]byte, error) {
+	resp, err := updater.client.Get(url)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			updater.config.Logger.Printf("error closing GeoDB download: %s", err)
+		}
+	}()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code %d downloading GeoDB", resp.StatusCode)
+	}
+
+	hash := sha256.New()
+
+	if _, err := io.Copy(io.MultiWriter(w, hash), resp.Body); err != nil {
+		return nil, err
+	}
+
+	return hash.Sum(nil), nil
+}
+
+func (updater *GeoDBUpdater) verifyChecksum(checksum []byte) error {
+	if updater.config.ChecksumURL == "" {
+		return nil
+	}
+
+	var buffer bytes.Buffer
+
+	if _, err := updater.download(updater.config.ChecksumURL, &buffer); err != nil {
+		return err
+	}
+
+	fields := strings.Fields(buffer.String())
+
+	if len(fields) == 0 || !strings.EqualFold(fields[0], hex.EncodeToString(checksum)) {
+		return errors.New("GeoDB checksum mismatch")
+	}
+
+	return nil
+}
+
+// extract writes the mmdb file contained in given download to a temporary file and returns its path.
+// The download is used as is if it isn't a tarball.
+func (updater *GeoDBUpdater) extract(download string) (string, error) {
+	in, err := os.Open(download)
+
+	if err != nil {
+		return "", err
+	}
+
+	defer func() {
+		if err := in.Close(); err != nil {
+			updater.config.Logger.Printf("error closing GeoDB download: %s", err)
+		}
+	}()
+	out, err := os.CreateTemp(updater.config.Path, "geodb-*.mmdb")
+
+	if err != nil {
+		return "", err
+	}
+
+	if err := updater.extractMMDB(in, out); err != nil {
+		updater.remove(out.Name())
+		return "", err
+	}
+
+	if err := out.Close(); err != nil {
+		updater.remove(out.Name())
+		return "", err
+	}
+
+	return out.Name(), nil
+}
+
+func (updater *GeoDBUpdater) extractMMDB(in io.Reader, out io.Writer) error {
+	buffered := bufio.NewReader(in)
+	magic, err := buffered.Peek(2)
+
+	if err != nil {
+		return err
+	}
+
+	// not a gzip file
+	if magic[0] != 0x1f || magic[1] != 0x8b {
+		_, err := io.Copy(out, buffered)
+		return err
+	}
+
+	gzipReader, err := gzip.NewReader(buffered)
+
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if err := gzipReader.Close(); err != nil {
+			updater.config.Logger.Printf("error closing GeoDB zip file: %s", err)
+		}
+	}()
+	r := tar.NewReader(gzipReader)
+
+	for {
+		header, err := r.Next()
+
+		if err == io.EOF {
+			return errors.New("GeoDB tarball does not contain a mmdb file")
+		} else if err != nil {
+			return err
+		}
+
+		if strings.HasSuffix(strings.ToLower(header.Name), ".mmdb") {
+			_, err := io.Copy(out, r)
+			return err
+		}
+	}
+}
+
+// validate opens given database, verifies its structure, and checks the database type.
+func (updater *GeoDBUpdater) validate(file string) (*maxminddb.Metadata, error) {
+	data, err := os.ReadFile(file)
+
+	if err != nil {
+		return nil, err
+	}
+
+	db, err := maxminddb.FromBytes(data)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err := db.Verify(); err != nil {
+		return nil, err
+	}
+
+	if !strings.Contains(strings.ToLower(db.Metadata.DatabaseType), strings.ToLower(updater.config.DatabaseType)) {
+		return nil, fmt.Errorf("unexpected GeoDB database type %s", db.Metadata.DatabaseType)
+	}
+
+	return &db.Metadata, nil
+}
+
+func (updater *GeoDBUpdater) file() string {
+	return filepath.Join(updater.config.Path, GeoLite2Filename)
+}
+
+func (updater *GeoDBUpdater) remove(file string) {
+	if err := os.Remove(file); err != nil && !os.IsNotExist(err) {
+		updater.config.Logger.Printf("error removing temporary GeoDB file %s: %s", file, err)
+	}
+}
@@ -0,0 +1,149 @@
+package omisocial
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestNewGeoDBUpdater(t *testing.T) {
+	_, err := NewGeoDBUpdater(GeoDBUpdaterConfig{Path: t.TempDir()})
+	assert.Equal(t, ErrNoGeoDBLicenseKey, err)
+	updater, err := NewGeoDBUpdater(GeoDBUpdaterConfig{Path: t.TempDir(), LicenseKey: "key"})
+	assert.NoError(t, err)
+	assert.Contains(t, updater.config.URL, "license_key=key")
+	assert.Contains(t, updater.config.ChecksumURL, "license_key=key")
+}
+
+func TestGeoDBUpdater_Update(t *testing.T) {
+	mmdb, err := os.ReadFile("geodb/GeoIP2-City-Test.mmdb")
+	assert.NoError(t, err)
+	tarGz := geoDBTarGz(t, mmdb)
+	checksum := sha256.Sum256(tarGz)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/GeoLite2-City.tar.gz":
+			w.Write(tarGz)
+		case "/GeoLite2-City.tar.gz.sha256":
+			w.Write([]byte(hex.EncodeToString(checksum[:]) + "  GeoLite2-City.tar.gz\n"))
+		case "/GeoLite2-City.mmdb":
+			w.Write(mmdb)
+		case "/invalid.mmdb":
+			w.Write([]byte("invalid"))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	client := NewMockClient()
+	tracker := NewTracker(client, "salt", nil)
+	path := t.TempDir()
+	updater, err := NewGeoDBUpdater(GeoDBUpdaterConfig{
+		Path:        path,
+		URL:         server.URL + "/GeoLite2-City.tar.gz",
+		ChecksumURL: server.URL + "/GeoLite2-City.tar.gz.sha256",
+		Tracker:     tracker,
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, updater.Update())
+	status := updater.Status()
+	assert.Equal(t, "GeoIP2-City", status.DatabaseType)
+	assert.False(t, status.LastUpdate.IsZero())
+	assert.False(t, status.BuildTime.IsZero())
+	assert.NotNil(t, tracker.geoDB)
+	assertGeoDBUpdaterFiles(t, path)
+
+	// checksum mismatch keeps the existing database
+	updater.config.ChecksumURL = server.URL + "/GeoLite2-City.mmdb"
+	assert.Error(t, updater.Update())
+	assertGeoDBUpdaterFiles(t, path)
+
+	// plain mmdb file without checksum
+	updater.config.URL = server.URL + "/GeoLite2-City.mmdb"
+	updater.config.ChecksumURL = ""
+	assert.NoError(t, updater.Update())
+
+	// invalid files and database types are rejected
+	updater.config.URL = server.URL + "/invalid.mmdb"
+	assert.Error(t, updater.Update())
+	updater.config.URL = server.URL + "/not-found"
+	assert.Error(t, updater.Update())
+	updater.config.URL = server.URL + "/GeoLite2-City.mmdb"
+	updater.config.DatabaseType = "ASN"
+	assert.Error(t, updater.Update())
+	assertGeoDBUpdaterFiles(t, path)
+
+	// loading the existing database doesn't count as an update
+	updater, err = NewGeoDBUpdater(GeoDBUpdaterConfig{
+		Path: path,
+		URL:  server.URL + "/GeoLite2-City.mmdb",
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, updater.load())
+	status = updater.Status()
+	assert.True(t, status.LastUpdate.IsZero())
+	assert.False(t, status.BuildTime.IsZero())
+}
+
+func TestGeoDBUpdater_Start(t *testing.T) {
+	mmdb, err := os.ReadFile("geodb/GeoIP2-City-Test.mmdb")
+	assert.NoError(t, err)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(mmdb)
+	}))
+	defer server.Close()
+	tracker := NewTracker(NewMockClient(), "salt", nil)
+	updater, err := NewGeoDBUpdater(GeoDBUpdaterConfig{
+		Path:    t.TempDir(),
+		URL:     server.URL,
+		Tracker: tracker,
+	})
+	assert.NoError(t, err)
+	cancel := updater.Start()
+	defer cancel()
+
+	for i := 0; i < 100 && updater.Status().LastCheck.IsZero(); i++ {
+		time.Sleep(time.Millisecond * 10)
+	}
+
+	status := updater.Status()
+	assert.False(t, status.LastCheck.IsZero())
+	assert.Empty(t, status.Error)
+	tracker.geoDBMutex.RLock()
+	assert.NotNil(t, tracker.geoDB)
+	tracker.geoDBMutex.RUnlock()
+}
+
+func assertGeoDBUpdaterFiles(t *testing.T, path string) {
+	files, err := os.ReadDir(path)
+	assert.NoError(t, err)
+	assert.Len(t, files, 1)
+	assert.Equal(t, GeoLite2Filename, files[0].Name())
+	_, err = NewGeoDB(GeoDBConfig{File: filepath.Join(path, GeoLite2Filename)})
+	assert.NoError(t, err)
+}
+
+func geoDBTarGz(t *testing.T, mmdb []byte) []byte {
+	var buffer bytes.Buffer
+	gzipWriter := gzip.NewWriter(&buffer)
+	tarWriter := tar.NewWriter(gzipWriter)
+	assert.NoError(t, tarWriter.WriteHeader(&tar.Header{
+		Name: "GeoLite2-City_20211123/" + GeoLite2Filename,
+		Mode: 0644,
+		Size: int64(len(mmdb)),
+	}))
+	_, err := tarWriter.Write(mmdb)
+	assert.NoError(t, err)
+	assert.NoError(t, tarWriter.Close())
+	assert.NoError(t, gzipWriter.Close())
+	return buffer.Bytes()
+}
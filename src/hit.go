@@ -17,7 +17,8 @@ const (
 	minEdgeVersion    = 88 // late 2020
 	minIEVersion      = 11 // late 2013
 
-	defaultSessionMaxAge = time.Minute * 15
+	defaultSessionMaxAge          = time.Minute * 15
+	defaultReturningVisitorMaxAge = time.Hour * 24 * 30
 )
 
 // SessionState is the state and cancellation for a session.
@@ -48,6 +49,11 @@ type HitOptions struct {
 	// Set to 15 minutes by default.
 	SessionMaxAge time.Duration
 
+	// ReturningVisitorMaxAge defines how far back a visitor is looked up when a new session is created.
+	// Visitors having a session within the time frame are considered returning, all others new visitors.
+	// Set it to a negative value to disable the lookup and treat all visitors as new. Set to 30 days by default.
+	ReturningVisitorMaxAge time.Duration
+
 	// URL can be set to manually overwrite the URL stored for this request.
 	// This will also affect the Path, except it is set too.
 	URL string
@@ -79,7 +85,14 @@ type HitOptions struct {
 	// ScreenHeight sets the screen height to be stored with the hit.
 	ScreenHeight uint16
 
-	geoDB *GeoDB
+	// SearchQueryParams are the query parameters of the page URL containing the site search term (like q or search).
+	// The first non-empty parameter is stored as the search term of the page view.
+	SearchQueryParams []string
+
+	searchTerm  string
+	geoDB       GeoResolver
+	androidApps *AndroidAppResolver
+	store       Store
 
 	UTMSource   string
 	UTMMedium   string
@@ -107,6 +120,10 @@ func HitFromRequest(r *http.Request, salt string, options *HitOptions) (*PageVie
 		options.SessionMaxAge = defaultSessionMaxAge
 	}
 
+	if options.ReturningVisitorMaxAge == 0 {
+		options.ReturningVisitorMaxAge = defaultReturningVisitorMaxAge
+	}
+
 	fingerprint := Fingerprint(r, salt+options.Salt)
 	getRequestURI(r, options)
 	path := getPath(options.Path)
@@ -117,6 +134,8 @@ func HitFromRequest(r *http.Request, salt string, options *HitOptions) (*PageVie
 	var ua *UserAgent
 	if session == nil {
 		session, ua = newSession(r, options, fingerprint, now, path, title)
+		session.NewVisitor = isNewVisitor(options, fingerprint, now)
+		ua.ClientID, ua.VisitorID, ua.SessionID = session.ClientID, session.VisitorID, session.SessionID
 		sessionState.State = *session
 		options.SessionCache.Put(options.ClientID, fingerprint, session)
 	} else {
@@ -139,15 +158,30 @@ func HitFromRequest(r *http.Request, salt string, options *HitOptions) (*PageVie
 		Language:        sessionState.State.Language,
 		CountryCode:     sessionState.State.CountryCode,
 		City:            sessionState.State.City,
+		CityGeoNameID:   sessionState.State.CityGeoNameID,
+		Region:          sessionState.State.Region,
+		PostalCode:      sessionState.State.PostalCode,
+		Latitude:        sessionState.State.Latitude,
+		Longitude:       sessionState.State.Longitude,
+		TimeZone:        sessionState.State.TimeZone,
+		ASN:             sessionState.State.ASN,
+		ASOrganization:  sessionState.State.ASOrganization,
 		Referrer:        sessionState.State.Referrer,
 		ReferrerName:    sessionState.State.ReferrerName,
 		ReferrerIcon:    sessionState.State.ReferrerIcon,
+		Channel:         sessionState.State.Channel,
 		OS:              sessionState.State.OS,
 		OSVersion:       sessionState.State.OSVersion,
 		Browser:         sessionState.State.Browser,
 		BrowserVersion:  sessionState.State.BrowserVersion,
 		Desktop:         sessionState.State.Desktop,
 		Mobile:          sessionState.State.Mobile,
+		WebView:         sessionState.State.WebView,
+		NewVisitor:      sessionState.State.NewVisitor,
+		DeviceType:      sessionState.State.DeviceType,
+		DeviceVendor:    sessionState.State.DeviceVendor,
+		DeviceModel:     sessionState.State.DeviceModel,
+		BotReason:       sessionState.State.BotReason,
 		ScreenWidth:     sessionState.State.ScreenWidth,
 		ScreenHeight:    sessionState.State.ScreenHeight,
 		ScreenClass:     sessionState.State.ScreenClass,
@@ -160,6 +194,7 @@ func HitFromRequest(r *http.Request, salt string, options *HitOptions) (*PageVie
 		OTMMedium:       sessionState.State.OTMMedium,
 		OTMCampaign:     sessionState.State.OTMCampaign,
 		OTMPosition:     sessionState.State.OTMPosition,
+		SearchTerm:      options.searchTerm,
 	}, sessionState, ua
 }
 
@@ -211,20 +246,14 @@ func IgnoreHit(r *http.Request) bool {
 		return true
 	}
 
-	userAgentResult := ParseUserAgent(r.UserAgent())
+	userAgentResult := UserAgentFromRequest(r)
 
 	if ignoreBrowserVersion(userAgentResult.Browser, userAgentResult.BrowserVersion) {
 		return true
 	}
 
 	// filter for bot keywords (most expensive operation last)
-	for _, botUserAgent := range userAgentBlacklist {
-		if strings.Contains(userAgent, botUserAgent) {
-			return true
-		}
-	}
-
-	return false
+	return isBotUserAgent(userAgent)
 }
 
 // HitOptionsFromRequest returns the HitOptions for given client request.
@@ -252,24 +281,36 @@ func HitOptionsFromRequest(r *http.Request) *HitOptions {
 
 func newSession(r *http.Request, options *HitOptions, fingerprint uint64, now time.Time, path, title string) (*Session, *UserAgent) {
 	// shorten strings if required and parse User-Agent to extract more data (OS, Browser)
-	userAgent := r.UserAgent()
-	uaInfo := ParseUserAgent(userAgent)
+	uaInfo := UserAgentFromRequest(r)
 	uaInfo.OS = shortenString(uaInfo.OS, 20)
 	uaInfo.OSVersion = shortenString(uaInfo.OSVersion, 20)
 	uaInfo.Browser = shortenString(uaInfo.Browser, 20)
 	uaInfo.BrowserVersion = shortenString(uaInfo.BrowserVersion, 20)
+	uaInfo.Vendor = shortenString(uaInfo.Vendor, 20)
+	uaInfo.Model = shortenString(uaInfo.Model, 100)
 	lang := shortenString(getLanguage(r), 10)
 	referrer, referrerName, referrerIcon := getReferrer(r, options.Referrer, options.ReferrerDomainBlacklist, options.ReferrerDomainBlacklistIncludesSubdomains)
+
+	if referrerName == "" && options.androidApps != nil {
+		referrerName, referrerIcon = options.androidApps.Resolve(referrer)
+	}
+
 	referrer = shortenString(referrer, 200)
 	referrerName = shortenString(referrerName, 200)
 	referrerIcon = shortenString(referrerIcon, 2000)
 	screen := GetScreenClass(options.ScreenWidth)
 	utm := getUTMParams(r)
 	otm := getOTMParams(r)
-	countryCode, city := "", ""
+
+	if utm.term == "" {
+		utm.term = shortenString(getSearchEngineKeyword(r, options.Referrer), 200)
+	}
+
+	var location GeoLocation
 
 	if options.geoDB != nil {
-		countryCode, city = options.geoDB.CountryCodeAndCity(getIP(r))
+		location = options.geoDB.Resolve(getIP(r))
+		location.shorten()
 	}
 
 	if options.ScreenWidth <= 0 || options.ScreenHeight <= 0 {
@@ -291,17 +332,30 @@ func newSession(r *http.Request, options *HitOptions, fingerprint uint64, now ti
 		EntryTitle:     title,
 		ExitTitle:      title,
 		Language:       lang,
-		CountryCode:    countryCode,
-		City:           city,
+		CountryCode:    location.CountryCode,
+		City:           location.City,
+		CityGeoNameID:  location.CityGeoNameID,
+		Region:         location.Region,
+		PostalCode:     location.PostalCode,
+		Latitude:       location.Latitude,
+		Longitude:      location.Longitude,
+		TimeZone:       location.TimeZone,
+		ASN:            location.ASN,
+		ASOrganization: location.ASOrganization,
 		Referrer:       referrer,
 		ReferrerName:   referrerName,
 		ReferrerIcon:   referrerIcon,
+		Channel:        getChannel(r, options.URL, referrer, referrerName, utm, otm),
 		OS:             uaInfo.OS,
 		OSVersion:      uaInfo.OSVersion,
 		Browser:        uaInfo.Browser,
 		BrowserVersion: uaInfo.BrowserVersion,
 		Desktop:        uaInfo.IsDesktop(),
 		Mobile:         uaInfo.IsMobile(),
+		WebView:        uaInfo.WebView,
+		DeviceType:     uaInfo.DeviceType(options.ScreenWidth, options.ScreenHeight),
+		DeviceVendor:   uaInfo.Vendor,
+		DeviceModel:    uaInfo.Model,
 		ScreenWidth:    options.ScreenWidth,
 		ScreenHeight:   options.ScreenHeight,
 		ScreenClass:    screen,
@@ -318,6 +372,30 @@ func newSession(r *http.Request, options *HitOptions, fingerprint uint64, now ti
 
 }
 
+// isNewVisitor returns true if the visitor had no session within the HitOptions.ReturningVisitorMaxAge.
+// The lookup is only done on session creation, so that the flag stays the same for the whole session.
+// As the session cache already missed for the session max age and might not keep sessions for longer (like SessionCacheRedis),
+// the Store is looked up directly if set.
+func isNewVisitor(options *HitOptions, fingerprint uint64, now time.Time) bool {
+	if options.ReturningVisitorMaxAge < 0 {
+		return true
+	}
+
+	maxAge := now.Add(-options.ReturningVisitorMaxAge)
+
+	if options.store != nil {
+		returning, err := options.store.HasSession(options.ClientID, fingerprint, maxAge)
+
+		if err != nil {
+			logger.Printf("error looking up returning visitor: %s", err)
+		}
+
+		return !returning
+	}
+
+	return options.SessionCache.Get(options.ClientID, fingerprint, maxAge) == nil
+}
+
 func updateSession(options *HitOptions, session *Session, now time.Time, path, title string) uint32 {
 	top := now.Unix() - session.Time.Unix()
 
@@ -338,6 +416,12 @@ func updateSession(options *HitOptions, session *Session, now time.Time, path, t
 	session.ExitPath = path
 	session.ExitTitle = title
 	session.PageViews++
+
+	// pick up the Android app name in case it has been resolved since the session started
+	if session.ReferrerName == "" && options.androidApps != nil {
+		session.ReferrerName, session.ReferrerIcon = options.androidApps.Cached(session.Referrer)
+	}
+
 	return uint32(top)
 }
 
@@ -392,7 +476,25 @@ func getRequestURI(r *http.Request, options *HitOptions) {
 		} else {
 			options.Path = u.Path
 		}
+
+		options.searchTerm = getSearchTerm(u, options.SearchQueryParams)
+	}
+}
+
+func getSearchTerm(u *url.URL, params []string) string {
+	if len(params) == 0 {
+		return ""
+	}
+
+	query := u.Query()
+
+	for _, param := range params {
+		if term := strings.TrimSpace(query.Get(param)); term != "" {
+			return shortenString(term, 200)
+		}
 	}
+
+	return ""
 }
 
 func shortenString(str string, n int) string {
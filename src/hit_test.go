@@ -5,6 +5,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 )
@@ -139,6 +140,47 @@ func TestHitFromRequestSession(t *testing.T) {
 	assert.Equal(t, uint32(5), pageView2.DurationSeconds)
 }
 
+func TestHitFromRequestNewVisitor(t *testing.T) {
+	sessionCache := NewSessionCacheMem(NewMockClient(), 100)
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/84.0.4147.135 Safari/537.36")
+	pageView, sessionState, _ := HitFromRequest(req, "salt", &HitOptions{
+		SessionCache: sessionCache,
+	})
+	assert.True(t, pageView.NewVisitor)
+	assert.True(t, sessionState.State.NewVisitor)
+	key := getSessionKey(0, sessionState.State.VisitorID)
+	session := sessionCache.sessions[key]
+	session.Time = session.Time.Add(-time.Hour)
+	sessionCache.sessions[key] = session
+	pageView, sessionState, _ = HitFromRequest(req, "salt", &HitOptions{
+		SessionCache: sessionCache,
+	})
+	assert.Nil(t, sessionState.Cancel)
+	assert.False(t, pageView.NewVisitor)
+	assert.False(t, sessionState.State.NewVisitor)
+
+	// the flag is kept for the whole session
+	_, sessionState, _ = HitFromRequest(req, "salt", &HitOptions{
+		SessionCache: sessionCache,
+	})
+	assert.NotNil(t, sessionState.Cancel)
+	assert.False(t, sessionState.State.NewVisitor)
+
+	// outside the lookback or disabled lookup
+	for _, maxAge := range []time.Duration{time.Minute * 30, -1} {
+		session = sessionCache.sessions[key]
+		session.Time = session.Time.Add(-time.Hour)
+		sessionCache.sessions[key] = session
+		_, sessionState, _ = HitFromRequest(req, "salt", &HitOptions{
+			SessionCache:           sessionCache,
+			ReturningVisitorMaxAge: maxAge,
+		})
+		assert.Nil(t, sessionState.Cancel)
+		assert.True(t, sessionState.State.NewVisitor)
+	}
+}
+
 func TestHitFromRequestBounces(t *testing.T) {
 	cleanupDB()
 	uaString := "Mozilla/5.0 (Windows NT 10.0) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/84.0.4147.135 Safari/537.36"
@@ -192,6 +234,20 @@ func TestHitFromRequestOverwritePathAndReferrer(t *testing.T) {
 	}
 }
 
+func TestHitFromRequestDeviceModel(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("User-Agent", "Mozilla/5.0 (Linux; Android 10; K) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/110.0.0.0 Mobile Safari/537.36")
+	req.Header.Set("Sec-CH-UA", `"Chromium";v="110", "Not A(Brand";v="24", "Google Chrome";v="110"`)
+	req.Header.Set("Sec-CH-UA-Platform", `"Android"`)
+	req.Header.Set("Sec-CH-UA-Model", `"SM-`+strings.Repeat("X", 200)+`"`)
+	pageView, sessionState, _ := HitFromRequest(req, "salt", &HitOptions{
+		SessionCache: NewSessionCacheMem(NewMockClient(), 100),
+	})
+	assert.Len(t, sessionState.State.DeviceModel, 100)
+	assert.Len(t, pageView.DeviceModel, 100)
+	assert.Equal(t, "Samsung", sessionState.State.DeviceVendor)
+}
+
 func TestHitFromRequestScreenSize(t *testing.T) {
 	req := httptest.NewRequest(http.MethodGet, "http://foo.bar/test/path?query=param&foo=bar#anchor", nil)
 	_, sessionState, _ := HitFromRequest(req, "salt", &HitOptions{
@@ -437,6 +493,21 @@ func TestHitOptionsFromRequest(t *testing.T) {
 	}
 }
 
+func TestGetRequestURISearchTerm(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/search?q=+shoes+&p=2", nil)
+	options := &HitOptions{SearchQueryParams: []string{"s", "q"}}
+	getRequestURI(r, options)
+	assert.Equal(t, "/search", options.Path)
+	assert.Equal(t, "shoes", options.searchTerm)
+	options = &HitOptions{URL: "https://example.com/products?s=dress&q=shoes", SearchQueryParams: []string{"s", "q"}}
+	getRequestURI(r, options)
+	assert.Equal(t, "/products", options.Path)
+	assert.Equal(t, "dress", options.searchTerm)
+	options = &HitOptions{URL: "https://example.com/products?q=shoes"}
+	getRequestURI(r, options)
+	assert.Empty(t, options.searchTerm)
+}
+
 func TestShortenString(t *testing.T) {
 	out := shortenString("Hello World", 5)
 
@@ -0,0 +1,296 @@
+package omisocial
+
+import (
+	"encoding/binary"
+	"errors"
+	"log"
+	"math"
+	"net"
+	"os"
+	"strings"
+)
+
+const (
+	ip2LocationHeaderSize = 64
+	ip2LocationMaxDBType  = 25
+	ip2LocationEmptyValue = "-"
+)
+
+// Column positions by database type (DB1 to DB25). Zero means the database doesn't contain the field.
+var (
+	ip2LocationCountryPosition   = [ip2LocationMaxDBType + 1]uint8{0, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2}
+	ip2LocationRegionPosition    = [ip2LocationMaxDBType + 1]uint8{0, 0, 0, 3, 3, 3, 3, 3, 3, 3, 3, 3, 3, 3, 3, 3, 3, 3, 3, 3, 3, 3, 3, 3, 3, 3}
+	ip2LocationCityPosition      = [ip2LocationMaxDBType + 1]uint8{0, 0, 0, 4, 4, 4, 4, 4, 4, 4, 4, 4, 4, 4, 4, 4, 4, 4, 4, 4, 4, 4, 4, 4, 4, 4}
+	ip2LocationLatitudePosition  = [ip2LocationMaxDBType + 1]uint8{0, 0, 0, 0, 0, 5, 5, 0, 5, 5, 5, 5, 5, 5, 5, 5, 5, 5, 5, 5, 5, 5, 5, 5, 5, 5}
+	ip2LocationLongitudePosition = [ip2LocationMaxDBType + 1]uint8{0, 0, 0, 0, 0, 6, 6, 0, 6, 6, 6, 6, 6, 6, 6, 6, 6, 6, 6, 6, 6, 6, 6, 6, 6, 6}
+	ip2LocationZipCodePosition   = [ip2LocationMaxDBType + 1]uint8{0, 0, 0, 0, 0, 0, 0, 0, 0, 7, 7, 7, 7, 0, 7, 7, 7, 0, 7, 0, 7, 7, 7, 0, 7, 7}
+	ip2LocationTimeZonePosition  = [ip2LocationMaxDBType + 1]uint8{0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 8, 8, 7, 8, 8, 8, 7, 8, 0, 8, 8, 8, 0, 8, 8}
+)
+
+// IP2LocationDBConfig is the configuration for the IP2LocationDB.
+type IP2LocationDBConfig struct {
+	// File is the path (including the filename) to the IP2Location (LITE) BIN database file.
+	// Use the DB11 (or higher) database to get the postal code and time zone.
+	File string
+
+	// Logger is the log.Logger used for logging.
+	// Note that this will log the IP address and should therefore only be used for debugging.
+	// Set it to nil to disable logging for IP2LocationDB.
+	Logger *log.Logger
+}
+
+// IP2LocationDB maps IPs to their geo location based on an IP2Location or IP2Location LITE BIN database.
+// The time zone is the UTC offset (like +01:00) and the ASN is not available in these databases.
+type IP2LocationDB struct {
+	data          []byte
+	dbType        uint8
+	dbColumn      uint8
+	ipv4Count     uint32
+	ipv4Base      uint32
+	ipv6Count     uint32
+	ipv6Base      uint32
+	ipv4IndexBase uint32
+	ipv6IndexBase uint32
+	logger        *log.Logger
+}
+
+// NewIP2LocationDB creates a new IP2LocationDB for given database file.
+// The file is loaded into memory. The database should be updated on a regular basis.
+func NewIP2LocationDB(config IP2LocationDBConfig) (*IP2LocationDB, error) {
+	data, err := os.ReadFile(config.File)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if len(data) < ip2LocationHeaderSize {
+		return nil, errors.New("invalid IP2Location database file")
+	}
+
+	db := &IP2LocationDB{
+		data:          data,
+		dbType:        data[0],
+		dbColumn:      data[1],
+		ipv4Count:     binary.LittleEndian.Uint32(data[5:]),
+		ipv4Base:      binary.LittleEndian.Uint32(data[9:]),
+		ipv6Count:     binary.LittleEndian.Uint32(data[13:]),
+		ipv6Base:      binary.LittleEndian.Uint32(data[17:]),
+		ipv4IndexBase: binary.LittleEndian.Uint32(data[21:]),
+		ipv6IndexBase: binary.LittleEndian.Uint32(data[25:]),
+		logger:        config.Logger,
+	}
+
+	if db.dbType == 0 || db.dbType > ip2LocationMaxDBType || db.dbColumn < 2 {
+		return nil, errors.New("unsupported IP2Location database type")
+	}
+
+	return db, nil
+}
+
+// Resolve implements the GeoResolver interface.
+func (db *IP2LocationDB) Resolve(ip string) GeoLocation {
+	parsedIP := net.ParseIP(ip)
+
+	if parsedIP == nil {
+		if db.logger != nil {
+			db.logger.Printf("error parsing IP address %s", ip)
+		}
+
+		return GeoLocation{}
+	}
+
+	var row, columnOffset uint32
+	var found bool
+
+	if ipv4 := parsedIP.To4(); ipv4 != nil {
+		row, found = db.findIPv4(binary.BigEndian.Uint32(ipv4))
+	} else {
+		row, found = db.findIPv6(binary.BigEndian.Uint64(parsedIP[:8]), binary.BigEndian.Uint64(parsedIP[8:]))
+		columnOffset = 12 // the IP number is 16 instead of 4 bytes long
+	}
+
+	if !found {
+		if db.logger != nil {
+			db.logger.Printf("error looking up IP address %s", parsedIP)
+		}
+
+		return GeoLocation{}
+	}
+
+	column := func(positions [ip2LocationMaxDBType + 1]uint8) (uint32, bool) {
+		position := positions[db.dbType]
+
+		if position == 0 {
+			return 0, false
+		}
+
+		return row + columnOffset + uint32(position-1)*4, true
+	}
+	var location GeoLocation
+
+	if pos, ok := column(ip2LocationCountryPosition); ok {
+		location.CountryCode = strings.ToLower(db.string(db.uint32(pos)))
+	}
+
+	if pos, ok := column(ip2LocationRegionPosition); ok {
+		location.Region = db.string(db.uint32(pos))
+	}
+
+	if pos, ok := column(ip2LocationCityPosition); ok {
+		location.City = db.string(db.uint32(pos))
+	}
+
+	if pos, ok := column(ip2LocationZipCodePosition); ok {
+		location.PostalCode = db.string(db.uint32(pos))
+	}
+
+	if pos, ok := column(ip2LocationTimeZonePosition); ok {
+		location.TimeZone = db.string(db.uint32(pos))
+	}
+
+	if pos, ok := column(ip2LocationLatitudePosition); ok {
+		location.Latitude = db.float(pos)
+	}
+
+	if pos, ok := column(ip2LocationLongitudePosition); ok {
+		location.Longitude = db.float(pos)
+	}
+
+	return location
+}
+
+// findIPv4 returns the (1-based) offset of the row containing given IP number.
+func (db *IP2LocationDB) findIPv4(ip uint32) (uint32, bool) {
+	if ip == math.MaxUint32 {
+		ip--
+	}
+
+	low, high := uint32(0), db.ipv4Count
+
+	if db.ipv4IndexBase > 0 {
+		index := db.ipv4IndexBase + (ip>>16)<<3
+		low, high = db.uint32(index), db.uint32(index+4)
+	}
+
+	columnSize := uint32(db.dbColumn) * 4
+
+	for low <= high {
+		mid := (low + high) / 2
+		row := db.ipv4Base + mid*columnSize
+		from, to := db.uint32(row), db.uint32(row+columnSize)
+
+		if ip >= from && ip < to {
+			return row, true
+		}
+
+		if ip < from {
+			if mid == 0 {
+				break
+			}
+
+			high = mid - 1
+		} else {
+			low = mid + 1
+		}
+	}
+
+	return 0, false
+}
+
+// findIPv6 returns the (1-based) offset of the row containing given IP number, split into the high and low 64 bits.
+func (db *IP2LocationDB) findIPv6(ipHigh, ipLow uint64) (uint32, bool) {
+	if db.ipv6Count == 0 {
+		return 0, false
+	}
+
+	if ipHigh == math.MaxUint64 && ipLow == math.MaxUint64 {
+		ipLow--
+	}
+
+	low, high := uint32(0), db.ipv6Count
+
+	if db.ipv6IndexBase > 0 {
+		index := db.ipv6IndexBase + uint32(ipHigh>>48)<<3
+		low, high = db.uint32(index), db.uint32(index+4)
+	}
+
+	columnSize := 16 + uint32(db.dbColumn-1)*4
+	less := func(aHigh, aLow, bHigh, bLow uint64) bool {
+		return aHigh < bHigh || (aHigh == bHigh && aLow < bLow)
+	}
+
+	for low <= high {
+		mid := (low + high) / 2
+		row := db.ipv6Base + mid*columnSize
+		fromHigh, fromLow := db.uint128(row)
+		toHigh, toLow := db.uint128(row + columnSize)
+
+		if !less(ipHigh, ipLow, fromHigh, fromLow) && less(ipHigh, ipLow, toHigh, toLow) {
+			return row, true
+		}
+
+		if less(ipHigh, ipLow, fromHigh, fromLow) {
+			if mid == 0 {
+				break
+			}
+
+			high = mid - 1
+		} else {
+			low = mid + 1
+		}
+	}
+
+	return 0, false
+}
+
+// uint32 reads the number at given 1-based offset.
+func (db *IP2LocationDB) uint32(pos uint32) uint32 {
+	i := int(pos) - 1
+
+	if i < 0 || i+4 > len(db.data) {
+		return 0
+	}
+
+	return binary.LittleEndian.Uint32(db.data[i:])
+}
+
+// uint128 reads the high and low 64 bits of the number at given 1-based offset.
+func (db *IP2LocationDB) uint128(pos uint32) (uint64, uint64) {
+	i := int(pos) - 1
+
+	if i < 0 || i+16 > len(db.data) {
+		return 0, 0
+	}
+
+	return binary.LittleEndian.Uint64(db.data[i+8:]), binary.LittleEndian.Uint64(db.data[i:])
+}
+
+func (db *IP2LocationDB) float(pos uint32) float64 {
+	f := float64(math.Float32frombits(db.uint32(pos)))
+
+	// reduce the float32 precision noise to the six decimal places stored in the database
+	return math.Round(f*1e6) / 1e6
+}
+
+// string reads the length-prefixed string at given 0-based offset.
+func (db *IP2LocationDB) string(pos uint32) string {
+	i := int(pos)
+
+	if i >= len(db.data) {
+		return ""
+	}
+
+	end := i + 1 + int(db.data[i])
+
+	if end > len(db.data) {
+		return ""
+	}
+
+	str := string(db.data[i+1 : end])
+
+	if str == ip2LocationEmptyValue {
+		return ""
+	}
+
+	return str
+}
@@ -0,0 +1,87 @@
+package omisocial
+
+import (
+	"bytes"
+	"encoding/binary"
+	"github.com/stretchr/testify/assert"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestIP2LocationDB_Resolve(t *testing.T) {
+	db, err := NewIP2LocationDB(IP2LocationDBConfig{
+		File: writeIP2LocationTestDB(t),
+	})
+	assert.NoError(t, err)
+	location := db.Resolve("81.2.69.142")
+	assert.Equal(t, "gb", location.CountryCode)
+	assert.Equal(t, "England", location.Region)
+	assert.Equal(t, "London", location.City)
+	assert.Equal(t, "EC1A", location.PostalCode)
+	assert.Equal(t, "+01:00", location.TimeZone)
+	assert.InDelta(t, 51.5085, location.Latitude, 0.0001)
+	assert.InDelta(t, -0.1257, location.Longitude, 0.0001)
+	assert.Equal(t, GeoLocation{}, db.Resolve("81.2.70.1"))
+	assert.Equal(t, GeoLocation{}, db.Resolve("10.0.0.1"))
+	assert.Equal(t, GeoLocation{}, db.Resolve("2001:db8::1"))
+	assert.Equal(t, GeoLocation{}, db.Resolve("invalid"))
+}
+
+func TestNewIP2LocationDBInvalid(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "invalid.bin")
+	assert.NoError(t, os.WriteFile(file, []byte("invalid"), 0644))
+	_, err := NewIP2LocationDB(IP2LocationDBConfig{File: file})
+	assert.Error(t, err)
+}
+
+// writeIP2LocationTestDB writes a DB11 database containing the IPv4 ranges 0.0.0.0, 81.2.69.0 (London), and 81.2.70.0.
+func writeIP2LocationTestDB(t *testing.T) string {
+	const columns = 8
+	strings := new(bytes.Buffer)
+	rowsSize := 4 * columns * 4
+	stringsBase := uint32(ip2LocationHeaderSize + rowsSize)
+	addString := func(values ...string) uint32 {
+		pos := stringsBase + uint32(strings.Len())
+
+		for _, value := range values {
+			strings.WriteByte(byte(len(value)))
+			strings.WriteString(value)
+		}
+
+		return pos
+	}
+	empty := addString("-", "-")
+	rows := [][columns]uint32{
+		{0, empty, empty, empty, 0, 0, empty, empty},
+		{
+			binary.BigEndian.Uint32([]byte{81, 2, 69, 0}),
+			addString("GB", "United Kingdom of Great Britain and Northern Ireland"),
+			addString("England"),
+			addString("London"),
+			math.Float32bits(51.5085),
+			math.Float32bits(-0.1257),
+			addString("EC1A"),
+			addString("+01:00"),
+		},
+		{binary.BigEndian.Uint32([]byte{81, 2, 70, 0}), empty, empty, empty, 0, 0, empty, empty},
+		{math.MaxUint32},
+	}
+	data := new(bytes.Buffer)
+	header := make([]byte, ip2LocationHeaderSize)
+	header[0] = 11
+	header[1] = columns
+	binary.LittleEndian.PutUint32(header[5:], uint32(len(rows)-1))
+	binary.LittleEndian.PutUint32(header[9:], ip2LocationHeaderSize+1)
+	data.Write(header)
+
+	for _, row := range rows {
+		assert.NoError(t, binary.Write(data, binary.LittleEndian, row))
+	}
+
+	data.Write(strings.Bytes())
+	file := filepath.Join(t.TempDir(), "IP2LOCATION-LITE-DB11.BIN")
+	assert.NoError(t, os.WriteFile(file, data.Bytes(), 0644))
+	return file
+}
@@ -0,0 +1,169 @@
+package omisocial
+
+import (
+	"errors"
+	"fmt"
+)
+
+const (
+	defaultPathFlowSteps = 3
+)
+
+var (
+	// ErrNoJourneyPath is returned in case no path was specified to list the next or previous pages for.
+	ErrNoJourneyPath = errors.New("no path specified")
+)
+
+// NextPages returns the pages viewed right after the Filter.Path within the same session.
+// Sessions that ended on the path are listed with an empty path.
+func (analyzer *Analyzer) NextPages(filter *Filter) ([]JourneyStats, error) {
+	return analyzer.journey(filter, true)
+}
+
+// PreviousPages returns the pages viewed right before the Filter.Path within the same session.
+// Sessions that started on the path are listed with an empty path.
+func (analyzer *Analyzer) PreviousPages(filter *Filter) ([]JourneyStats, error) {
+	return analyzer.journey(filter, false)
+}
+
+// TopPaths returns the most common sequences of given number of pages viewed within a session, which can be used for Sankey diagrams.
+// The sequences start on the entry page, or on the first view of the Filter.Path if set, and are shorter for sessions with fewer page views.
+// The number of steps is set to 3 in case it's less or equal to zero.
+func (analyzer *Analyzer) TopPaths(filter *Filter, steps int) ([]PathFlowStats, error) {
+	filter = analyzer.getFilter(filter)
+
+	if steps <= 0 {
+		steps = defaultPathFlowSteps
+	}
+
+	sequenceArgs, sequenceQuery := analyzer.sessionPathsQuery(filter)
+	args := make([]interface{}, 0, len(sequenceArgs)*2+4)
+	args = append(args, sequenceArgs...)
+	start, where := "1", ""
+
+	if filter.Path != "" {
+		start, where = "indexOf(paths, ?)", "WHERE has(paths, ?) "
+		args = append(args, filter.Path, filter.Path)
+	}
+
+	args = append(args, steps)
+	args = append(args, sequenceArgs...)
+
+	if filter.Path != "" {
+		args = append(args, filter.Path)
+	}
+
+	query := fmt.Sprintf(`SELECT flow paths,
+		uniq(visitor_id) visitors,
+		count() sessions,
+		sessions / greatest((SELECT count() FROM (%s) %s), 1) relative_sessions
+		FROM (
+			SELECT visitor_id,
+			arraySlice(paths, %s, ?) flow
+			FROM (%s)
+			%s
+		)
+		GROUP BY flow
+		ORDER BY sessions DESC, flow
+		%s%s`, sequenceQuery, where, start, sequenceQuery, where, filter.withLimit(), filter.withOffset())
+	var stats []PathFlowStats
+
+	if err := analyzer.store.Select(&stats, query, args...); err != nil {
+		return nil, err
+	}
+
+	return stats, nil
+}
+
+// journey returns the pages viewed right after (next) or before the Filter.Path.
+func (analyzer *Analyzer) journey(filter *Filter, next bool) ([]JourneyStats, error) {
+	filter = analyzer.getFilter(filter)
+
+	if filter.Path == "" {
+		return nil, ErrNoJourneyPath
+	}
+
+	sequenceArgs, sequenceQuery := analyzer.sessionPathsQuery(filter)
+	args := make([]interface{}, 0, len(sequenceArgs)*2+2)
+	args = append(args, filter.Path)
+	args = append(args, sequenceArgs...)
+	args = append(args, filter.Path)
+	args = append(args, sequenceArgs...)
+	neighbor := "i + 1"
+
+	if next {
+		neighbor = "i - 1"
+	}
+
+	// the paths are enclosed in empty strings for the entry and exit of the session
+	query := fmt.Sprintf(`SELECT path,
+		uniq(visitor_id) visitors,
+		uniq(visitor_id, session_id) sessions,
+		sessions / greatest((SELECT countIf(has(paths, ?)) FROM (%s)), 1) relative_sessions
+		FROM (
+			SELECT visitor_id,
+			session_id,
+			arrayJoin(arrayFilter((p, i) -> pages[%s] = ?, pages, arrayEnumerate(pages))) path
+			FROM (
+				SELECT visitor_id,
+				session_id,
+				arrayConcat([''], paths, ['']) pages
+				FROM (%s)
+			)
+		)
+		GROUP BY path
+		ORDER BY sessions DESC, path
+		%s%s`, sequenceQuery, neighbor, sequenceQuery, filter.withLimit(), filter.withOffset())
+	var stats []JourneyStats
+
+	if err := analyzer.store.Select(&stats, query, args...); err != nil {
+		return nil, err
+	}
+
+	return stats, nil
+}
+
+// sessionPathsQuery returns the query for the paths of all sessions ordered by time.
+// The path is used to select the pages within the sequences and is therefore ignored.
+// The path pattern and other page filters restrict the sessions to those with a matching page view.
+func (analyzer *Analyzer) sessionPathsQuery(filter *Filter) ([]interface{}, string) {
+	path, pathPattern, entryPath, exitPath, eventName, conditions := filter.Path, filter.PathPattern, filter.EntryPath, filter.ExitPath, filter.EventName, filter.Conditions
+	pageConditions := filter.pageConditions()
+	filter.Path, filter.PathPattern, filter.EntryPath, filter.ExitPath, filter.EventName, filter.Conditions = "", "", "", "", "", filter.sessionConditions()
+	args, filterQuery := filter.query()
+	sessionQuery := ""
+
+	if entryPath != "" || exitPath != "" {
+		filter.EntryPath, filter.ExitPath = entryPath, exitPath
+		sessionArgs, sessionFilterQuery := filter.query()
+		args = append(args, sessionArgs...)
+		sessionQuery = fmt.Sprintf(`AND (visitor_id, session_id) IN (
+			SELECT visitor_id, session_id
+			FROM session
+			WHERE %s
+			GROUP BY visitor_id, session_id
+			HAVING sum(sign) > 0
+		) `, sessionFilterQuery)
+	}
+
+	if pathPattern != "" || len(pageConditions) > 0 {
+		filter.PathPattern, filter.Conditions = pathPattern, pageConditions
+		timeArgs, timeQuery := filter.queryTime()
+		pageArgs, pageFilterQuery := filter.queryPageOrEvent()
+		args = append(args, timeArgs...)
+		args = append(args, pageArgs...)
+		sessionQuery += fmt.Sprintf(`AND (visitor_id, session_id) IN (
+			SELECT visitor_id, session_id
+			FROM page_view
+			WHERE %s AND %s
+		) `, timeQuery, pageFilterQuery)
+	}
+
+	filter.Path, filter.PathPattern, filter.EntryPath, filter.ExitPath, filter.EventName, filter.Conditions = path, pathPattern, entryPath, exitPath, eventName, conditions
+	return args, fmt.Sprintf(`SELECT visitor_id,
+		session_id,
+		arrayMap(p -> p.2, arraySort(p -> p.1, groupArray((time, path)))) paths
+		FROM page_view
+		WHERE %s %s
+		GROUP BY visitor_id, session_id`, filterQuery, sessionQuery)
+}
@@ -0,0 +1,114 @@
+package omisocial
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestAnalyzer_NextPreviousPages(t *testing.T) {
+	cleanupDB()
+	assert.NoError(t, dbClient.SavePageViews([]PageView{
+		{VisitorID: 1, SessionID: 1, Time: Today(), Path: "/"},
+		{VisitorID: 1, SessionID: 1, Time: Today().Add(time.Second), Path: "/pricing"},
+		{VisitorID: 1, SessionID: 1, Time: Today().Add(time.Second * 2), Path: "/signup"},
+		{VisitorID: 2, SessionID: 1, Time: Today(), Path: "/blog"},
+		{VisitorID: 2, SessionID: 1, Time: Today().Add(time.Second), Path: "/pricing"},
+		{VisitorID: 3, SessionID: 1, Time: Today(), Path: "/pricing"},
+		{VisitorID: 3, SessionID: 1, Time: Today().Add(time.Second), Path: "/signup"},
+	}))
+	time.Sleep(time.Millisecond * 20)
+	analyzer := NewAnalyzer(dbClient)
+	next, err := analyzer.NextPages(&Filter{Path: "/pricing"})
+	assert.NoError(t, err)
+	assert.Len(t, next, 2)
+	assert.Equal(t, "/signup", next[0].Path)
+	assert.Equal(t, 2, next[0].Visitors)
+	assert.Equal(t, 2, next[0].Sessions)
+	assert.InDelta(t, 0.6666, next[0].RelativeSessions, 0.01)
+	assert.Equal(t, "", next[1].Path)
+	assert.Equal(t, 1, next[1].Sessions)
+	previous, err := analyzer.PreviousPages(&Filter{Path: "/pricing"})
+	assert.NoError(t, err)
+	assert.Len(t, previous, 3)
+	assert.Equal(t, "", previous[0].Path)
+	assert.Equal(t, "/", previous[1].Path)
+	assert.Equal(t, "/blog", previous[2].Path)
+	previous, err = analyzer.PreviousPages(&Filter{Path: "/signup", Limit: 1})
+	assert.NoError(t, err)
+	assert.Len(t, previous, 1)
+	assert.Equal(t, "/pricing", previous[0].Path)
+	assert.Equal(t, 2, previous[0].Sessions)
+	assert.InDelta(t, 1, previous[0].RelativeSessions, 0.01)
+	_, err = analyzer.NextPages(nil)
+	assert.Equal(t, ErrNoJourneyPath, err)
+}
+
+func TestAnalyzer_TopPaths(t *testing.T) {
+	cleanupDB()
+	assert.NoError(t, dbClient.SavePageViews([]PageView{
+		{VisitorID: 1, SessionID: 1, Time: Today(), Path: "/"},
+		{VisitorID: 1, SessionID: 1, Time: Today().Add(time.Second), Path: "/pricing"},
+		{VisitorID: 1, SessionID: 1, Time: Today().Add(time.Second * 2), Path: "/signup"},
+		{VisitorID: 2, SessionID: 1, Time: Today(), Path: "/"},
+		{VisitorID: 2, SessionID: 1, Time: Today().Add(time.Second), Path: "/pricing"},
+		{VisitorID: 2, SessionID: 1, Time: Today().Add(time.Second * 2), Path: "/signup"},
+		{VisitorID: 2, SessionID: 1, Time: Today().Add(time.Second * 3), Path: "/welcome"},
+		{VisitorID: 3, SessionID: 1, Time: Today(), Path: "/blog"},
+		{VisitorID: 3, SessionID: 1, Time: Today().Add(time.Second), Path: "/pricing"},
+	}))
+	time.Sleep(time.Millisecond * 20)
+	analyzer := NewAnalyzer(dbClient)
+	stats, err := analyzer.TopPaths(nil, 0)
+	assert.NoError(t, err)
+	assert.Len(t, stats, 2)
+	assert.Equal(t, []string{"/", "/pricing", "/signup"}, stats[0].Paths)
+	assert.Equal(t, 2, stats[0].Visitors)
+	assert.Equal(t, 2, stats[0].Sessions)
+	assert.InDelta(t, 0.6666, stats[0].RelativeSessions, 0.01)
+	assert.Equal(t, []string{"/blog", "/pricing"}, stats[1].Paths)
+	stats, err = analyzer.TopPaths(&Filter{Path: "/pricing"}, 2)
+	assert.NoError(t, err)
+	assert.Len(t, stats, 2)
+	assert.Equal(t, []string{"/pricing", "/signup"}, stats[0].Paths)
+	assert.Equal(t, 2, stats[0].Sessions)
+	assert.Equal(t, []string{"/pricing"}, stats[1].Paths)
+	assert.InDelta(t, 0.3333, stats[1].RelativeSessions, 0.01)
+}
+
+func TestAnalyzer_JourneyPageFilter(t *testing.T) {
+	cleanupDB()
+	assert.NoError(t, dbClient.SavePageViews([]PageView{
+		{VisitorID: 1, SessionID: 1, Time: Today(), Path: "/", Title: "Home"},
+		{VisitorID: 1, SessionID: 1, Time: Today().Add(time.Second), Path: "/pricing", Title: "Pricing"},
+		{VisitorID: 1, SessionID: 1, Time: Today().Add(time.Second * 2), Path: "/signup", Title: "Sign up"},
+		{VisitorID: 2, SessionID: 1, Time: Today(), Path: "/blog/post", Title: "Post"},
+		{VisitorID: 2, SessionID: 1, Time: Today().Add(time.Second), Path: "/pricing", Title: "Pricing"},
+	}))
+	time.Sleep(time.Millisecond * 20)
+	analyzer := NewAnalyzer(dbClient)
+	stats, err := analyzer.TopPaths(&Filter{PathPattern: "^/blog/"}, 0)
+	assert.NoError(t, err)
+	assert.Len(t, stats, 1)
+	assert.Equal(t, []string{"/blog/post", "/pricing"}, stats[0].Paths)
+	assert.InDelta(t, 1, stats[0].RelativeSessions, 0.01)
+	next, err := analyzer.NextPages(&Filter{Path: "/pricing", Conditions: []Condition{
+		{Field: "title", Operator: FilterIn, Values: []string{"Sign up"}},
+	}})
+	assert.NoError(t, err)
+	assert.Len(t, next, 1)
+	assert.Equal(t, "/signup", next[0].Path)
+	assert.Equal(t, 1, next[0].Sessions)
+	assert.InDelta(t, 1, next[0].RelativeSessions, 0.01)
+}
+
+func TestAnalyzer_NextPagesNoPath(t *testing.T) {
+	analyzer := NewAnalyzer(NewMockClient())
+	_, err := analyzer.NextPages(&Filter{ClientID: 1})
+	assert.Equal(t, ErrNoJourneyPath, err)
+	_, err = analyzer.PreviousPages(&Filter{ClientID: 1, PathPattern: "^/blog"})
+	assert.Equal(t, ErrNoJourneyPath, err)
+	stats, err := analyzer.TopPaths(&Filter{ClientID: 1}, 3)
+	assert.NoError(t, err)
+	assert.Empty(t, stats)
+}
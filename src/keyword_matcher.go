@@ -0,0 +1,103 @@
+package omisocial
+
+// keywordMatcher finds keywords in a string using the Aho–Corasick algorithm.
+// All keywords are matched in a single pass, instead of calling strings.Contains for each of them.
+// The matcher is immutable after creation and therefore safe for concurrent use.
+type keywordMatcher struct {
+	nodes []keywordMatcherNode
+}
+
+type keywordMatcherNode struct {
+	next  map[byte]int
+	fail  int
+	match bool
+}
+
+// newKeywordMatcher creates a new keywordMatcher for given keywords.
+// Empty keywords are ignored. The keywords are matched case-sensitive.
+func newKeywordMatcher(keywords []string) *keywordMatcher {
+	matcher := &keywordMatcher{
+		nodes: []keywordMatcherNode{{next: make(map[byte]int)}},
+	}
+
+	for _, keyword := range keywords {
+		if keyword != "" {
+			matcher.add(keyword)
+		}
+	}
+
+	matcher.build()
+	return matcher
+}
+
+// Match returns true if any keyword is contained in given string.
+func (matcher *keywordMatcher) Match(str string) bool {
+	state := 0
+
+	for i := 0; i < len(str); i++ {
+		state = matcher.step(state, str[i])
+
+		if matcher.nodes[state].match {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (matcher *keywordMatcher) add(keyword string) {
+	state := 0
+
+	for i := 0; i < len(keyword); i++ {
+		next, found := matcher.nodes[state].next[keyword[i]]
+
+		if !found {
+			matcher.nodes = append(matcher.nodes, keywordMatcherNode{next: make(map[byte]int)})
+			next = len(matcher.nodes) - 1
+			matcher.nodes[state].next[keyword[i]] = next
+		}
+
+		state = next
+	}
+
+	matcher.nodes[state].match = true
+}
+
+// build sets the failure links using a breadth-first search over the trie.
+// A node matches if one of the nodes on its failure chain matches, so that keywords contained in other keywords are found.
+func (matcher *keywordMatcher) build() {
+	queue := make([]int, 0, len(matcher.nodes))
+
+	for _, next := range matcher.nodes[0].next {
+		queue = append(queue, next)
+	}
+
+	for len(queue) > 0 {
+		state := queue[0]
+		queue = queue[1:]
+
+		for c, next := range matcher.nodes[state].next {
+			matcher.nodes[next].fail = matcher.step(matcher.nodes[state].fail, c)
+
+			if matcher.nodes[matcher.nodes[next].fail].match {
+				matcher.nodes[next].match = true
+			}
+
+			queue = append(queue, next)
+		}
+	}
+}
+
+func (matcher *keywordMatcher) step(state int, c byte) int {
+	for {
+		if next, found := matcher.nodes[state].next[c]; found {
+			return next
+		}
+
+		if state == 0 {
+			return 0
+		}
+
+		state = matcher.nodes[state].fail
+	}
+}
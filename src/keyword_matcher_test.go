@@ -0,0 +1,34 @@
+package omisocial
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestKeywordMatcher(t *testing.T) {
+	matcher := newKeywordMatcher([]string{"bot", "crawler", "he", "she", "hers", ""})
+	assert.True(t, matcher.Match("googlebot/2.1"))
+	assert.True(t, matcher.Match("some crawler"))
+	assert.True(t, matcher.Match("ushers"))
+	assert.True(t, matcher.Match("she"))
+	assert.True(t, matcher.Match("bbot"))
+	assert.False(t, matcher.Match(""))
+	assert.False(t, matcher.Match("mozilla/5.0"))
+	assert.False(t, matcher.Match("bo t"))
+
+	// a keyword contained in another keyword is found through the failure links
+	matcher = newKeywordMatcher([]string{"abcd", "bc"})
+	assert.True(t, matcher.Match("xabcx"))
+	assert.False(t, newKeywordMatcher(nil).Match("anything"))
+}
+
+func TestKeywordMatcherBlacklist(t *testing.T) {
+	matcher := newKeywordMatcher(userAgentBlacklist)
+
+	for _, keyword := range userAgentBlacklist {
+		assert.True(t, matcher.Match(keyword), keyword)
+		assert.True(t, matcher.Match("prefix "+keyword+" suffix"), keyword)
+	}
+
+	assert.False(t, matcher.Match("mozilla/5.0 (windows nt 10.0; win64; x64) applewebkit/537.36 (khtml, like gecko) chrome/110.0.0.0 safari/537.36"))
+}
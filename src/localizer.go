@@ -0,0 +1,243 @@
+package omisocial
+
+import (
+	"encoding/csv"
+	"fmt"
+	iso6391 "github.com/emvi/iso-639-1"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync/atomic"
+)
+
+const defaultLocale = "en"
+
+// LocalizerConfig is the configuration for the Localizer.
+type LocalizerConfig struct {
+	// LocationFiles is a list of MaxMind City Locations CSV files (like GeoLite2-City-Locations-de.csv).
+	// Files in the same format can be used to add locales MaxMind doesn't provide, like Vietnamese.
+	// Only the geoname_id, locale_code, country_iso_code, country_name, and city_name columns are required.
+	LocationFiles []string
+
+	// LanguageFiles is a list of CSV files containing language names, with the columns locale_code, language_code, and language_name.
+	// English and native language names are built in.
+	LanguageFiles []string
+}
+
+// Localizer resolves country codes, city GeoName IDs, and ISO 639-1 language codes to display names in a locale.
+// Locales are case-insensitive and fall back to the base language (pt-BR to pt) and English.
+type Localizer struct {
+	countries map[string]map[string]string // locale -> country code -> name
+	cities    map[string]map[uint32]string // locale -> GeoName ID -> name
+	languages map[string]map[string]string // locale -> language code -> name
+}
+
+var localizer atomic.Value // *Localizer
+
+func init() {
+	localizer.Store(&Localizer{
+		countries: make(map[string]map[string]string),
+		cities:    make(map[string]map[uint32]string),
+		languages: make(map[string]map[string]string),
+	})
+}
+
+// SetLocalizer sets the Localizer used by the Analyzer to add display names to reports (see Filter.Locale).
+// This function is concurrency save and can be called at any time.
+func SetLocalizer(l *Localizer) {
+	if l != nil {
+		localizer.Store(l)
+	}
+}
+
+func getLocalizer() *Localizer {
+	return localizer.Load().(*Localizer)
+}
+
+// NewLocalizer creates a new Localizer for given configuration and loads all files.
+func NewLocalizer(config LocalizerConfig) (*Localizer, error) {
+	l := &Localizer{
+		countries: make(map[string]map[string]string),
+		cities:    make(map[string]map[uint32]string),
+		languages: make(map[string]map[string]string),
+	}
+
+	for _, file := range config.LocationFiles {
+		if err := l.readCSV(file, []string{"geoname_id", "locale_code", "country_iso_code", "country_name", "city_name"}, l.addLocation); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, file := range config.LanguageFiles {
+		if err := l.readCSV(file, []string{"locale_code", "language_code", "language_name"}, l.addLanguage); err != nil {
+			return nil, err
+		}
+	}
+
+	return l, nil
+}
+
+// CountryName returns the name of the country for given ISO country code in given locale, or an empty string if it is unknown.
+func (l *Localizer) CountryName(countryCode, locale string) string {
+	countryCode = strings.ToLower(countryCode)
+
+	for _, loc := range l.fallback(locale) {
+		if name := l.countries[loc][countryCode]; name != "" {
+			return name
+		}
+	}
+
+	return ""
+}
+
+// CityName returns the name of the city for given GeoName ID in given locale, or an empty string if it is unknown.
+func (l *Localizer) CityName(geoNameID uint32, locale string) string {
+	if geoNameID == 0 {
+		return ""
+	}
+
+	for _, loc := range l.fallback(locale) {
+		if name := l.cities[loc][geoNameID]; name != "" {
+			return name
+		}
+	}
+
+	return ""
+}
+
+// LanguageName returns the name of the language for given ISO 639-1 code in given locale, or an empty string if it is unknown.
+// The native name is returned if the locale is the language itself.
+func (l *Localizer) LanguageName(languageCode, locale string) string {
+	languageCode = strings.ToLower(languageCode)
+
+	for _, loc := range l.fallback(locale) {
+		if name := l.languages[loc][languageCode]; name != "" {
+			return name
+		}
+
+		if loc == languageCode {
+			if name := iso6391.NativeName(languageCode); name != "" {
+				return name
+			}
+		}
+	}
+
+	return iso6391.Name(languageCode)
+}
+
+// fallback returns the locales to look up for given locale in order.
+func (l *Localizer) fallback(locale string) []string {
+	locale = strings.ToLower(strings.ReplaceAll(locale, "_", "-"))
+	locales := make([]string, 0, 3)
+
+	if locale != "" {
+		locales = append(locales, locale)
+
+		if i := strings.IndexRune(locale, '-'); i > 0 {
+			locales = append(locales, locale[:i])
+		}
+	}
+
+	if locale != defaultLocale {
+		locales = append(locales, defaultLocale)
+	}
+
+	return locales
+}
+
+func (l *Localizer) addLocation(row map[string]string) error {
+	locale := strings.ToLower(row["locale_code"])
+	countryCode := strings.ToLower(row["country_iso_code"])
+
+	if countryCode != "" && row["country_name"] != "" {
+		if l.countries[locale] == nil {
+			l.countries[locale] = make(map[string]string)
+		}
+
+		l.countries[locale][countryCode] = row["country_name"]
+	}
+
+	if row["city_name"] != "" {
+		id, err := strconv.ParseUint(row["geoname_id"], 10, 32)
+
+		if err != nil {
+			return err
+		}
+
+		if l.cities[locale] == nil {
+			l.cities[locale] = make(map[uint32]string)
+		}
+
+		l.cities[locale][uint32(id)] = row["city_name"]
+	}
+
+	return nil
+}
+
+func (l *Localizer) addLanguage(row map[string]string) error {
+	locale := strings.ToLower(row["locale_code"])
+
+	if l.languages[locale] == nil {
+		l.languages[locale] = make(map[string]string)
+	}
+
+	l.languages[locale][strings.ToLower(row["language_code"])] = row["language_name"]
+	return nil
+}
+
+// readCSV reads given CSV file with a header row and calls add for each row with the required columns.
+func (l *Localizer) readCSV(file string, columns []string, add func(map[string]string) error) error {
+	f, err := os.Open(file)
+
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if err := f.Close(); err != nil {
+			logger.Printf("error closing localization file %s: %s", file, err)
+		}
+	}()
+	r := csv.NewReader(f)
+	r.FieldsPerRecord = -1
+	header, err := r.Read()
+
+	if err != nil {
+		return err
+	}
+
+	index := make(map[string]int, len(columns))
+
+	for i, column := range header {
+		index[strings.TrimSpace(strings.TrimPrefix(column, "\ufeff"))] = i
+	}
+
+	for _, column := range columns {
+		if _, found := index[column]; !found {
+			return fmt.Errorf("column %s missing in localization file %s", column, file)
+		}
+	}
+
+	for {
+		record, err := r.Read()
+
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		row := make(map[string]string, len(columns))
+
+		for _, column := range columns {
+			if i := index[column]; i < len(record) {
+				row[column] = strings.TrimSpace(record[i])
+			}
+		}
+
+		if err := add(row); err != nil {
+			return fmt.Errorf("error reading localization file %s: %s", file, err)
+		}
+	}
+}
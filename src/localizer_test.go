@@ -0,0 +1,49 @@
+package omisocial
+
+import (
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLocalizer(t *testing.T) {
+	dir := t.TempDir()
+	en := filepath.Join(dir, "GeoLite2-City-Locations-en.csv")
+	vi := filepath.Join(dir, "City-Locations-vi.csv")
+	languages := filepath.Join(dir, "languages.csv")
+	assert.NoError(t, os.WriteFile(en, []byte("\ufeffgeoname_id,locale_code,continent_code,continent_name,country_iso_code,country_name,subdivision_1_iso_code,subdivision_1_name,subdivision_2_iso_code,subdivision_2_name,city_name,metro_code,time_zone,is_in_european_union\n"+
+		"1566083,en,AS,Asia,VN,Vietnam,SG,\"Ho Chi Minh\",,,\"Ho Chi Minh City\",,Asia/Ho_Chi_Minh,0\n"+
+		"2643743,en,EU,Europe,GB,\"United Kingdom\",ENG,England,,,London,,Europe/London,0\n"+
+		"1562822,en,AS,Asia,VN,Vietnam,,,,,,,Asia/Bangkok,0\n"), 0644))
+	assert.NoError(t, os.WriteFile(vi, []byte("geoname_id,locale_code,country_iso_code,country_name,city_name\n"+
+		"1566083,vi,VN,Việt Nam,Thành phố Hồ Chí Minh\n"), 0644))
+	assert.NoError(t, os.WriteFile(languages, []byte("locale_code,language_code,language_name\n"+
+		"vi,en,Tiếng Anh\n"), 0644))
+	l, err := NewLocalizer(LocalizerConfig{
+		LocationFiles: []string{en, vi},
+		LanguageFiles: []string{languages},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "Việt Nam", l.CountryName("vn", "vi"))
+	assert.Equal(t, "Việt Nam", l.CountryName("VN", "vi-VN"))
+	assert.Equal(t, "Vietnam", l.CountryName("vn", "en"))
+	assert.Equal(t, "United Kingdom", l.CountryName("gb", "vi"))
+	assert.Empty(t, l.CountryName("de", "vi"))
+	assert.Equal(t, "Thành phố Hồ Chí Minh", l.CityName(1566083, "vi"))
+	assert.Equal(t, "Ho Chi Minh City", l.CityName(1566083, "de"))
+	assert.Equal(t, "London", l.CityName(2643743, "vi"))
+	assert.Empty(t, l.CityName(0, "vi"))
+	assert.Equal(t, "Tiếng Anh", l.LanguageName("en", "vi"))
+	assert.Equal(t, "Tiếng Việt", l.LanguageName("vi", "vi"))
+	assert.Equal(t, "Vietnamese", l.LanguageName("vi", "de"))
+	assert.Equal(t, "German", l.LanguageName("DE", ""))
+	assert.Empty(t, l.LanguageName("xx", "vi"))
+}
+
+func TestNewLocalizerMissingColumn(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "invalid.csv")
+	assert.NoError(t, os.WriteFile(file, []byte("geoname_id,city_name\n1,City\n"), 0644))
+	_, err := NewLocalizer(LocalizerConfig{LocationFiles: []string{file}})
+	assert.Error(t, err)
+}
@@ -33,5 +33,6 @@ func cleanupDB() {
 	dbClient.MustExec(`ALTER TABLE "session" DELETE WHERE 1=1`)
 	dbClient.MustExec(`ALTER TABLE "event" DELETE WHERE 1=1`)
 	dbClient.MustExec(`ALTER TABLE "user_agent" DELETE WHERE 1=1`)
+	dbClient.MustExec(`ALTER TABLE "saved_segment" DELETE WHERE 1=1`)
 	time.Sleep(time.Millisecond * 20)
 }
@@ -17,15 +17,30 @@ type PageView struct {
 	Language        string
 	CountryCode     string `db:"country_code"`
 	City            string
+	CityGeoNameID   uint32 `db:"city_geoname_id"`
+	Region          string
+	PostalCode      string `db:"postal_code"`
+	Latitude        float64
+	Longitude       float64
+	TimeZone        string `db:"time_zone"`
+	ASN             uint32 `db:"asn"`
+	ASOrganization  string `db:"as_organization"`
 	Referrer        string
 	ReferrerName    string `db:"referrer_name"`
 	ReferrerIcon    string `db:"referrer_icon"`
+	Channel         string `db:"channel"`
 	OS              string
 	OSVersion       string `db:"os_version"`
 	Browser         string
 	BrowserVersion  string `db:"browser_version"`
 	Desktop         bool
 	Mobile          bool
+	WebView         bool   `db:"webview"`
+	NewVisitor      bool   `db:"new_visitor"`
+	DeviceType      string `db:"device_type"`
+	DeviceVendor    string `db:"device_vendor"`
+	DeviceModel     string `db:"device_model"`
+	BotReason       string `db:"bot_reason"`
 	ScreenWidth     uint16 `db:"screen_width"`
 	ScreenHeight    uint16 `db:"screen_height"`
 	ScreenClass     string `db:"screen_class"`
@@ -38,6 +53,7 @@ type PageView struct {
 	OTMMedium       string `db:"otm_medium"`
 	OTMCampaign     string `db:"otm_campaign"`
 	OTMPosition     string `db:"otm_position"`
+	SearchTerm      string `db:"search_term"`
 }
 
 // String implements the Stringer interface.
@@ -64,15 +80,30 @@ type Session struct {
 	Language        string
 	CountryCode     string `db:"country_code"`
 	City            string
+	CityGeoNameID   uint32 `db:"city_geoname_id"`
+	Region          string
+	PostalCode      string `db:"postal_code"`
+	Latitude        float64
+	Longitude       float64
+	TimeZone        string `db:"time_zone"`
+	ASN             uint32 `db:"asn"`
+	ASOrganization  string `db:"as_organization"`
 	Referrer        string
 	ReferrerName    string `db:"referrer_name"`
 	ReferrerIcon    string `db:"referrer_icon"`
+	Channel         string `db:"channel"`
 	OS              string
 	OSVersion       string `db:"os_version"`
 	Browser         string
 	BrowserVersion  string `db:"browser_version"`
 	Desktop         bool
 	Mobile          bool
+	WebView         bool   `db:"webview"`
+	NewVisitor      bool   `db:"new_visitor"`
+	DeviceType      string `db:"device_type"`
+	DeviceVendor    string `db:"device_vendor"`
+	DeviceModel     string `db:"device_model"`
+	BotReason       string `db:"bot_reason"`
 	ScreenWidth     uint16 `db:"screen_width"`
 	ScreenHeight    uint16 `db:"screen_height"`
 	ScreenClass     string `db:"screen_class"`
@@ -109,15 +140,30 @@ type Event struct {
 	Language        string
 	CountryCode     string `db:"country_code"`
 	City            string
+	CityGeoNameID   uint32 `db:"city_geoname_id"`
+	Region          string
+	PostalCode      string `db:"postal_code"`
+	Latitude        float64
+	Longitude       float64
+	TimeZone        string `db:"time_zone"`
+	ASN             uint32 `db:"asn"`
+	ASOrganization  string `db:"as_organization"`
 	Referrer        string
 	ReferrerName    string `db:"referrer_name"`
 	ReferrerIcon    string `db:"referrer_icon"`
+	Channel         string `db:"channel"`
 	OS              string
 	OSVersion       string `db:"os_version"`
 	Browser         string
 	BrowserVersion  string `db:"browser_version"`
 	Desktop         bool
 	Mobile          bool
+	WebView         bool   `db:"webview"`
+	NewVisitor      bool   `db:"new_visitor"`
+	DeviceType      string `db:"device_type"`
+	DeviceVendor    string `db:"device_vendor"`
+	DeviceModel     string `db:"device_model"`
+	BotReason       string `db:"bot_reason"`
 	ScreenWidth     uint16 `db:"screen_width"`
 	ScreenHeight    uint16 `db:"screen_height"`
 	ScreenClass     string `db:"screen_class"`
@@ -130,6 +176,7 @@ type Event struct {
 	OTMMedium       string `db:"otm_medium"`
 	OTMCampaign     string `db:"otm_campaign"`
 	OTMPosition     string `db:"otm_position"`
+	SearchTerm      string `db:"search_term"`
 }
 
 // String implements the Stringer interface.
@@ -138,6 +185,42 @@ func (event Event) String() string {
 	return string(out)
 }
 
+// BotHit is a single request filtered as bot traffic.
+type BotHit struct {
+	ClientID    uint64 `db:"client_id"`
+	VisitorID   uint64 `db:"visitor_id"`
+	Time        time.Time
+	Reason      string `db:"bot_reason"`
+	UserAgent   string `db:"user_agent"`
+	CountryCode string `db:"country_code"`
+	Path        string
+}
+
+// String implements the Stringer interface.
+func (hit BotHit) String() string {
+	out, _ := json.Marshal(hit)
+	return string(out)
+}
+
+// AndroidApp is an Android app resolved from the Google Play Store for android-app:// referrers.
+type AndroidApp struct {
+	PackageName string `db:"package_name"`
+	Name        string
+	Icon        string
+	Time        time.Time
+}
+
+// SavedSegment is a named Filter saved for a client.
+// Updates and deletions are stored as new versions of the segment, the latest version (by Time) is used.
+type SavedSegment struct {
+	ClientID int64         `db:"client_id" json:"client_id"`
+	ID       uint64        `json:"id"`
+	Name     string        `json:"name"`
+	Filter   SegmentFilter `json:"filter"`
+	Deleted  bool          `json:"-"`
+	Time     time.Time     `json:"time"`
+}
+
 // ActiveVisitorStats is the result type for active visitor statistics.
 type ActiveVisitorStats struct {
 	Path     string `json:"path"`
@@ -156,8 +239,11 @@ type TotalVisitorStats struct {
 
 // VisitorPlatformStats is the result type for total visitor group by platform.
 type PlatformVisitorStats struct {
+	ComparisonStats
+	Other      bool    `db:"other" json:"other"`
 	Desktop    bool    `json:"desktop"`
 	Mobile     bool    `json:"mobile"`
+	DeviceType string  `db:"device_type" json:"device_type"`
 	Visitors   int     `json:"visitors"`
 	Views      int     `json:"views"`
 	Sessions   int     `json:"sessions"`
@@ -166,13 +252,27 @@ type PlatformVisitorStats struct {
 }
 
 // VisitorStats is the result type for visitor statistics.
+// Period is the start of the period (like the first day of the week) the statistics are grouped by.
 type VisitorStats struct {
-	Period     string  `db:"period" json:"period"`
-	Visitors   int     `json:"visitors"`
-	Views      int     `json:"views"`
-	Sessions   int     `json:"sessions"`
-	Bounces    int     `json:"bounces"`
-	BounceRate float64 `db:"bounce_rate" json:"bounce_rate"`
+	Period     time.Time `db:"period" json:"period"`
+	Visitors   int       `json:"visitors"`
+	Views      int       `json:"views"`
+	Sessions   int       `json:"sessions"`
+	Bounces    int       `json:"bounces"`
+	BounceRate float64   `db:"bounce_rate" json:"bounce_rate"`
+
+	// Previous is the entry at the same position of the comparison period in case Filter.Compare is set.
+	Previous *VisitorStats `db:"-" json:"previous,omitempty"`
+}
+
+// NewVisitorStats is the result type for new and returning visitor statistics.
+// Period is the start of the period (like the first day of the week) the statistics are grouped by.
+// Visitors returning within the same period are counted as both, new and returning visitors.
+type NewVisitorStats struct {
+	Period            time.Time `db:"period" json:"period"`
+	Visitors          int       `json:"visitors"`
+	NewVisitors       int       `db:"new_visitors" json:"new_visitors"`
+	ReturningVisitors int       `db:"returning_visitors" json:"returning_visitors"`
 }
 
 // Growth represents the visitors, views, sessions, bounces, and average session duration growth between two time periods.
@@ -190,8 +290,19 @@ type VisitorHourStats struct {
 	Visitors int `json:"visitors"`
 }
 
+// TrafficHeatmapStats is the result type for traffic statistics grouped by day of week and time of day.
+// Weekday is the day of week from 1 (Monday) to 7 (Sunday).
+type TrafficHeatmapStats struct {
+	Weekday  int `json:"weekday"`
+	Hour     int `json:"hour"`
+	Visitors int `json:"visitors"`
+	Sessions int `json:"sessions"`
+	Events   int `json:"events"`
+}
+
 // PageStats is the result type for page statistics.
 type PageStats struct {
+	ComparisonStats
 	Path                    string  `json:"path"`
 	Title                   string  `json:"title"`
 	Visitors                int     `json:"visitors"`
@@ -207,6 +318,7 @@ type PageStats struct {
 
 // EntryStats is the result type for entry page statistics.
 type EntryStats struct {
+	ComparisonStats
 	Path                    string  `db:"entry_path" json:"path"`
 	Title                   string  `json:"title"`
 	Visitors                int     `json:"visitors"`
@@ -218,6 +330,7 @@ type EntryStats struct {
 
 // ExitStats is the result type for exit page statistics.
 type ExitStats struct {
+	ComparisonStats
 	Path     string  `db:"exit_path" json:"path"`
 	Title    string  `json:"title"`
 	Visitors int     `json:"visitors"`
@@ -226,6 +339,54 @@ type ExitStats struct {
 	ExitRate float64 `db:"exit_rate" json:"exit_rate"`
 }
 
+// SearchTermStats is the result type for site search term statistics.
+// Exits is the number of searches after which the visitor left the site.
+type SearchTermStats struct {
+	SearchTerm string  `db:"search_term" json:"search_term"`
+	Searches   int     `json:"searches"`
+	Visitors   int     `json:"visitors"`
+	Sessions   int     `json:"sessions"`
+	Exits      int     `json:"exits"`
+	ExitRate   float64 `db:"exit_rate" json:"exit_rate"`
+}
+
+// SiteSearchStats is the result type for site search statistics.
+// Sessions is the number of sessions that used the site search.
+type SiteSearchStats struct {
+	Searches           int     `json:"searches"`
+	Sessions           int     `json:"sessions"`
+	SearchesPerSession float64 `db:"searches_per_session" json:"searches_per_session"`
+	Exits              int     `json:"exits"`
+	ExitRate           float64 `db:"exit_rate" json:"exit_rate"`
+}
+
+// AttributionStats is the result type for attribution statistics.
+// Value is the channel, referrer, or campaign parameter the (fractional) conversions are credited to.
+type AttributionStats struct {
+	Value               string  `json:"value"`
+	Conversions         float64 `json:"conversions"`
+	RelativeConversions float64 `json:"relative_conversions"`
+}
+
+// JourneyStats is the result type for the next and previous pages of a path.
+// An empty Path is the exit of the session for the next pages and the entry for the previous pages.
+// RelativeSessions is the share of sessions that viewed the path.
+type JourneyStats struct {
+	Path             string  `json:"path"`
+	Visitors         int     `json:"visitors"`
+	Sessions         int     `json:"sessions"`
+	RelativeSessions float64 `db:"relative_sessions" json:"relative_sessions"`
+}
+
+// PathFlowStats is the result type for the top sequences of pages viewed within a session.
+// RelativeSessions is the share of all sessions (that viewed the starting path).
+type PathFlowStats struct {
+	Paths            []string `json:"paths"`
+	Visitors         int      `json:"visitors"`
+	Sessions         int      `json:"sessions"`
+	RelativeSessions float64  `db:"relative_sessions" json:"relative_sessions"`
+}
+
 // PageConversionsStats is the result type for page conversions.
 type PageConversionsStats struct {
 	Visitors int `json:"visitors"`
@@ -234,6 +395,7 @@ type PageConversionsStats struct {
 
 // EventStats is the result type for custom events.
 type EventStats struct {
+	ComparisonStats
 	Name                   string   `db:"event_name" json:"name"`
 	Visitors               int      `json:"visitors"`
 	Views                  int      `json:"views"`
@@ -243,18 +405,21 @@ type EventStats struct {
 	MetaValue              string   `db:"meta_value" json:"meta_value"`
 }
 
-// GroupEventStats is the result type for custom events.
+// GroupEventStats is the result type for custom events grouped by period.
+// Period is the start of the period (like the first day of the week) the events are grouped by.
 type GroupEventStats struct {
-	Period    string `db:"period" json:"period"`
-	Name      string `db:"event_name" json:"name"`
-	Visitors  int    `json:"visitors"`
-	Views     int    `json:"views"`
-	Sessions  int    `json:"sessions"`
-	MetaValue string `db:"meta_value" json:"meta_value"`
+	Period    time.Time `db:"period" json:"period"`
+	Name      string    `db:"event_name" json:"name"`
+	Visitors  int       `json:"visitors"`
+	Views     int       `json:"views"`
+	Sessions  int       `json:"sessions"`
+	MetaValue string    `db:"meta_value" json:"meta_value"`
 }
 
 // ReferrerStats is the result type for referrer statistics.
 type ReferrerStats struct {
+	ComparisonStats
+	Other            bool    `db:"other" json:"other"`
 	Referrer         string  `json:"referrer"`
 	ReferrerName     string  `db:"referrer_name" json:"referrer_name"`
 	ReferrerIcon     string  `db:"referrer_icon" json:"referrer_icon"`
@@ -265,6 +430,12 @@ type ReferrerStats struct {
 	BounceRate       float64 `db:"bounce_rate" json:"bounce_rate"`
 }
 
+// ChannelStats is the result type for traffic channel statistics.
+type ChannelStats struct {
+	MetaStats
+	Channel string `db:"channel" json:"channel"`
+}
+
 // PlatformStats is the result type for platform statistics.
 type PlatformStats struct {
 	PlatformDesktop         int     `db:"platform_desktop" json:"platform_desktop"`
@@ -273,18 +444,43 @@ type PlatformStats struct {
 	RelativePlatformDesktop float64 `db:"relative_platform_desktop" json:"relative_platform_desktop"`
 	RelativePlatformMobile  float64 `db:"relative_platform_mobile" json:"relative_platform_mobile"`
 	RelativePlatformUnknown float64 `db:"relative_platform_unknown" json:"relative_platform_unknown"`
+	DeviceDesktop           int     `db:"device_desktop" json:"device_desktop"`
+	DeviceMobile            int     `db:"device_mobile" json:"device_mobile"`
+	DeviceTablet            int     `db:"device_tablet" json:"device_tablet"`
+	DeviceTV                int     `db:"device_tv" json:"device_tv"`
+	DeviceConsole           int     `db:"device_console" json:"device_console"`
+	DeviceBot               int     `db:"device_bot" json:"device_bot"`
+	DeviceUnknown           int     `db:"device_unknown" json:"device_unknown"`
+	RelativeDeviceDesktop   float64 `db:"relative_device_desktop" json:"relative_device_desktop"`
+	RelativeDeviceMobile    float64 `db:"relative_device_mobile" json:"relative_device_mobile"`
+	RelativeDeviceTablet    float64 `db:"relative_device_tablet" json:"relative_device_tablet"`
+	RelativeDeviceTV        float64 `db:"relative_device_tv" json:"relative_device_tv"`
+	RelativeDeviceConsole   float64 `db:"relative_device_console" json:"relative_device_console"`
+	RelativeDeviceBot       float64 `db:"relative_device_bot" json:"relative_device_bot"`
+	RelativeDeviceUnknown   float64 `db:"relative_device_unknown" json:"relative_device_unknown"`
 }
 
 // TimeSpentStats is the result type for average time spent statistics (sessions, time on page).
+// Period is the start of the period (like the first day of the week) the statistics are grouped by.
 type TimeSpentStats struct {
-	Day                     time.Time `json:"day"`
+	Period                  time.Time `db:"period" json:"period"`
 	Path                    string    `json:"path"`
 	Title                   string    `json:"title"`
 	AverageTimeSpentSeconds int       `db:"average_time_spent_seconds" json:"average_time_spent_seconds"`
 }
 
+// ComparisonStats is the comparison of the visitor count to the period set by Filter.Compare.
+// The fields are left empty in case no comparison was requested.
+type ComparisonStats struct {
+	PreviousVisitors int     `db:"previous_visitors" json:"previous_visitors,omitempty"`
+	VisitorsDelta    int     `db:"visitors_delta" json:"visitors_delta,omitempty"`
+	VisitorsGrowth   float64 `db:"visitors_growth" json:"visitors_growth,omitempty"`
+}
+
 // MetaStats is the base for meta result types (languages, countries, ...).
 type MetaStats struct {
+	ComparisonStats
+	Other            bool    `db:"other" json:"other"`
 	Visitors         int     `json:"visitors"`
 	RelativeVisitors float64 `db:"relative_visitors" json:"relative_visitors"`
 }
@@ -292,19 +488,64 @@ type MetaStats struct {
 // LanguageStats is the result type for language statistics.
 type LanguageStats struct {
 	MetaStats
-	Language string `json:"language"`
+	Language     string `json:"language"`
+	LanguageName string `db:"-" json:"language_name,omitempty"`
 }
 
 // CountryStats is the result type for country statistics.
 type CountryStats struct {
 	MetaStats
 	CountryCode string `db:"country_code" json:"country_code"`
+	CountryName string `db:"-" json:"country_name,omitempty"`
 }
 
 // CityStats is the result type for city statistics.
 type CityStats struct {
 	MetaStats
-	City string `json:"city"`
+	City      string `json:"city"`
+	GeoNameID uint32 `db:"city_geoname_id" json:"geoname_id"`
+	CityName  string `db:"-" json:"city_name,omitempty"`
+}
+
+// RegionStats is the result type for region statistics.
+type RegionStats struct {
+	MetaStats
+	CountryCode string `db:"country_code" json:"country_code"`
+	CountryName string `db:"-" json:"country_name,omitempty"`
+	Region      string `json:"region"`
+}
+
+// PostalCodeStats is the result type for postal code statistics.
+type PostalCodeStats struct {
+	MetaStats
+	CountryCode string `db:"country_code" json:"country_code"`
+	CountryName string `db:"-" json:"country_name,omitempty"`
+	PostalCode  string `db:"postal_code" json:"postal_code"`
+}
+
+// LocationStats is the result type for city statistics including the coordinates.
+type LocationStats struct {
+	MetaStats
+	CountryCode string  `db:"country_code" json:"country_code"`
+	CountryName string  `db:"-" json:"country_name,omitempty"`
+	City        string  `json:"city"`
+	GeoNameID   uint32  `db:"city_geoname_id" json:"geoname_id"`
+	CityName    string  `db:"-" json:"city_name,omitempty"`
+	Latitude    float64 `json:"latitude"`
+	Longitude   float64 `json:"longitude"`
+}
+
+// TimeZoneStats is the result type for time zone statistics.
+type TimeZoneStats struct {
+	MetaStats
+	TimeZone string `db:"time_zone" json:"time_zone"`
+}
+
+// ASNStats is the result type for autonomous system statistics.
+type ASNStats struct {
+	MetaStats
+	ASN            uint32 `db:"asn" json:"asn"`
+	ASOrganization string `db:"as_organization" json:"as_organization"`
 }
 
 // BrowserStats is the result type for browser statistics.
@@ -333,6 +574,47 @@ type OSVersionStats struct {
 	OSVersion string `db:"os_version" json:"os_version"`
 }
 
+// DeviceStats is the result type for device vendor and model statistics.
+type DeviceStats struct {
+	MetaStats
+	DeviceVendor string `db:"device_vendor" json:"device_vendor"`
+	DeviceModel  string `db:"device_model" json:"device_model"`
+}
+
+// BotStats is the result type for bot traffic statistics.
+type BotStats struct {
+	Reason      string `db:"bot_reason" json:"bot_reason"`
+	UserAgent   string `db:"user_agent" json:"user_agent"`
+	CountryCode string `db:"country_code" json:"country_code"`
+	Hits        int    `json:"hits"`
+	Visitors    int    `json:"visitors"`
+}
+
+// UserAgentSessionStats is the result type for the number of sessions per User-Agent.
+type UserAgentSessionStats struct {
+	UserAgent         string `db:"user_agent" json:"user_agent"`
+	Sessions          int    `json:"sessions"`
+	Visitors          int    `json:"visitors"`
+	MultiPageSessions int    `db:"multi_page_sessions" json:"multi_page_sessions"`
+}
+
+// BotCandidate is a User-Agent proposed to be added to the bot blacklist.
+type BotCandidate struct {
+	UserAgentSessionStats
+
+	// Reasons are the suspicious patterns found for the User-Agent.
+	Reasons []string `json:"reasons"`
+
+	// Keyword is the proposed blacklist entry.
+	Keyword string `json:"keyword"`
+}
+
+// WebViewStats is the result type for in-app browser and webview statistics.
+type WebViewStats struct {
+	MetaStats
+	WebView bool `db:"webview" json:"webview"`
+}
+
 // ScreenClassStats is the result type for screen class statistics.
 type ScreenClassStats struct {
 	MetaStats
@@ -374,3 +656,56 @@ type OTMSourceStats struct {
 	MetaStats
 	OTMSource string `db:"otm_source" json:"otm_source"`
 }
+
+// QueryStats is the result type for custom queries (see Analyzer.Query).
+// Only the selected dimensions and metrics are set, use Columns to get them by name.
+type QueryStats struct {
+	ComparisonStats
+	Other                   bool      `db:"other" json:"other"`
+	Path                    string    `db:"path" json:"path"`
+	EntryPath               string    `db:"entry_path" json:"entry_path"`
+	ExitPath                string    `db:"exit_path" json:"exit_path"`
+	Referrer                string    `db:"referrer" json:"referrer"`
+	ReferrerName            string    `db:"referrer_name" json:"referrer_name"`
+	Channel                 string    `db:"channel" json:"channel"`
+	Language                string    `db:"language" json:"language"`
+	CountryCode             string    `db:"country_code" json:"country_code"`
+	Region                  string    `db:"region" json:"region"`
+	City                    string    `db:"city" json:"city"`
+	PostalCode              string    `db:"postal_code" json:"postal_code"`
+	TimeZone                string    `db:"time_zone" json:"time_zone"`
+	ASN                     uint32    `db:"asn" json:"asn"`
+	ASOrganization          string    `db:"as_organization" json:"as_organization"`
+	Browser                 string    `db:"browser" json:"browser"`
+	BrowserVersion          string    `db:"browser_version" json:"browser_version"`
+	OS                      string    `db:"os" json:"os"`
+	OSVersion               string    `db:"os_version" json:"os_version"`
+	DeviceType              string    `db:"device_type" json:"device_type"`
+	DeviceVendor            string    `db:"device_vendor" json:"device_vendor"`
+	DeviceModel             string    `db:"device_model" json:"device_model"`
+	ScreenClass             string    `db:"screen_class" json:"screen_class"`
+	WebView                 bool      `db:"webview" json:"webview"`
+	NewVisitor              bool      `db:"new_visitor" json:"new_visitor"`
+	UTMSource               string    `db:"utm_source" json:"utm_source"`
+	UTMMedium               string    `db:"utm_medium" json:"utm_medium"`
+	UTMCampaign             string    `db:"utm_campaign" json:"utm_campaign"`
+	UTMContent              string    `db:"utm_content" json:"utm_content"`
+	UTMTerm                 string    `db:"utm_term" json:"utm_term"`
+	OTMSource               string    `db:"otm_source" json:"otm_source"`
+	Hour                    int       `db:"hour" json:"hour"`
+	Weekday                 int       `db:"weekday" json:"weekday"`
+	Day                     time.Time `db:"day" json:"day"`
+	Week                    time.Time `db:"week" json:"week"`
+	Month                   time.Time `db:"month" json:"month"`
+	Quarter                 time.Time `db:"quarter" json:"quarter"`
+	Year                    time.Time `db:"year" json:"year"`
+	Visitors                int       `db:"visitors" json:"visitors"`
+	Sessions                int       `db:"sessions" json:"sessions"`
+	Views                   int       `db:"views" json:"views"`
+	Bounces                 int       `db:"bounces" json:"bounces"`
+	BounceRate              float64   `db:"bounce_rate" json:"bounce_rate"`
+	RelativeVisitors        float64   `db:"relative_visitors" json:"relative_visitors"`
+	RelativeViews           float64   `db:"relative_views" json:"relative_views"`
+	CR                      float64   `db:"cr" json:"cr"`
+	AverageTimeSpentSeconds int       `db:"average_time_spent_seconds" json:"average_time_spent_seconds"`
+}
@@ -5,7 +5,39 @@ import (
 	"strings"
 )
 
+const (
+	// maxMinutePeriodDays is the maximum number of days time series are grouped by minute for.
+	maxMinutePeriodDays = 7
+
+	// maxHourPeriodDays is the maximum number of days time series are grouped by hour for.
+	maxHourPeriodDays = 366
+)
+
 var (
+	// otherSumFields are the fields summed up for the row aggregating the results past the limit.
+	otherSumFields = []string{
+		"visitors",
+		"sessions",
+		"views",
+		"bounces",
+		"entries",
+		"exits",
+		"relative_visitors",
+		"relative_views",
+		"cr",
+	}
+
+	// otherPageFields are the page fields results cannot be aggregated for past the limit.
+	// Visitors view more than one page, so that summing them up would count them more than once.
+	otherPageFields = []string{
+		"path",
+		"title",
+		"entry_path",
+		"entry_title",
+		"exit_path",
+		"exit_title",
+	}
+
 	fieldPath = field{
 		querySessions:  "path",
 		queryPageViews: "path",
@@ -62,6 +94,12 @@ var (
 		queryDirection: "DESC",
 		name:           "sessions",
 	}
+	fieldEvents = field{
+		querySessions:  "count(1)",
+		queryPageViews: "count(1)",
+		queryDirection: "DESC",
+		name:           "events",
+	}
 	fieldViews = field{
 		querySessions:  "sum(page_views*sign)",
 		queryPageViews: "count(1)",
@@ -105,6 +143,12 @@ var (
 		queryDirection: "ASC",
 		name:           "referrer_name",
 	}
+	fieldChannel = field{
+		querySessions:  "channel",
+		queryPageViews: "channel",
+		queryDirection: "ASC",
+		name:           "channel",
+	}
 	fieldReferrerIcon = field{
 		querySessions:  "any(referrer_icon)",
 		queryPageViews: "any(referrer_icon)",
@@ -129,6 +173,54 @@ var (
 		queryDirection: "ASC",
 		name:           "city",
 	}
+	fieldCityGeoNameID = field{
+		querySessions:  "any(city_geoname_id)",
+		queryPageViews: "any(city_geoname_id)",
+		queryDirection: "ASC",
+		name:           "city_geoname_id",
+	}
+	fieldRegion = field{
+		querySessions:  "region",
+		queryPageViews: "region",
+		queryDirection: "ASC",
+		name:           "region",
+	}
+	fieldPostalCode = field{
+		querySessions:  "postal_code",
+		queryPageViews: "postal_code",
+		queryDirection: "ASC",
+		name:           "postal_code",
+	}
+	fieldLatitude = field{
+		querySessions:  "any(latitude)",
+		queryPageViews: "any(latitude)",
+		queryDirection: "ASC",
+		name:           "latitude",
+	}
+	fieldLongitude = field{
+		querySessions:  "any(longitude)",
+		queryPageViews: "any(longitude)",
+		queryDirection: "ASC",
+		name:           "longitude",
+	}
+	fieldTimeZone = field{
+		querySessions:  "time_zone",
+		queryPageViews: "time_zone",
+		queryDirection: "ASC",
+		name:           "time_zone",
+	}
+	fieldASN = field{
+		querySessions:  "asn",
+		queryPageViews: "asn",
+		queryDirection: "ASC",
+		name:           "asn",
+	}
+	fieldASOrganization = field{
+		querySessions:  "as_organization",
+		queryPageViews: "as_organization",
+		queryDirection: "ASC",
+		name:           "as_organization",
+	}
 	fieldBrowser = field{
 		querySessions:  "browser",
 		queryPageViews: "browser",
@@ -139,7 +231,7 @@ var (
 		querySessions:  "browser_version",
 		queryPageViews: "browser_version",
 		queryDirection: "ASC",
-		name:           "browser_verfieldDaysion",
+		name:           "browser_version",
 	}
 	fieldOS = field{
 		querySessions:  "os",
@@ -221,6 +313,13 @@ var (
 		timezone:       true,
 		name:           "hour",
 	}
+	fieldWeekday = field{
+		querySessions:  "toDayOfWeek(time, '%s')",
+		queryPageViews: "toDayOfWeek(time, '%s')",
+		queryDirection: "ASC",
+		timezone:       true,
+		name:           "weekday",
+	}
 	fieldDay = field{
 		querySessions:  "toDate(time, '%s')",
 		queryPageViews: "toDate(time, '%s')",
@@ -229,19 +328,51 @@ var (
 		timezone:       true,
 		name:           "period",
 	}
+	fieldMinute = field{
+		querySessions:  "toStartOfMinute(time, '%s')",
+		queryPageViews: "toStartOfMinute(time, '%s')",
+		queryDirection: "ASC",
+		queryWithFill:  "WITH FILL FROM toDateTime(toDate(?), '%s') TO toDateTime(toDate(?)+1, '%s') STEP 60",
+		timezone:       true,
+		name:           "period",
+	}
+	fieldHourPeriod = field{
+		querySessions:  "toStartOfHour(time, '%s')",
+		queryPageViews: "toStartOfHour(time, '%s')",
+		queryDirection: "ASC",
+		queryWithFill:  "WITH FILL FROM toDateTime(toDate(?), '%s') TO toDateTime(toDate(?)+1, '%s') STEP 3600",
+		timezone:       true,
+		name:           "period",
+	}
 	fieldWeek = field{
-		querySessions:  "toWeek(time, 9, '%s')",
-		queryPageViews: "toWeek(time, 9, '%s')",
+		querySessions:  "toMonday(time, '%s')",
+		queryPageViews: "toMonday(time, '%s')",
 		queryDirection: "ASC",
-		queryWithFill:  "WITH FILL FROM toWeek(toDate(?), 9, '%s') TO toWeek(toDate(?), 9, '%s')",
+		queryWithFill:  "WITH FILL FROM toMonday(toDate(?)) TO toDate(?)+1 STEP 7",
 		timezone:       true,
 		name:           "period",
 	}
 	fieldMonth = field{
-		querySessions:  "toMonth(time, '%s')",
-		queryPageViews: "toMonth(time, '%s')",
+		querySessions:  "toStartOfMonth(time, '%s')",
+		queryPageViews: "toStartOfMonth(time, '%s')",
+		queryDirection: "ASC",
+		queryWithFill:  "WITH FILL FROM toStartOfMonth(toDate(?)) TO toDate(?)+1 STEP INTERVAL 1 MONTH",
+		timezone:       true,
+		name:           "period",
+	}
+	fieldQuarter = field{
+		querySessions:  "toStartOfQuarter(time, '%s')",
+		queryPageViews: "toStartOfQuarter(time, '%s')",
+		queryDirection: "ASC",
+		queryWithFill:  "WITH FILL FROM toStartOfQuarter(toDate(?)) TO toDate(?)+1 STEP INTERVAL 3 MONTH",
+		timezone:       true,
+		name:           "period",
+	}
+	fieldYear = field{
+		querySessions:  "toStartOfYear(time, '%s')",
+		queryPageViews: "toStartOfYear(time, '%s')",
 		queryDirection: "ASC",
-		queryWithFill:  "WITH FILL FROM toMonth(toDate(?), '%s') TO toMonth(toDate(?), '%s')",
+		queryWithFill:  "WITH FILL FROM toStartOfYear(toDate(?)) TO toDate(?)+1 STEP INTERVAL 1 YEAR",
 		timezone:       true,
 		name:           "period",
 	}
@@ -262,6 +393,48 @@ var (
 		queryDirection: "DESC",
 		name:           "mobile",
 	}
+	fieldDeviceType = field{
+		querySessions:  "device_type",
+		queryPageViews: "device_type",
+		queryDirection: "ASC",
+		name:           "device_type",
+	}
+	fieldDeviceVendor = field{
+		querySessions:  "device_vendor",
+		queryPageViews: "device_vendor",
+		queryDirection: "ASC",
+		name:           "device_vendor",
+	}
+	fieldDeviceModel = field{
+		querySessions:  "device_model",
+		queryPageViews: "device_model",
+		queryDirection: "ASC",
+		name:           "device_model",
+	}
+	fieldWebView = field{
+		querySessions:  "webview",
+		queryPageViews: "webview",
+		queryDirection: "DESC",
+		name:           "webview",
+	}
+	fieldNewVisitor = field{
+		querySessions:  "new_visitor",
+		queryPageViews: "new_visitor",
+		queryDirection: "DESC",
+		name:           "new_visitor",
+	}
+	fieldNewVisitors = field{
+		querySessions:  "uniqIf(visitor_id, new_visitor = 1)",
+		queryPageViews: "uniqIf(visitor_id, new_visitor = 1)",
+		queryDirection: "DESC",
+		name:           "new_visitors",
+	}
+	fieldReturningVisitors = field{
+		querySessions:  "uniqIf(visitor_id, new_visitor = 0)",
+		queryPageViews: "uniqIf(visitor_id, new_visitor = 0)",
+		queryDirection: "DESC",
+		name:           "returning_visitors",
+	}
 )
 
 type field struct {
@@ -276,11 +449,19 @@ type field struct {
 }
 
 func buildQuery(filter *Filter, fields, groupBy, orderBy []field) ([]interface{}, string) {
+	if previous := filter.comparisonFilter(); previous != nil && canCompare(fields, groupBy) {
+		return buildComparisonQuery(filter, previous, fields, groupBy, orderBy)
+	}
+
+	if filter.Other && filter.Limit > 0 && filter.Compare == "" && canAggregateOther(groupBy) {
+		return buildOtherQuery(filter, fields, groupBy, orderBy)
+	}
+
 	table := filter.table()
 	args := make([]interface{}, 0)
 	var query strings.Builder
 
-	if table == "event" || filter.Path != "" || filter.PathPattern != "" || fieldsContain(fields, fieldPath.name) {
+	if table == "event" || filter.pageFilter() || fieldsContain(fields, fieldPath.name) {
 		if table == "session" {
 			table = "page_view"
 		}
@@ -293,10 +474,10 @@ func buildQuery(filter *Filter, fields, groupBy, orderBy []field) ([]interface{}
 			fieldsContain(fields, fieldViews.name) ||
 			fieldsContain(fields, fieldEntryPath.name) ||
 			fieldsContain(fields, fieldExitPath.name) {
-			path, pathPattern, eventName := filter.Path, filter.PathPattern, filter.EventName
-			filter.Path, filter.PathPattern, filter.EventName = "", "", ""
+			path, pathPattern, eventName, conditions := filter.Path, filter.PathPattern, filter.EventName, filter.Conditions
+			filter.Path, filter.PathPattern, filter.EventName, filter.Conditions = "", "", "", filter.sessionConditions()
 			filterArgs, filterQuery := filter.query()
-			filter.Path, filter.PathPattern, filter.EventName = path, pathPattern, eventName
+			filter.Path, filter.PathPattern, filter.EventName, filter.Conditions = path, pathPattern, eventName, conditions
 			args = append(args, filterArgs...)
 
 			if table == "page_view" {
@@ -344,7 +525,7 @@ func buildQuery(filter *Filter, fields, groupBy, orderBy []field) ([]interface{}
 				filterArgs, filterQuery = filter.query()
 				args = append(args, filterArgs...)
 				query.WriteString(fmt.Sprintf(`WHERE %s `, filterQuery))
-			} else if filter.Path != "" || filter.PathPattern != "" {
+			} else if filter.pageFilter() {
 				filterArgs, filterQuery = filter.queryPageOrEvent()
 				args = append(args, filterArgs...)
 				query.WriteString(fmt.Sprintf(`WHERE %s `, filterQuery))
@@ -385,6 +566,119 @@ func buildQuery(filter *Filter, fields, groupBy, orderBy []field) ([]interface{}
 	return args, query.String()
 }
 
+// buildComparisonQuery joins the results for the selected period with the visitor count for the comparison period on the groupBy fields.
+// Rows that don't exist in the comparison period have zero previous visitors.
+func buildComparisonQuery(filter, previous *Filter, fields, groupBy, orderBy []field) ([]interface{}, string) {
+	current := *filter
+	current.Compare = ""
+	current.Other = false
+	args, currentQuery := buildQuery(&current, fields, groupBy, orderBy)
+	previousArgs, previousQuery := buildQuery(previous, fields, groupBy, nil)
+	args = append(args, previousArgs...)
+	var query strings.Builder
+	query.WriteString("SELECT ")
+
+	for i := range fields {
+		query.WriteString(fmt.Sprintf(`c.%s %s, `, fields[i].name, fields[i].name))
+	}
+
+	query.WriteString(fmt.Sprintf(`p.visitors previous_visitors,
+		toInt64(c.visitors) - toInt64(p.visitors) visitors_delta,
+		if(p.visitors = 0, if(c.visitors = 0, 0, 1), visitors_delta / p.visitors) visitors_growth
+		FROM (%s) c
+		LEFT JOIN (%s) p
+		ON `, currentQuery, previousQuery))
+
+	for i := range groupBy {
+		if i > 0 {
+			query.WriteString("AND ")
+		}
+
+		query.WriteString(fmt.Sprintf(`c.%s = p.%s `, groupBy[i].name, groupBy[i].name))
+	}
+
+	if len(orderBy) > 0 {
+		query.WriteString(fmt.Sprintf(`ORDER BY %s `, joinOrderBy(&args, filter, orderBy)))
+	}
+
+	return args, query.String()
+}
+
+// canCompare returns whether the results can be compared row by row to the comparison period.
+// This is the case for breakdowns of the visitor count, but not for time series, which are filled for the selected period.
+func canCompare(fields, groupBy []field) bool {
+	if len(groupBy) == 0 || !fieldsContain(fields, fieldVisitors.name) {
+		return false
+	}
+
+	for i := range groupBy {
+		if groupBy[i].withFill || groupBy[i].queryWithFill != "" {
+			return false
+		}
+	}
+
+	return true
+}
+
+// buildOtherQuery returns the results for the filter limit, followed by a single row aggregating all results past it.
+// The row is left out if there are no more results.
+func buildOtherQuery(filter *Filter, fields, groupBy, orderBy []field) ([]interface{}, string) {
+	top := *filter
+	top.Other = false
+	args, topQuery := buildQuery(&top, fields, groupBy, orderBy)
+	rest := top
+	rest.Limit = 0
+	rest.Offset = filter.Offset + filter.Limit
+	restArgs, restQuery := buildQuery(&rest, fields, groupBy, orderBy)
+	args = append(args, restArgs...)
+	names := make([]string, 0, len(fields))
+	otherFields := make([]string, 0, len(fields))
+
+	for i := range fields {
+		names = append(names, fields[i].name)
+
+		if fields[i].name == fieldBounceRate.name {
+			otherFields = append(otherFields, fmt.Sprintf(`%s %s`, fieldBounceRate.querySessions, fieldBounceRate.name))
+		} else if containsString(otherSumFields, fields[i].name) {
+			otherFields = append(otherFields, fmt.Sprintf(`sum(%s) %s`, fields[i].name, fields[i].name))
+		} else {
+			otherFields = append(otherFields, fmt.Sprintf(`defaultValueOfArgumentType(any(%s)) %s`, fields[i].name, fields[i].name))
+		}
+	}
+
+	var query strings.Builder
+	query.WriteString(fmt.Sprintf(`SELECT %s, other FROM (
+		SELECT *, 0 other FROM (%s)
+		UNION ALL
+		SELECT %s, 1 other FROM (%s) HAVING count() > 0
+	) `, strings.Join(names, ","), topQuery, strings.Join(otherFields, ","), restQuery))
+
+	if len(orderBy) > 0 {
+		query.WriteString(fmt.Sprintf(`ORDER BY other, %s `, joinOrderBy(&args, filter, orderBy)))
+	} else {
+		query.WriteString(`ORDER BY other `)
+	}
+
+	return args, query.String()
+}
+
+// canAggregateOther returns whether the results past the limit can be aggregated into a single row.
+// This is the case for breakdowns, but not for time series, which are filled for the selected period,
+// and pages, as the visitors can only be summed up for dimensions with a single value per session.
+func canAggregateOther(groupBy []field) bool {
+	if len(groupBy) == 0 {
+		return false
+	}
+
+	for i := range groupBy {
+		if groupBy[i].withFill || groupBy[i].queryWithFill != "" || containsString(otherPageFields, groupBy[i].name) {
+			return false
+		}
+	}
+
+	return true
+}
+
 func joinPageViewFields(args *[]interface{}, filter *Filter, fields []field) string {
 	if len(fields) == 0 {
 		return ""
@@ -454,16 +748,9 @@ func joinOrderBy(args *[]interface{}, filter *Filter, fields []field) string {
 	var out strings.Builder
 
 	for i := range fields {
-		if fields[i].queryWithFill != "" {
-			queryFill := fields[i].queryWithFill
-			if fields[i].timezone {
-				queryFill = fmt.Sprintf(fields[i].queryWithFill, filter.Timezone.String(), filter.Timezone.String())
-			}
-			fillArgs := []interface{}{filter.From, filter.To}
-			*args = append(*args, fillArgs...)
-			out.WriteString(fmt.Sprintf(`%s %s %s,`, fields[i].name, fields[i].queryDirection, queryFill))
-		} else if fields[i].withFill {
-			fillArgs, fillQuery := filter.withFill()
+		fillArgs, fillQuery := fieldWithFill(filter, fields[i])
+
+		if fillQuery != "" {
 			*args = append(*args, fillArgs...)
 			out.WriteString(fmt.Sprintf(`%s %s %s,`, fields[i].name, fields[i].queryDirection, fillQuery))
 		} else {
@@ -475,6 +762,55 @@ func joinOrderBy(args *[]interface{}, filter *Filter, fields []field) string {
 	return str[:len(str)-1]
 }
 
+// fieldWithFill returns the WITH FILL clause for given field, or an empty string if the field isn't filled or no period is set.
+func fieldWithFill(filter *Filter, f field) ([]interface{}, string) {
+	if f.withFill {
+		return filter.withFill()
+	}
+
+	if f.queryWithFill == "" || filter.From.IsZero() || filter.To.IsZero() {
+		return nil, ""
+	}
+
+	queryFill := f.queryWithFill
+
+	if f.timezone {
+		queryFill = strings.ReplaceAll(queryFill, "%s", filter.Timezone.String())
+	}
+
+	return []interface{}{filter.From, filter.To}, queryFill
+}
+
+// periodField returns the field to group time series for given filter by given period (like PeriodWeek).
+// Unknown periods are grouped by day. As the time series is filled for the whole filter range,
+// minutes and hours fall back to the next longer period for ranges longer than maxMinutePeriodDays and maxHourPeriodDays.
+func periodField(filter *Filter, period string) field {
+	switch period {
+	case PeriodMinute:
+		if filter.days() <= maxMinutePeriodDays {
+			return fieldMinute
+		}
+
+		return periodField(filter, PeriodHour)
+	case PeriodHour:
+		if filter.days() <= maxHourPeriodDays {
+			return fieldHourPeriod
+		}
+
+		return fieldDay
+	case PeriodWeek:
+		return fieldWeek
+	case PeriodMonth:
+		return fieldMonth
+	case PeriodQuarter:
+		return fieldQuarter
+	case PeriodYear:
+		return fieldYear
+	default:
+		return fieldDay
+	}
+}
+
 func fieldsContain(haystack []field, needle string) bool {
 	for i := range haystack {
 		if haystack[i].name == needle {
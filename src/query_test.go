@@ -99,3 +99,206 @@ func TestBuildQuery(t *testing.T) {
 	assert.InDelta(t, 0.5, vstats.RelativeVisitors, 0.01)
 	assert.InDelta(t, 0.3333, vstats.RelativeViews, 0.01)
 }
+
+func TestBuildQueryCompare(t *testing.T) {
+	cleanupDB()
+	assert.NoError(t, dbClient.SavePageViews([]PageView{
+		{VisitorID: 1, Time: pastDay(1), Path: "/"},
+		{VisitorID: 2, Time: pastDay(1), Path: "/"},
+		{VisitorID: 3, Time: pastDay(1), Path: "/foo"},
+		{VisitorID: 4, Time: Today(), Path: "/"},
+		{VisitorID: 5, Time: Today(), Path: "/bar"},
+		{VisitorID: 6, Time: Today(), Path: "/bar"},
+	}))
+	analyzer := NewAnalyzer(dbClient)
+	filter := analyzer.getFilter(&Filter{Day: Today(), Compare: ComparePrevious, Limit: 1})
+	args, query := buildQuery(filter, []field{fieldPath, fieldVisitors}, []field{fieldPath}, []field{fieldVisitors, fieldPath})
+	var stats []PageStats
+	assert.NoError(t, dbClient.Select(&stats, query, args...))
+	assert.Len(t, stats, 1)
+	assert.Equal(t, "/bar", stats[0].Path)
+	assert.Equal(t, 2, stats[0].Visitors)
+	assert.Equal(t, 0, stats[0].PreviousVisitors)
+	assert.Equal(t, 2, stats[0].VisitorsDelta)
+	assert.InDelta(t, 1, stats[0].VisitorsGrowth, 0.001)
+	filter.Limit = 0
+	args, query = buildQuery(filter, []field{fieldPath, fieldVisitors}, []field{fieldPath}, []field{fieldVisitors, fieldPath})
+	stats = stats[:0]
+	assert.NoError(t, dbClient.Select(&stats, query, args...))
+	assert.Len(t, stats, 2)
+	assert.Equal(t, "/", stats[1].Path)
+	assert.Equal(t, 1, stats[1].Visitors)
+	assert.Equal(t, 2, stats[1].PreviousVisitors)
+	assert.Equal(t, -1, stats[1].VisitorsDelta)
+	assert.InDelta(t, -0.5, stats[1].VisitorsGrowth, 0.001)
+}
+
+func TestCanCompare(t *testing.T) {
+	assert.True(t, canCompare([]field{fieldPath, fieldVisitors}, []field{fieldPath}))
+	assert.False(t, canCompare([]field{fieldPath}, []field{fieldPath}))
+	assert.False(t, canCompare([]field{fieldVisitors}, nil))
+	assert.False(t, canCompare([]field{fieldDay, fieldVisitors}, []field{fieldDay}))
+	assert.False(t, canCompare([]field{fieldHour, fieldVisitors}, []field{fieldHour}))
+}
+
+func TestPeriodField(t *testing.T) {
+	filter := &Filter{}
+	assert.Equal(t, fieldMinute, periodField(filter, PeriodMinute))
+	assert.Equal(t, fieldHourPeriod, periodField(filter, PeriodHour))
+	assert.Equal(t, fieldDay, periodField(filter, PeriodDay))
+	assert.Equal(t, fieldWeek, periodField(filter, PeriodWeek))
+	assert.Equal(t, fieldMonth, periodField(filter, PeriodMonth))
+	assert.Equal(t, fieldQuarter, periodField(filter, PeriodQuarter))
+	assert.Equal(t, fieldYear, periodField(filter, PeriodYear))
+	assert.Equal(t, fieldDay, periodField(filter, ""))
+	assert.Equal(t, fieldDay, periodField(filter, "unknown"))
+	filter = &Filter{From: pastDay(6), To: Today()}
+	assert.Equal(t, fieldMinute, periodField(filter, PeriodMinute))
+	filter = &Filter{From: pastDay(7), To: Today()}
+	assert.Equal(t, fieldHourPeriod, periodField(filter, PeriodMinute))
+	assert.Equal(t, fieldHourPeriod, periodField(filter, PeriodHour))
+	filter = &Filter{From: pastDay(366), To: Today()}
+	assert.Equal(t, fieldDay, periodField(filter, PeriodMinute))
+	assert.Equal(t, fieldDay, periodField(filter, PeriodHour))
+}
+
+func TestFieldWithFill(t *testing.T) {
+	filter := NewFilter(NullClient)
+	filter.validate()
+	args, query := fieldWithFill(filter, fieldWeek)
+	assert.Empty(t, args)
+	assert.Empty(t, query)
+	filter.From = pastDay(10)
+	filter.To = Today()
+	filter.Timezone, _ = time.LoadLocation("Europe/Berlin")
+	args, query = fieldWithFill(filter, fieldHourPeriod)
+	assert.Equal(t, []interface{}{filter.From, filter.To}, args)
+	assert.Equal(t, "WITH FILL FROM toDateTime(toDate(?), 'Europe/Berlin') TO toDateTime(toDate(?)+1, 'Europe/Berlin') STEP 3600", query)
+	_, query = fieldWithFill(filter, fieldMonth)
+	assert.Equal(t, "WITH FILL FROM toStartOfMonth(toDate(?)) TO toDate(?)+1 STEP INTERVAL 1 MONTH", query)
+	_, query = fieldWithFill(filter, fieldDay)
+	assert.Equal(t, "WITH FILL FROM toDate(?) TO toDate(?)+1 ", query)
+	_, query = fieldWithFill(filter, fieldPath)
+	assert.Empty(t, query)
+}
+
+func TestBuildQueryOther(t *testing.T) {
+	cleanupDB()
+	saveSessions(t, [][]Session{
+		{
+			{Sign: 1, VisitorID: 1, Time: Today(), Start: Today(), CountryCode: "de", PageViews: 1, IsBounce: true},
+			{Sign: 1, VisitorID: 2, Time: Today(), Start: Today(), CountryCode: "de", PageViews: 2},
+			{Sign: 1, VisitorID: 3, Time: Today(), Start: Today(), CountryCode: "de", PageViews: 1, IsBounce: true},
+			{Sign: 1, VisitorID: 4, Time: Today(), Start: Today(), CountryCode: "jp", PageViews: 1, IsBounce: true},
+			{Sign: 1, VisitorID: 5, Time: Today(), Start: Today(), CountryCode: "jp", PageViews: 3},
+			{Sign: 1, VisitorID: 6, Time: Today(), Start: Today(), CountryCode: "vn", PageViews: 1, IsBounce: true},
+		},
+	})
+	time.Sleep(time.Millisecond * 20)
+	analyzer := NewAnalyzer(dbClient)
+	filter := analyzer.getFilter(&Filter{Limit: 1, Other: true})
+	args, query := buildQuery(filter, []field{fieldCountry, fieldVisitors, fieldRelativeVisitors, fieldSessions, fieldBounces, fieldBounceRate}, []field{fieldCountry}, []field{fieldVisitors, fieldCountry})
+	var stats []QueryStats
+	assert.NoError(t, dbClient.Select(&stats, query, args...))
+	assert.Len(t, stats, 2)
+	assert.Equal(t, "de", stats[0].CountryCode)
+	assert.False(t, stats[0].Other)
+	assert.Equal(t, 3, stats[0].Visitors)
+	assert.InDelta(t, 0.5, stats[0].RelativeVisitors, 0.01)
+	assert.Equal(t, "", stats[1].CountryCode)
+	assert.True(t, stats[1].Other)
+	assert.Equal(t, 3, stats[1].Visitors)
+	assert.InDelta(t, 0.5, stats[1].RelativeVisitors, 0.01)
+	assert.Equal(t, 3, stats[1].Sessions)
+	assert.Equal(t, 2, stats[1].Bounces)
+	assert.InDelta(t, 0.6666, stats[1].BounceRate, 0.01)
+	filter.Offset = 2
+	args, query = buildQuery(filter, []field{fieldCountry, fieldVisitors, fieldRelativeVisitors}, []field{fieldCountry}, []field{fieldVisitors, fieldCountry})
+	stats = stats[:0]
+	assert.NoError(t, dbClient.Select(&stats, query, args...))
+	assert.Len(t, stats, 1)
+	assert.Equal(t, "vn", stats[0].CountryCode)
+	assert.False(t, stats[0].Other)
+}
+
+func TestCanAggregateOther(t *testing.T) {
+	assert.True(t, canAggregateOther([]field{fieldCountry}))
+	assert.True(t, canAggregateOther([]field{fieldReferrerName, fieldReferrer}))
+	assert.False(t, canAggregateOther(nil))
+	assert.False(t, canAggregateOther([]field{fieldDay}))
+	assert.False(t, canAggregateOther([]field{fieldWeek}))
+	assert.False(t, canAggregateOther([]field{fieldPath}))
+	assert.False(t, canAggregateOther([]field{fieldEntryPath, fieldEntryTitle}))
+	assert.False(t, canAggregateOther([]field{fieldExitPath}))
+}
+
+func TestBuildQueryConditions(t *testing.T) {
+	cleanupDB()
+	assert.NoError(t, dbClient.SavePageViews([]PageView{
+		{VisitorID: 1, Time: Today(), Path: "/", Title: "Home", CountryCode: "vn", UTMSource: "facebook"},
+		{VisitorID: 2, Time: Today(), Path: "/blog", Title: "Blog Post", CountryCode: "th", UTMSource: "Zalo"},
+		{VisitorID: 3, Time: Today(), Path: "/blog", Title: "Blog Post", CountryCode: "de", UTMSource: "google"},
+	}))
+	saveSessions(t, [][]Session{
+		{
+			{Sign: 1, VisitorID: 1, Time: Today(), EntryPath: "/", ExitPath: "/", EntryTitle: "Home", CountryCode: "vn", UTMSource: "facebook", PageViews: 1},
+			{Sign: 1, VisitorID: 2, Time: Today(), EntryPath: "/blog", ExitPath: "/blog", EntryTitle: "Blog Post", CountryCode: "th", UTMSource: "Zalo", PageViews: 1},
+			{Sign: 1, VisitorID: 3, Time: Today(), EntryPath: "/blog", ExitPath: "/blog", EntryTitle: "Blog Post", CountryCode: "de", UTMSource: "google", PageViews: 1},
+		},
+	})
+	time.Sleep(time.Millisecond * 20)
+	analyzer := NewAnalyzer(dbClient)
+	filter := analyzer.getFilter(&Filter{Conditions: []Condition{
+		{Field: "country_code", Operator: FilterIn, Values: []string{"vn", "th", "id"}},
+		{Field: "utm_source", Operator: FilterContains, Values: []string{"facebook", "zalo"}},
+	}})
+	args, query := buildQuery(filter, []field{fieldVisitors}, nil, nil)
+	var stats PageStats
+	assert.NoError(t, dbClient.Get(&stats, query, args...))
+	assert.Equal(t, 2, stats.Visitors)
+	filter = analyzer.getFilter(&Filter{Conditions: []Condition{
+		{Field: "title", Operator: FilterPrefix, Values: []string{"Blog"}},
+		{Field: "country_code", Operator: FilterNotIn, Values: []string{"de"}},
+	}})
+	args, query = buildQuery(filter, []field{fieldVisitors, fieldSessions, fieldViews}, nil, nil)
+	assert.NoError(t, dbClient.Get(&stats, query, args...))
+	assert.Equal(t, 1, stats.Visitors)
+	assert.Equal(t, 1, stats.Views)
+}
+
+func TestBuildQuerySegments(t *testing.T) {
+	cleanupDB()
+	assert.NoError(t, dbClient.SavePageViews([]PageView{
+		{VisitorID: 1, SessionID: 1, Time: Today(), Path: "/"},
+		{VisitorID: 1, SessionID: 2, Time: Today().Add(time.Hour), Path: "/pricing"},
+		{VisitorID: 2, SessionID: 1, Time: Today(), Path: "/"},
+		{VisitorID: 3, SessionID: 1, Time: Today(), Path: "/pricing"},
+	}))
+	assert.NoError(t, dbClient.SaveEvents([]Event{
+		{Name: "purchase", VisitorID: 1, SessionID: 2, Time: Today().Add(time.Hour), Path: "/pricing"},
+	}))
+	saveSessions(t, [][]Session{
+		{
+			{Sign: 1, VisitorID: 1, SessionID: 1, Time: Today(), EntryPath: "/", ExitPath: "/", PageViews: 1},
+			{Sign: 1, VisitorID: 1, SessionID: 2, Time: Today().Add(time.Hour), EntryPath: "/pricing", ExitPath: "/pricing", PageViews: 1},
+			{Sign: 1, VisitorID: 2, SessionID: 1, Time: Today(), EntryPath: "/", ExitPath: "/", PageViews: 1},
+			{Sign: 1, VisitorID: 3, SessionID: 1, Time: Today(), EntryPath: "/pricing", ExitPath: "/pricing", PageViews: 1},
+		},
+	})
+	time.Sleep(time.Millisecond * 20)
+	analyzer := NewAnalyzer(dbClient)
+	filter := analyzer.getFilter(&Filter{Segments: []Segment{{EventName: "purchase"}}})
+	args, query := buildQuery(filter, []field{fieldVisitors, fieldSessions, fieldViews}, nil, nil)
+	var stats PageStats
+	assert.NoError(t, dbClient.Get(&stats, query, args...))
+	assert.Equal(t, 1, stats.Visitors)
+	assert.Equal(t, 2, stats.Sessions)
+	assert.Equal(t, 2, stats.Views)
+	filter = analyzer.getFilter(&Filter{Segments: []Segment{{Scope: SegmentSession, Path: "/pricing", Exclude: true}}})
+	args, query = buildQuery(filter, []field{fieldPath, fieldVisitors}, []field{fieldPath}, []field{fieldPath})
+	var pages []PageStats
+	assert.NoError(t, dbClient.Select(&pages, query, args...))
+	assert.Len(t, pages, 1)
+	assert.Equal(t, "/", pages[0].Path)
+	assert.Equal(t, 2, pages[0].Visitors)
+}
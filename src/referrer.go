@@ -1,18 +1,13 @@
 package omisocial
 
 import (
-	"fmt"
-	"golang.org/x/net/html"
 	"net"
 	"net/http"
 	"net/url"
 	"strings"
 )
 
-const (
-	androidAppPrefix   = "android-app://"
-	googlePlayStoreURL = "https://play.google.com/store/apps/details?id=%s"
-)
+const androidAppPrefix = "android-app://"
 
 var referrerQueryParams = []string{
 	"ref",
@@ -35,9 +30,7 @@ func ignoreReferrer(r *http.Request) bool {
 		referrer = u.Hostname()
 	}
 
-	referrer = stripSubdomain(referrer)
-	_, found := referrerBlacklist[referrer]
-	return found
+	return getBlacklists().IgnoreReferrer(referrer)
 }
 
 func getReferrer(r *http.Request, ref string, domainBlacklist []string, ignoreSubdomain bool) (string, string, string) {
@@ -54,8 +47,12 @@ func getReferrer(r *http.Request, ref string, domainBlacklist []string, ignoreSu
 	}
 
 	if strings.HasPrefix(strings.ToLower(referrer), androidAppPrefix) {
-		name, icon := getAndroidAppName(referrer)
-		return referrer, name, icon
+		if source := getReferrerSourceByAndroidApp(getAndroidAppPackageName(referrer)); source != nil {
+			return referrer, source.Name, source.Icon
+		}
+
+		// unknown apps are resolved asynchronously by the AndroidAppResolver
+		return referrer, "", ""
 	}
 
 	u, err := url.ParseRequestURI(referrer)
@@ -67,6 +64,10 @@ func getReferrer(r *http.Request, ref string, domainBlacklist []string, ignoreSu
 
 		// accept non-url referrers (from utm_source for example)
 		if !containsString(domainBlacklist, referrer) {
+			if source := getReferrerSourceByName(referrer); source != nil {
+				return "", source.Name, source.Icon
+			}
+
 			return "", strings.TrimSpace(referrer), ""
 		}
 
@@ -79,6 +80,8 @@ func getReferrer(r *http.Request, ref string, domainBlacklist []string, ignoreSu
 		return "", "", ""
 	}
 
+	source := getReferrerSourceByHost(hostname)
+
 	if ignoreSubdomain {
 		hostname = stripSubdomain(hostname)
 	}
@@ -95,6 +98,10 @@ func getReferrer(r *http.Request, ref string, domainBlacklist []string, ignoreSu
 		u.Path = ""
 	}
 
+	if source != nil {
+		return u.String(), source.Name, source.Icon
+	}
+
 	return u.String(), hostname, ""
 }
 
@@ -154,105 +161,6 @@ func stripSubdomain(hostname string) string {
 	return hostname[index:]
 }
 
-func getAndroidAppName(referrer string) (string, string) {
-	packageName := referrer[len(androidAppPrefix):]
-	resp, err := http.Get(fmt.Sprintf(googlePlayStoreURL, packageName))
-
-	if err != nil || resp.StatusCode != http.StatusOK {
-		return "", ""
-	}
-
-	defer resp.Body.Close()
-	doc, err := html.Parse(resp.Body)
-
-	if err != nil {
-		return "", ""
-	}
-
-	titleNode := findAndroidAppName(doc)
-
-	if titleNode == nil {
-		return "", ""
-	}
-
-	appName := findTextNode(titleNode)
-
-	if appName == nil {
-		return "", ""
-	}
-
-	icon := ""
-	iconNode := findAndroidAppIcon(doc)
-
-	if iconNode != nil {
-		icon = getHTMLAttribute(iconNode, "src")
-	}
-
-	return appName.Data, icon
-}
-
-func findAndroidAppName(node *html.Node) *html.Node {
-	if node.Type == html.ElementNode && node.Data == "h1" {
-		return node
-	}
-
-	for c := node.FirstChild; c != nil; c = c.NextSibling {
-		if n := findAndroidAppName(c); n != nil {
-			return n
-		}
-	}
-
-	return nil
-}
-
-func findAndroidAppIcon(node *html.Node) *html.Node {
-	if node.Type == html.ElementNode && node.Data == "img" && hasHTMLAttribute(node, "itemprop", "image") {
-		return node
-	}
-
-	for c := node.FirstChild; c != nil; c = c.NextSibling {
-		if n := findAndroidAppIcon(c); n != nil {
-			return n
-		}
-	}
-
-	return nil
-}
-
-func findTextNode(node *html.Node) *html.Node {
-	if node.Type == html.TextNode {
-		return node
-	}
-
-	for c := node.FirstChild; c != nil; c = c.NextSibling {
-		if n := findTextNode(c); n != nil {
-			return n
-		}
-	}
-
-	return nil
-}
-
-func hasHTMLAttribute(node *html.Node, key, value string) bool {
-	for _, attr := range node.Attr {
-		if attr.Key == key && attr.Val == value {
-			return true
-		}
-	}
-
-	return false
-}
-
-func getHTMLAttribute(node *html.Node, key string) string {
-	for _, attr := range node.Attr {
-		if attr.Key == key {
-			return attr.Val
-		}
-	}
-
-	return ""
-}
-
 func containsString(list []string, str string) bool {
 	for _, item := range list {
 		if item == str {
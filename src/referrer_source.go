@@ -0,0 +1,192 @@
+package omisocial
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"sync/atomic"
+)
+
+// DefaultReferrerSources are the default sources used to normalize referrer names.
+// The first matching source is used, so more specific hosts (like mail.google.com) must be listed before general ones (google.*).
+var DefaultReferrerSources = []ReferrerSource{
+	// email
+	{Name: "Gmail", Icon: "https://mail.google.com/favicon.ico", Hosts: []string{"mail.google.com"}, AndroidApps: []string{"com.google.android.gm"}, Sources: []string{"gmail"}},
+	{Name: "Outlook", Icon: "https://outlook.live.com/favicon.ico", Hosts: []string{"outlook.live.com", "outlook.office.com", "outlook.office365.com"}, AndroidApps: []string{"com.microsoft.office.outlook"}, Sources: []string{"outlook"}},
+	{Name: "Yahoo Mail", Icon: "https://mail.yahoo.com/favicon.ico", Hosts: []string{"mail.yahoo.com"}, AndroidApps: []string{"com.yahoo.mobile.client.android.mail"}},
+
+	// search engines
+	{Name: "Google", Icon: "https://www.google.com/favicon.ico", Hosts: []string{"google.*"}, AndroidApps: []string{"com.google.android.googlequicksearchbox"}, Sources: []string{"google"}, KeywordParams: []string{"q"}},
+	{Name: "Bing", Icon: "https://www.bing.com/favicon.ico", Hosts: []string{"bing.com"}, Sources: []string{"bing"}, KeywordParams: []string{"q"}},
+	{Name: "Yahoo", Icon: "https://www.yahoo.com/favicon.ico", Hosts: []string{"yahoo.*"}, Sources: []string{"yahoo"}, KeywordParams: []string{"p"}},
+	{Name: "DuckDuckGo", Icon: "https://duckduckgo.com/favicon.ico", Hosts: []string{"duckduckgo.com"}, AndroidApps: []string{"com.duckduckgo.mobile.android"}, Sources: []string{"duckduckgo"}, KeywordParams: []string{"q"}},
+	{Name: "Baidu", Icon: "https://www.baidu.com/favicon.ico", Hosts: []string{"baidu.com"}, Sources: []string{"baidu"}, KeywordParams: []string{"wd", "word"}},
+	{Name: "Yandex", Icon: "https://yandex.com/favicon.ico", Hosts: []string{"yandex.*", "ya.ru"}, Sources: []string{"yandex"}, KeywordParams: []string{"text"}},
+	{Name: "Ecosia", Icon: "https://www.ecosia.org/favicon.ico", Hosts: []string{"ecosia.org"}, Sources: []string{"ecosia"}, KeywordParams: []string{"q"}},
+	{Name: "Qwant", Icon: "https://www.qwant.com/favicon.ico", Hosts: []string{"qwant.com"}, Sources: []string{"qwant"}, KeywordParams: []string{"q"}},
+	{Name: "Naver", Icon: "https://www.naver.com/favicon.ico", Hosts: []string{"naver.com"}, Sources: []string{"naver"}, KeywordParams: []string{"query"}},
+	{Name: "Seznam", Icon: "https://www.seznam.cz/favicon.ico", Hosts: []string{"seznam.cz"}, Sources: []string{"seznam"}, KeywordParams: []string{"q"}},
+	{Name: "Cốc Cốc", Icon: "https://coccoc.com/favicon.ico", Hosts: []string{"coccoc.com"}, AndroidApps: []string{"com.coccoc.trinhduyet"}, Sources: []string{"coccoc"}, KeywordParams: []string{"query"}},
+	{Name: "Brave Search", Icon: "https://search.brave.com/favicon.ico", Hosts: []string{"search.brave.com"}, KeywordParams: []string{"q"}},
+	{Name: "Startpage", Icon: "https://www.startpage.com/favicon.ico", Hosts: []string{"startpage.com"}, KeywordParams: []string{"query"}},
+
+	// social networks
+	{Name: "Facebook", Icon: "https://www.facebook.com/favicon.ico", Hosts: []string{"facebook.com", "fb.com", "fb.me"}, AndroidApps: []string{"com.facebook.katana", "com.facebook.lite"}, Sources: []string{"facebook", "fb"}},
+	{Name: "Instagram", Icon: "https://www.instagram.com/favicon.ico", Hosts: []string{"instagram.com"}, AndroidApps: []string{"com.instagram.android"}, Sources: []string{"instagram", "ig"}},
+	{Name: "Twitter", Icon: "https://twitter.com/favicon.ico", Hosts: []string{"twitter.com", "t.co", "x.com"}, AndroidApps: []string{"com.twitter.android"}, Sources: []string{"twitter", "x"}},
+	{Name: "LinkedIn", Icon: "https://www.linkedin.com/favicon.ico", Hosts: []string{"linkedin.com", "lnkd.in"}, AndroidApps: []string{"com.linkedin.android"}, Sources: []string{"linkedin"}},
+	{Name: "Pinterest", Icon: "https://www.pinterest.com/favicon.ico", Hosts: []string{"pinterest.*", "pin.it"}, AndroidApps: []string{"com.pinterest"}, Sources: []string{"pinterest"}},
+	{Name: "Reddit", Icon: "https://www.reddit.com/favicon.ico", Hosts: []string{"reddit.com", "redd.it"}, AndroidApps: []string{"com.reddit.frontpage"}, Sources: []string{"reddit"}},
+	{Name: "TikTok", Icon: "https://www.tiktok.com/favicon.ico", Hosts: []string{"tiktok.com"}, AndroidApps: []string{"com.zhiliaoapp.musically", "com.ss.android.ugc.trill"}, Sources: []string{"tiktok"}},
+	{Name: "YouTube", Icon: "https://www.youtube.com/favicon.ico", Hosts: []string{"youtube.com", "youtu.be"}, AndroidApps: []string{"com.google.android.youtube"}, Sources: []string{"youtube"}},
+	{Name: "VK", Icon: "https://vk.com/favicon.ico", Hosts: []string{"vk.com"}, AndroidApps: []string{"com.vkontakte.android"}, Sources: []string{"vk"}},
+	{Name: "Threads", Icon: "https://www.threads.net/favicon.ico", Hosts: []string{"threads.net"}, AndroidApps: []string{"com.instagram.barcelona"}, Sources: []string{"threads"}},
+	{Name: "Tumblr", Icon: "https://www.tumblr.com/favicon.ico", Hosts: []string{"tumblr.com"}, Sources: []string{"tumblr"}},
+	{Name: "Quora", Icon: "https://www.quora.com/favicon.ico", Hosts: []string{"quora.com"}, Sources: []string{"quora"}},
+	{Name: "Hacker News", Icon: "https://news.ycombinator.com/favicon.ico", Hosts: []string{"news.ycombinator.com"}, Sources: []string{"hackernews", "hn"}},
+	{Name: "GitHub", Icon: "https://github.com/favicon.ico", Hosts: []string{"github.com"}, Sources: []string{"github"}},
+
+	// messaging apps
+	{Name: "Messenger", Icon: "https://www.messenger.com/favicon.ico", Hosts: []string{"messenger.com", "m.me"}, AndroidApps: []string{"com.facebook.orca"}, Sources: []string{"messenger"}},
+	{Name: "WhatsApp", Icon: "https://www.whatsapp.com/favicon.ico", Hosts: []string{"whatsapp.com", "wa.me"}, AndroidApps: []string{"com.whatsapp"}, Sources: []string{"whatsapp"}},
+	{Name: "Telegram", Icon: "https://telegram.org/favicon.ico", Hosts: []string{"telegram.org", "telegram.me", "t.me"}, AndroidApps: []string{"org.telegram.messenger"}, Sources: []string{"telegram"}},
+	{Name: "Zalo", Icon: "https://zalo.me/favicon.ico", Hosts: []string{"zalo.me"}, AndroidApps: []string{"com.zing.zalo"}, Sources: []string{"zalo"}},
+	{Name: "LINE", Icon: "https://line.me/favicon.ico", Hosts: []string{"line.me"}, AndroidApps: []string{"jp.naver.line.android"}, Sources: []string{"line"}},
+	{Name: "Discord", Icon: "https://discord.com/favicon.ico", Hosts: []string{"discord.com", "discord.gg", "discordapp.com"}, AndroidApps: []string{"com.discord"}, Sources: []string{"discord"}},
+	{Name: "Slack", Icon: "https://slack.com/favicon.ico", Hosts: []string{"slack.com"}, AndroidApps: []string{"com.Slack"}, Sources: []string{"slack"}},
+	{Name: "Snapchat", Icon: "https://www.snapchat.com/favicon.ico", Hosts: []string{"snapchat.com"}, AndroidApps: []string{"com.snapchat.android"}, Sources: []string{"snapchat"}},
+	{Name: "Viber", Icon: "https://www.viber.com/favicon.ico", Hosts: []string{"viber.com"}, AndroidApps: []string{"com.viber.voip"}, Sources: []string{"viber"}},
+}
+
+// ReferrerSource maps referrers to a canonical name and icon.
+// This way referrers from different hosts of the same source (like m.facebook.com and l.facebook.com) are grouped together.
+// Values are compared case-insensitive.
+type ReferrerSource struct {
+	// Name is the canonical name stored as the referrer name.
+	Name string
+
+	// Icon is the URL or identifier stored as the referrer icon.
+	Icon string
+
+	// Hosts matches the referrer hostname, including subdomains.
+	// Entries ending with ".*" match all top-level domains (google.* matches google.com and google.com.vn for example).
+	Hosts []string
+
+	// AndroidApps matches the package name of android-app:// referrers (like com.facebook.katana).
+	AndroidApps []string
+
+	// Sources matches referrers that aren't URLs (like utm_source=fb).
+	Sources []string
+
+	// KeywordParams are the query parameters of the referrer URL containing the search term for search engines.
+	// Most search engines don't send the search term anymore, but if they do, it is stored as the utm_term in case it isn't set.
+	KeywordParams []string
+}
+
+var referrerSources atomic.Value // []ReferrerSource
+
+func init() {
+	SetReferrerSources(DefaultReferrerSources)
+}
+
+// SetReferrerSources sets the sources used to normalize referrer names and icons of new sessions.
+// Referrers not matching any source keep the hostname as their name.
+// This function is concurrency save and can be called at any time.
+func SetReferrerSources(sources []ReferrerSource) {
+	normalized := make([]ReferrerSource, len(sources))
+
+	for i, source := range sources {
+		normalized[i] = ReferrerSource{
+			Name:          source.Name,
+			Icon:          source.Icon,
+			Hosts:         toLowerStrings(source.Hosts),
+			AndroidApps:   toLowerStrings(source.AndroidApps),
+			Sources:       toLowerStrings(source.Sources),
+			KeywordParams: source.KeywordParams,
+		}
+	}
+
+	referrerSources.Store(normalized)
+}
+
+// getReferrerSourceByHost returns the source for given referrer hostname or nil if there is none.
+func getReferrerSourceByHost(hostname string) *ReferrerSource {
+	hostname = strings.ToLower(hostname)
+	sources := referrerSources.Load().([]ReferrerSource)
+
+	for i := range sources {
+		if matchAnyHost(sources[i].Hosts, hostname) {
+			return &sources[i]
+		}
+	}
+
+	return nil
+}
+
+// getReferrerSourceByAndroidApp returns the source for given Android package name or nil if there is none.
+func getReferrerSourceByAndroidApp(packageName string) *ReferrerSource {
+	packageName = strings.ToLower(packageName)
+	sources := referrerSources.Load().([]ReferrerSource)
+
+	for i := range sources {
+		if containsString(sources[i].AndroidApps, packageName) {
+			return &sources[i]
+		}
+	}
+
+	return nil
+}
+
+// getReferrerSourceByName returns the source for given non-URL referrer or nil if there is none.
+// The referrer is matched against the sources first, and the hosts in case it looks like a hostname (like facebook.com/page).
+func getReferrerSourceByName(name string) *ReferrerSource {
+	name = strings.ToLower(strings.TrimSpace(name))
+	sources := referrerSources.Load().([]ReferrerSource)
+
+	for i := range sources {
+		if containsString(sources[i].Sources, name) {
+			return &sources[i]
+		}
+	}
+
+	if strings.Contains(name, ".") {
+		if i := strings.IndexRune(name, '/'); i > 0 {
+			name = name[:i]
+		}
+
+		return getReferrerSourceByHost(name)
+	}
+
+	return nil
+}
+
+// getSearchEngineKeyword returns the search term from the referrer URL in case it is a search engine sending it.
+func getSearchEngineKeyword(r *http.Request, ref string) string {
+	referrer := ref
+
+	if referrer == "" {
+		referrer = getReferrerFromHeaderOrQuery(r)
+	}
+
+	u, err := url.ParseRequestURI(referrer)
+
+	if err != nil {
+		return ""
+	}
+
+	source := getReferrerSourceByHost(u.Hostname())
+
+	if source == nil {
+		return ""
+	}
+
+	query := u.Query()
+
+	for _, param := range source.KeywordParams {
+		if keyword := strings.TrimSpace(query.Get(param)); keyword != "" {
+			return keyword
+		}
+	}
+
+	return ""
+}
@@ -83,6 +83,98 @@ func TestGetReferrer(t *testing.T) {
 	}
 }
 
+func TestGetReferrerSource(t *testing.T) {
+	input := []string{
+		"https://m.facebook.com/",
+		"https://l.facebook.com/l.php?u=https%3A%2F%2Fexample.com",
+		"https://lm.facebook.com/",
+		"https://www.google.com.vn/",
+		"https://mail.google.com/mail/u/0/",
+		"https://t.co/abc",
+		"https://zalo.me/",
+		"fb",
+		"Instagram",
+		"facebook.com/page",
+		"newsletter",
+		androidAppPrefix + "com.facebook.katana",
+		"https://example.com/",
+	}
+	expected := []struct {
+		name string
+		icon string
+	}{
+		{"Facebook", "https://www.facebook.com/favicon.ico"},
+		{"Facebook", "https://www.facebook.com/favicon.ico"},
+		{"Facebook", "https://www.facebook.com/favicon.ico"},
+		{"Google", "https://www.google.com/favicon.ico"},
+		{"Gmail", "https://mail.google.com/favicon.ico"},
+		{"Twitter", "https://twitter.com/favicon.ico"},
+		{"Zalo", "https://zalo.me/favicon.ico"},
+		{"Facebook", "https://www.facebook.com/favicon.ico"},
+		{"Instagram", "https://www.instagram.com/favicon.ico"},
+		{"Facebook", "https://www.facebook.com/favicon.ico"},
+		{"newsletter", ""},
+		{"Facebook", "https://www.facebook.com/favicon.ico"},
+		{"example.com", ""},
+	}
+
+	for i, in := range input {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Add("Referer", in)
+		_, name, icon := getReferrer(r, "", nil, false)
+		assert.Equal(t, expected[i].name, name, in)
+		assert.Equal(t, expected[i].icon, icon, in)
+	}
+}
+
+func TestSetReferrerSources(t *testing.T) {
+	defer SetReferrerSources(DefaultReferrerSources)
+	SetReferrerSources([]ReferrerSource{
+		{Name: "Shopee", Icon: "shopee", Hosts: []string{"Shopee.*"}, Sources: []string{"Shopee"}},
+	})
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Add("Referer", "https://shopee.vn/product")
+	referrer, name, icon := getReferrer(r, "", nil, false)
+	assert.Equal(t, "https://shopee.vn/product", referrer)
+	assert.Equal(t, "Shopee", name)
+	assert.Equal(t, "shopee", icon)
+	_, name, _ = getReferrer(r, "shopee", nil, false)
+	assert.Equal(t, "Shopee", name)
+	_, name, icon = getReferrer(r, "https://m.facebook.com/", nil, false)
+	assert.Equal(t, "m.facebook.com", name)
+	assert.Empty(t, icon)
+}
+
+func TestGetSearchEngineKeyword(t *testing.T) {
+	input := []string{
+		"https://www.google.com/",
+		"https://www.google.com/search?q=omisocial+analytics",
+		"https://yandex.ru/search/?text=analytics",
+		"https://www.baidu.com/s?word=analytics",
+		"https://coccoc.com/search?query=ph%C3%A2n+t%C3%ADch",
+		"https://example.com/?q=analytics",
+		"not a url",
+	}
+	expected := []string{
+		"",
+		"omisocial analytics",
+		"analytics",
+		"analytics",
+		"phân tích",
+		"",
+		"",
+	}
+
+	for i, in := range input {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Add("Referer", in)
+		assert.Equal(t, expected[i], getSearchEngineKeyword(r, ""))
+	}
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	assert.Equal(t, "shoes", getSearchEngineKeyword(r, "https://www.bing.com/search?q=shoes"))
+}
+
 func TestGetReferrerFromHeaderOrQuery(t *testing.T) {
 	input := [][]string{
 		{"", ""},
@@ -0,0 +1,338 @@
+package omisocial
+
+import (
+	"errors"
+	"github.com/jmoiron/sqlx/reflectx"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+const (
+	// queryTimeOnPage is the metric for the average time on page, which is calculated separately from the other metrics.
+	queryTimeOnPage = "average_time_spent_seconds"
+)
+
+var (
+	// ErrNoQueryMetric is returned in case no metric was specified for a query.
+	ErrNoQueryMetric = errors.New("no metric specified")
+
+	// ErrUnknownQueryDimension is returned in case a dimension is not supported.
+	ErrUnknownQueryDimension = errors.New("unknown dimension")
+
+	// ErrUnknownQueryMetric is returned in case a metric is not supported.
+	ErrUnknownQueryMetric = errors.New("unknown metric")
+
+	// ErrInvalidQuerySort is returned in case the results are sorted by a field that has not been selected or can't be sorted by.
+	ErrInvalidQuerySort = errors.New("sort field must be a selected dimension or metric")
+
+	// ErrQueryTimeOnPage is returned in case the time on page is requested for dimensions other than the path.
+	ErrQueryTimeOnPage = errors.New("time on page requires the path as the only dimension")
+
+	// queryDimensions are the fields results can be grouped by.
+	queryDimensions = map[string]field{
+		"path":            fieldPath,
+		"entry_path":      fieldEntryPath,
+		"exit_path":       fieldExitPath,
+		"referrer":        fieldReferrer,
+		"referrer_name":   fieldReferrerName,
+		"channel":         fieldChannel,
+		"language":        fieldLanguage,
+		"country_code":    fieldCountry,
+		"region":          fieldRegion,
+		"city":            fieldCity,
+		"postal_code":     fieldPostalCode,
+		"time_zone":       fieldTimeZone,
+		"asn":             fieldASN,
+		"as_organization": fieldASOrganization,
+		"browser":         fieldBrowser,
+		"browser_version": fieldBrowserVersion,
+		"os":              fieldOS,
+		"os_version":      fieldOSVersion,
+		"device_type":     fieldDeviceType,
+		"device_vendor":   fieldDeviceVendor,
+		"device_model":    fieldDeviceModel,
+		"screen_class":    fieldScreenClass,
+		"webview":         fieldWebView,
+		"new_visitor":     fieldNewVisitor,
+		"utm_source":      fieldUTMSource,
+		"utm_medium":      fieldUTMMedium,
+		"utm_campaign":    fieldUTMCampaign,
+		"utm_content":     fieldUTMContent,
+		"utm_term":        fieldUTMTerm,
+		"otm_source":      fieldOTMSource,
+		"hour":            fieldHour,
+		"weekday":         fieldWeekday,
+		"day":             renameField(fieldDay, "day"),
+		"week":            renameField(fieldWeek, "week"),
+		"month":           renameField(fieldMonth, "month"),
+		"quarter":         renameField(fieldQuarter, "quarter"),
+		"year":            renameField(fieldYear, "year"),
+	}
+
+	// queryMetrics are the fields that can be selected for the dimensions, in the order they must be selected.
+	// Derived metrics (like the bounce rate) must follow the metrics they are calculated from.
+	queryMetrics = []field{
+		fieldVisitors,
+		fieldSessions,
+		fieldViews,
+		fieldBounces,
+		fieldRelativeVisitors,
+		fieldRelativeViews,
+		fieldBounceRate,
+		fieldCR,
+	}
+
+	// queryMetricDependencies are the metrics required to calculate a derived metric.
+	queryMetricDependencies = map[string][]string{
+		fieldRelativeVisitors.name: {fieldVisitors.name},
+		fieldRelativeViews.name:    {fieldViews.name},
+		fieldBounceRate.name:       {fieldBounces.name, fieldSessions.name},
+		fieldCR.name:               {fieldVisitors.name},
+	}
+
+	// queryFilters set the Filter field for a dimension or filter name.
+	queryFilters = map[string]func(*Filter, string){
+		"path":            func(filter *Filter, value string) { filter.Path = value },
+		"path_pattern":    func(filter *Filter, value string) { filter.PathPattern = value },
+		"entry_path":      func(filter *Filter, value string) { filter.EntryPath = value },
+		"exit_path":       func(filter *Filter, value string) { filter.ExitPath = value },
+		"event_name":      func(filter *Filter, value string) { filter.EventName = value },
+		"referrer":        func(filter *Filter, value string) { filter.Referrer = value },
+		"referrer_name":   func(filter *Filter, value string) { filter.ReferrerName = value },
+		"channel":         func(filter *Filter, value string) { filter.Channel = value },
+		"language":        func(filter *Filter, value string) { filter.Language = value },
+		"country_code":    func(filter *Filter, value string) { filter.Country = value },
+		"region":          func(filter *Filter, value string) { filter.Region = value },
+		"city":            func(filter *Filter, value string) { filter.City = value },
+		"postal_code":     func(filter *Filter, value string) { filter.PostalCode = value },
+		"time_zone":       func(filter *Filter, value string) { filter.TimeZone = value },
+		"asn":             func(filter *Filter, value string) { filter.ASN = value },
+		"as_organization": func(filter *Filter, value string) { filter.ASOrganization = value },
+		"browser":         func(filter *Filter, value string) { filter.Browser = value },
+		"browser_version": func(filter *Filter, value string) { filter.BrowserVersion = value },
+		"os":              func(filter *Filter, value string) { filter.OS = value },
+		"os_version":      func(filter *Filter, value string) { filter.OSVersion = value },
+		"platform":        func(filter *Filter, value string) { filter.Platform = value },
+		"device_type":     func(filter *Filter, value string) { filter.DeviceType = value },
+		"device_vendor":   func(filter *Filter, value string) { filter.DeviceVendor = value },
+		"device_model":    func(filter *Filter, value string) { filter.DeviceModel = value },
+		"screen_class":    func(filter *Filter, value string) { filter.ScreenClass = value },
+		"webview":         func(filter *Filter, value string) { filter.WebView = value },
+		"new_visitor":     func(filter *Filter, value string) { filter.NewVisitor = value },
+		"utm_source":      func(filter *Filter, value string) { filter.UTMSource = value },
+		"utm_medium":      func(filter *Filter, value string) { filter.UTMMedium = value },
+		"utm_campaign":    func(filter *Filter, value string) { filter.UTMCampaign = value },
+		"utm_content":     func(filter *Filter, value string) { filter.UTMContent = value },
+		"utm_term":        func(filter *Filter, value string) { filter.UTMTerm = value },
+	}
+
+	queryStatsMapper = reflectx.NewMapperFunc("db", strings.ToLower)
+)
+
+// QueryOptions selects the dimensions and metrics for Analyzer.Query.
+type QueryOptions struct {
+	// Dimensions are the fields the results are grouped by.
+	// Can be path, entry_path, exit_path, referrer, referrer_name, channel, language, country_code, region, city, postal_code,
+	// time_zone, asn, as_organization, browser, browser_version, os, os_version, device_type, device_vendor, device_model,
+	// screen_class, webview, new_visitor, one of the utm_ fields (like utm_source), otm_source, the time of day (hour), the day of week (weekday, 1 for Monday),
+	// and day, week, month, quarter, or year (labeled with the start of the period).
+	Dimensions []string
+
+	// Metrics are the values calculated for the dimensions.
+	// Can be visitors, sessions, views, bounces, bounce_rate, relative_visitors, relative_views, cr (conversion rate),
+	// or average_time_spent_seconds (time on page, which requires the path as the only dimension).
+	// At least one metric must be selected.
+	Metrics []string
+
+	// Sort are the selected dimensions and metrics to sort the results by, in ascending order unless prefixed with "-".
+	// The time on page can't be sorted by.
+	// Set to the first metric in descending order followed by the dimensions by default.
+	Sort []string
+}
+
+func (options *QueryOptions) validate() error {
+	if len(options.Metrics) == 0 {
+		return ErrNoQueryMetric
+	}
+
+	options.Dimensions = uniqueStrings(options.Dimensions)
+	options.Metrics = uniqueStrings(options.Metrics)
+
+	for _, dimension := range options.Dimensions {
+		if _, found := queryDimensions[dimension]; !found {
+			return ErrUnknownQueryDimension
+		}
+	}
+
+	for _, metric := range options.Metrics {
+		if metric == queryTimeOnPage {
+			if len(options.Dimensions) != 1 || options.Dimensions[0] != fieldPath.name {
+				return ErrQueryTimeOnPage
+			}
+		} else if !fieldsContain(queryMetrics, metric) {
+			return ErrUnknownQueryMetric
+		}
+	}
+
+	if len(options.Sort) == 0 {
+		for _, metric := range options.Metrics {
+			if metric != queryTimeOnPage {
+				options.Sort = append(options.Sort, "-"+metric)
+				break
+			}
+		}
+
+		options.Sort = append(options.Sort, options.Dimensions...)
+	}
+
+	for _, sort := range options.Sort {
+		name := strings.TrimPrefix(sort, "-")
+
+		if name == queryTimeOnPage || (!containsString(options.Dimensions, name) && !containsString(options.Metrics, name)) {
+			return ErrInvalidQuerySort
+		}
+	}
+
+	return nil
+}
+
+// fields returns the fields to select, group, and order by.
+// Metrics required to calculate a selected metric are selected too.
+func (options *QueryOptions) fields() ([]field, []field, []field) {
+	groupBy := make([]field, 0, len(options.Dimensions))
+
+	for _, dimension := range options.Dimensions {
+		groupBy = append(groupBy, queryDimensions[dimension])
+	}
+
+	metrics := make(map[string]bool)
+
+	for _, metric := range options.Metrics {
+		metrics[metric] = true
+
+		for _, dependency := range queryMetricDependencies[metric] {
+			metrics[dependency] = true
+		}
+	}
+
+	fields := make([]field, 0, len(groupBy)+len(metrics))
+	fields = append(fields, groupBy...)
+
+	for _, metric := range queryMetrics {
+		if metrics[metric.name] {
+			fields = append(fields, metric)
+		}
+	}
+
+	orderBy := make([]field, 0, len(options.Sort))
+
+	for _, sort := range options.Sort {
+		name := strings.TrimPrefix(sort, "-")
+
+		for _, f := range fields {
+			if f.name == name {
+				if strings.HasPrefix(sort, "-") {
+					f.queryDirection = "DESC"
+				} else {
+					f.queryDirection = "ASC"
+				}
+
+				orderBy = append(orderBy, f)
+				break
+			}
+		}
+	}
+
+	return fields, groupBy, orderBy
+}
+
+// Query returns the selected metrics grouped by the selected dimensions.
+// The dimensions and metrics are validated against an allow-list and an error is returned for unknown fields.
+// The Filter.Limit and Filter.Offset can be used to page through the results.
+func (analyzer *Analyzer) Query(filter *Filter, options QueryOptions) ([]QueryStats, error) {
+	if err := options.validate(); err != nil {
+		return nil, err
+	}
+
+	filter = analyzer.getFilter(filter)
+	fields, groupBy, orderBy := options.fields()
+	args, query := buildQuery(filter, fields, groupBy, orderBy)
+	var stats []QueryStats
+
+	if err := analyzer.store.Select(&stats, query, args...); err != nil {
+		return nil, err
+	}
+
+	if containsString(options.Metrics, queryTimeOnPage) && filter.table() == "session" {
+		paths := make([]string, 0, len(stats))
+
+		for i := range stats {
+			paths = append(paths, stats[i].Path)
+		}
+
+		top, err := analyzer.avgTimeOnPage(filter, paths)
+
+		if err != nil {
+			return nil, err
+		}
+
+		for i := range stats {
+			for j := range top {
+				if stats[i].Path == top[j].Path {
+					stats[i].AverageTimeSpentSeconds = top[j].AverageTimeSpentSeconds
+					break
+				}
+			}
+		}
+	}
+
+	return stats, nil
+}
+
+// Columns returns the values for given dimensions and metrics by name.
+// Unknown names are ignored.
+func (stats *QueryStats) Columns(names []string) map[string]interface{} {
+	columns := make(map[string]interface{}, len(names))
+	v := reflect.ValueOf(stats).Elem()
+	fields := queryStatsMapper.TypeMap(v.Type()).Names
+
+	for _, name := range names {
+		if info, found := fields[name]; found {
+			columns[name] = reflectx.FieldByIndexesReadOnly(v, info.Index).Interface()
+		}
+	}
+
+	return columns
+}
+
+// SetQueryFilter sets the filter field for given dimension name (like country_code), using the same syntax as the Filter.
+// Next to the dimensions, path_pattern, event_name, and platform can be filtered by.
+// It returns false in case the field can't be filtered by.
+func (filter *Filter) SetQueryFilter(name, value string) bool {
+	set, found := queryFilters[name]
+
+	if found {
+		set(filter, value)
+	}
+
+	return found
+}
+
+// QueryFilters returns the names that can be used with Filter.SetQueryFilter.
+func QueryFilters() []string {
+	names := make([]string, 0, len(queryFilters))
+
+	for name := range queryFilters {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+	return names
+}
+
+// renameField returns a copy of given field selected as name.
+func renameField(f field, name string) field {
+	f.name = name
+	return f
+}
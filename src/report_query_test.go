@@ -0,0 +1,100 @@
+package omisocial
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestAnalyzer_Query(t *testing.T) {
+	cleanupDB()
+	saveSessions(t, [][]Session{
+		{
+			{Sign: 1, VisitorID: 1, Time: Today(), Start: Today(), ExitPath: "/", CountryCode: "de", Browser: BrowserChrome, PageViews: 1, IsBounce: true},
+			{Sign: 1, VisitorID: 2, Time: Today(), Start: Today(), ExitPath: "/", CountryCode: "de", Browser: BrowserFirefox, PageViews: 2},
+			{Sign: 1, VisitorID: 3, Time: Today(), Start: Today(), ExitPath: "/", CountryCode: "jp", Browser: BrowserChrome, PageViews: 1, IsBounce: true},
+		},
+	})
+	time.Sleep(time.Millisecond * 20)
+	analyzer := NewAnalyzer(dbClient)
+	stats, err := analyzer.Query(nil, QueryOptions{
+		Dimensions: []string{"country_code"},
+		Metrics:    []string{"visitors", "bounce_rate"},
+	})
+	assert.NoError(t, err)
+	assert.Len(t, stats, 2)
+	assert.Equal(t, "de", stats[0].CountryCode)
+	assert.Equal(t, 2, stats[0].Visitors)
+	assert.InDelta(t, 0.5, stats[0].BounceRate, 0.01)
+	assert.Equal(t, "jp", stats[1].CountryCode)
+	assert.Equal(t, 1, stats[1].Visitors)
+	assert.InDelta(t, 1, stats[1].BounceRate, 0.01)
+	stats, err = analyzer.Query(&Filter{Browser: BrowserChrome}, QueryOptions{
+		Dimensions: []string{"browser", "country_code"},
+		Metrics:    []string{"views"},
+		Sort:       []string{"-country_code"},
+	})
+	assert.NoError(t, err)
+	assert.Len(t, stats, 2)
+	assert.Equal(t, "jp", stats[0].CountryCode)
+	assert.Equal(t, "de", stats[1].CountryCode)
+	assert.Equal(t, 1, stats[0].Views)
+}
+
+func TestQueryOptions_Validate(t *testing.T) {
+	options := QueryOptions{Dimensions: []string{"path", "path"}, Metrics: []string{"bounce_rate", "visitors"}}
+	assert.NoError(t, options.validate())
+	assert.Equal(t, []string{"path"}, options.Dimensions)
+	assert.Equal(t, []string{"-bounce_rate", "path"}, options.Sort)
+	options = QueryOptions{Dimensions: []string{"path"}, Metrics: []string{"average_time_spent_seconds"}}
+	assert.NoError(t, options.validate())
+	assert.Equal(t, []string{"path"}, options.Sort)
+	assert.Equal(t, ErrNoQueryMetric, (&QueryOptions{Dimensions: []string{"path"}}).validate())
+	assert.Equal(t, ErrUnknownQueryDimension, (&QueryOptions{Dimensions: []string{"path; DROP TABLE session"}, Metrics: []string{"visitors"}}).validate())
+	assert.Equal(t, ErrUnknownQueryMetric, (&QueryOptions{Metrics: []string{"uniq(visitor_id)"}}).validate())
+	assert.Equal(t, ErrInvalidQuerySort, (&QueryOptions{Dimensions: []string{"path"}, Metrics: []string{"visitors"}, Sort: []string{"views"}}).validate())
+	assert.Equal(t, ErrInvalidQuerySort, (&QueryOptions{Dimensions: []string{"path"}, Metrics: []string{"average_time_spent_seconds"}, Sort: []string{"-average_time_spent_seconds"}}).validate())
+	assert.Equal(t, ErrQueryTimeOnPage, (&QueryOptions{Dimensions: []string{"path", "browser"}, Metrics: []string{"average_time_spent_seconds"}}).validate())
+}
+
+func TestQueryOptions_Fields(t *testing.T) {
+	options := QueryOptions{Dimensions: []string{"week", "browser"}, Metrics: []string{"bounce_rate", "relative_visitors"}, Sort: []string{"week", "bounce_rate"}}
+	assert.NoError(t, options.validate())
+	fields, groupBy, orderBy := options.fields()
+	names := make([]string, 0, len(fields))
+
+	for _, f := range fields {
+		names = append(names, f.name)
+	}
+
+	assert.Equal(t, []string{"week", "browser", "visitors", "sessions", "bounces", "relative_visitors", "bounce_rate"}, names)
+	assert.Len(t, groupBy, 2)
+	assert.Len(t, orderBy, 2)
+	assert.Equal(t, "week", orderBy[0].name)
+	assert.Equal(t, "ASC", orderBy[0].queryDirection)
+	assert.Equal(t, "bounce_rate", orderBy[1].name)
+	assert.Equal(t, "ASC", orderBy[1].queryDirection)
+	assert.Equal(t, "DESC", fieldBounceRate.queryDirection)
+}
+
+func TestQueryStats_Columns(t *testing.T) {
+	stats := QueryStats{Path: "/", Visitors: 42, BounceRate: 0.5}
+	stats.PreviousVisitors = 21
+	assert.Equal(t, map[string]interface{}{
+		"path":              "/",
+		"visitors":          42,
+		"bounce_rate":       0.5,
+		"previous_visitors": 21,
+	}, stats.Columns([]string{"path", "visitors", "bounce_rate", "previous_visitors", "unknown"}))
+}
+
+func TestFilter_SetQueryFilter(t *testing.T) {
+	filter := NewFilter(NullClient)
+	assert.True(t, filter.SetQueryFilter("country_code", "!de"))
+	assert.True(t, filter.SetQueryFilter("utm_source", "null"))
+	assert.False(t, filter.SetQueryFilter("client_id", "1"))
+	assert.Equal(t, "!de", filter.Country)
+	assert.Equal(t, "null", filter.UTMSource)
+	assert.Zero(t, filter.ClientID)
+	assert.Contains(t, QueryFilters(), "event_name")
+}
@@ -1,5 +1,7 @@
 package omisocial
 
+import "time"
+
 type Response struct {
 	Message string      `json:"message"`
 	Error   bool        `json:"error"`
@@ -78,7 +80,7 @@ type GroupEvent struct {
 }
 
 type GroupEvents struct {
-	Period string       `json:"period"`
+	Period time.Time    `json:"period"`
 	Events []GroupEvent `json:"events"`
 }
 
@@ -89,7 +91,7 @@ type ResponseGroupEvents struct {
 }
 
 type OverTime struct {
-	Period   string       `json:"period"`
+	Period   time.Time    `json:"period"`
 	Visitors int          `json:"visitors"`
 	Events   []GroupEvent `json:"events"`
 }
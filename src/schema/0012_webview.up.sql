@@ -0,0 +1,3 @@
+ALTER TABLE "page_view" ADD COLUMN "webview" Int8 DEFAULT 0;
+ALTER TABLE "session" ADD COLUMN "webview" Int8 DEFAULT 0;
+ALTER TABLE "event" ADD COLUMN "webview" Int8 DEFAULT 0;
@@ -0,0 +1,3 @@
+ALTER TABLE "page_view" ADD COLUMN "device_type" LowCardinality(String) DEFAULT '';
+ALTER TABLE "session" ADD COLUMN "device_type" LowCardinality(String) DEFAULT '';
+ALTER TABLE "event" ADD COLUMN "device_type" LowCardinality(String) DEFAULT '';
@@ -0,0 +1,6 @@
+ALTER TABLE "page_view" ADD COLUMN "device_vendor" LowCardinality(String) DEFAULT '';
+ALTER TABLE "page_view" ADD COLUMN "device_model" LowCardinality(String) DEFAULT '';
+ALTER TABLE "session" ADD COLUMN "device_vendor" LowCardinality(String) DEFAULT '';
+ALTER TABLE "session" ADD COLUMN "device_model" LowCardinality(String) DEFAULT '';
+ALTER TABLE "event" ADD COLUMN "device_vendor" LowCardinality(String) DEFAULT '';
+ALTER TABLE "event" ADD COLUMN "device_model" LowCardinality(String) DEFAULT '';
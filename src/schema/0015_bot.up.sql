@@ -0,0 +1,17 @@
+ALTER TABLE "page_view" ADD COLUMN "bot_reason" LowCardinality(String) DEFAULT '';
+ALTER TABLE "session" ADD COLUMN "bot_reason" LowCardinality(String) DEFAULT '';
+ALTER TABLE "event" ADD COLUMN "bot_reason" LowCardinality(String) DEFAULT '';
+
+CREATE TABLE "bot" (
+    client_id UInt64,
+    visitor_id UInt64,
+    time DateTime('UTC'),
+    bot_reason LowCardinality(String),
+    user_agent String,
+    country_code LowCardinality(FixedString(2)),
+    path String
+) ENGINE = MergeTree()
+PARTITION BY toYYYYMM(time)
+ORDER BY (client_id, time)
+TTL time + INTERVAL 3 MONTH
+;
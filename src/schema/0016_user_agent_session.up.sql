@@ -0,0 +1,3 @@
+ALTER TABLE "user_agent" ADD COLUMN "client_id" UInt64 DEFAULT 0;
+ALTER TABLE "user_agent" ADD COLUMN "visitor_id" UInt64 DEFAULT 0;
+ALTER TABLE "user_agent" ADD COLUMN "session_id" UInt32 DEFAULT 0;
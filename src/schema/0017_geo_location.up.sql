@@ -0,0 +1,21 @@
+ALTER TABLE "page_view" ADD COLUMN "region" LowCardinality(String) DEFAULT '';
+ALTER TABLE "page_view" ADD COLUMN "postal_code" LowCardinality(String) DEFAULT '';
+ALTER TABLE "page_view" ADD COLUMN "latitude" Float64 DEFAULT 0;
+ALTER TABLE "page_view" ADD COLUMN "longitude" Float64 DEFAULT 0;
+ALTER TABLE "page_view" ADD COLUMN "time_zone" LowCardinality(String) DEFAULT '';
+ALTER TABLE "page_view" ADD COLUMN "asn" UInt32 DEFAULT 0;
+ALTER TABLE "page_view" ADD COLUMN "as_organization" LowCardinality(String) DEFAULT '';
+ALTER TABLE "session" ADD COLUMN "region" LowCardinality(String) DEFAULT '';
+ALTER TABLE "session" ADD COLUMN "postal_code" LowCardinality(String) DEFAULT '';
+ALTER TABLE "session" ADD COLUMN "latitude" Float64 DEFAULT 0;
+ALTER TABLE "session" ADD COLUMN "longitude" Float64 DEFAULT 0;
+ALTER TABLE "session" ADD COLUMN "time_zone" LowCardinality(String) DEFAULT '';
+ALTER TABLE "session" ADD COLUMN "asn" UInt32 DEFAULT 0;
+ALTER TABLE "session" ADD COLUMN "as_organization" LowCardinality(String) DEFAULT '';
+ALTER TABLE "event" ADD COLUMN "region" LowCardinality(String) DEFAULT '';
+ALTER TABLE "event" ADD COLUMN "postal_code" LowCardinality(String) DEFAULT '';
+ALTER TABLE "event" ADD COLUMN "latitude" Float64 DEFAULT 0;
+ALTER TABLE "event" ADD COLUMN "longitude" Float64 DEFAULT 0;
+ALTER TABLE "event" ADD COLUMN "time_zone" LowCardinality(String) DEFAULT '';
+ALTER TABLE "event" ADD COLUMN "asn" UInt32 DEFAULT 0;
+ALTER TABLE "event" ADD COLUMN "as_organization" LowCardinality(String) DEFAULT '';
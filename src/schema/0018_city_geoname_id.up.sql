@@ -0,0 +1,3 @@
+ALTER TABLE "page_view" ADD COLUMN "city_geoname_id" UInt32 DEFAULT 0;
+ALTER TABLE "session" ADD COLUMN "city_geoname_id" UInt32 DEFAULT 0;
+ALTER TABLE "event" ADD COLUMN "city_geoname_id" UInt32 DEFAULT 0;
@@ -0,0 +1,3 @@
+ALTER TABLE "page_view" ADD COLUMN "channel" LowCardinality(String) DEFAULT '';
+ALTER TABLE "session" ADD COLUMN "channel" LowCardinality(String) DEFAULT '';
+ALTER TABLE "event" ADD COLUMN "channel" LowCardinality(String) DEFAULT '';
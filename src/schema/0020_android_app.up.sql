@@ -0,0 +1,8 @@
+CREATE TABLE "android_app" (
+    package_name String,
+    name String,
+    icon String,
+    time DateTime('UTC')
+) ENGINE = ReplacingMergeTree(time)
+ORDER BY package_name
+;
@@ -0,0 +1,2 @@
+ALTER TABLE "page_view" ADD COLUMN "search_term" String DEFAULT '';
+ALTER TABLE "event" ADD COLUMN "search_term" String DEFAULT '';
@@ -0,0 +1,10 @@
+CREATE TABLE "saved_segment" (
+    client_id UInt64,
+    id UInt64,
+    name String,
+    filter String,
+    deleted Int8,
+    time DateTime64(3, 'UTC')
+) ENGINE = ReplacingMergeTree(time)
+ORDER BY (client_id, id)
+;
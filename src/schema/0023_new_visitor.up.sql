@@ -0,0 +1,3 @@
+ALTER TABLE "page_view" ADD COLUMN "new_visitor" Int8 DEFAULT 0;
+ALTER TABLE "session" ADD COLUMN "new_visitor" Int8 DEFAULT 0;
+ALTER TABLE "event" ADD COLUMN "new_visitor" Int8 DEFAULT 0;
@@ -0,0 +1,226 @@
+package omisocial
+
+import (
+	"encoding/json"
+	"errors"
+	"math/rand"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// maxSegmentID is the upper bound for generated segment IDs.
+// IDs are returned as JSON numbers and must be exactly representable in JavaScript (Number.MAX_SAFE_INTEGER).
+const maxSegmentID = 1<<53 - 1
+
+var (
+	// ErrNoSegmentName is returned in case a segment is saved without a name.
+	ErrNoSegmentName = errors.New("no segment name specified")
+
+	// ErrSegmentNotFound is returned in case a saved segment does not exist for the client.
+	ErrSegmentNotFound = errors.New("segment not found")
+)
+
+// SegmentFilter is the JSON encoded Filter of a SavedSegment.
+// Only the fields used to filter results are stored, the client, period, comparison, and result options are left out.
+type SegmentFilter string
+
+// NewSegmentFilter returns the SegmentFilter for given Filter.
+func NewSegmentFilter(filter *Filter) (SegmentFilter, error) {
+	segmentFilter := *filter
+	segmentFilter.ClientID = 0
+	segmentFilter.Timezone = nil
+	segmentFilter.From = time.Time{}
+	segmentFilter.To = time.Time{}
+	segmentFilter.Day = time.Time{}
+	segmentFilter.Start = time.Time{}
+	segmentFilter.Compare = ""
+	segmentFilter.CompareFrom = time.Time{}
+	segmentFilter.CompareTo = time.Time{}
+	segmentFilter.Locale = ""
+	segmentFilter.Limit = 0
+	segmentFilter.Offset = 0
+	segmentFilter.Other = false
+	segmentFilter.IncludeTitle = false
+	segmentFilter.IncludeTimeOnPage = false
+	segmentFilter.MaxTimeOnPageSeconds = 0
+	out, err := json.Marshal(&segmentFilter)
+
+	if err != nil {
+		return "", err
+	}
+
+	return SegmentFilter(out), nil
+}
+
+// Filter returns the decoded Filter.
+func (segmentFilter SegmentFilter) Filter() (*Filter, error) {
+	filter := new(Filter)
+
+	if segmentFilter == "" {
+		return filter, nil
+	}
+
+	if err := json.Unmarshal([]byte(segmentFilter), filter); err != nil {
+		return nil, err
+	}
+
+	return filter, nil
+}
+
+// MarshalJSON implements the json.Marshaler interface to embed the Filter as an object.
+func (segmentFilter SegmentFilter) MarshalJSON() ([]byte, error) {
+	if segmentFilter == "" {
+		return []byte("null"), nil
+	}
+
+	return []byte(segmentFilter), nil
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface to read the Filter from an object.
+func (segmentFilter *SegmentFilter) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		*segmentFilter = ""
+		return nil
+	}
+
+	filter := new(Filter)
+
+	if err := json.Unmarshal(data, filter); err != nil {
+		return err
+	}
+
+	f, err := NewSegmentFilter(filter)
+
+	if err != nil {
+		return err
+	}
+
+	*segmentFilter = f
+	return nil
+}
+
+// Apply adds the saved filter to given Filter.
+// Fields already set on the Filter take precedence, conditions and segments are added to the existing ones.
+func (segment *SavedSegment) Apply(filter *Filter) error {
+	saved, err := segment.Filter.Filter()
+
+	if err != nil {
+		return err
+	}
+
+	from := reflect.ValueOf(saved).Elem()
+	to := reflect.ValueOf(filter).Elem()
+
+	for i := 0; i < from.NumField(); i++ {
+		if to.Field(i).CanSet() && from.Field(i).Kind() == reflect.String && to.Field(i).String() == "" {
+			to.Field(i).SetString(from.Field(i).String())
+		}
+	}
+
+	filter.Conditions = append(filter.Conditions, saved.Conditions...)
+	filter.Segments = append(filter.Segments, saved.Segments...)
+	return nil
+}
+
+// Segments saves, lists, and deletes the saved segments of clients.
+type Segments struct {
+	store Store
+}
+
+// NewSegments returns a new Segments for given Store.
+func NewSegments(store Store) *Segments {
+	return &Segments{
+		store: store,
+	}
+}
+
+// List returns the saved segments for given client ordered by name.
+func (segments *Segments) List(clientID int64) ([]SavedSegment, error) {
+	var list []SavedSegment
+
+	if err := segments.store.Select(&list, segments.query("client_id = ? "), clientID); err != nil {
+		return nil, err
+	}
+
+	return list, nil
+}
+
+// Get returns the saved segment for given client and ID.
+// ErrSegmentNotFound is returned in case it doesn't exist.
+func (segments *Segments) Get(clientID int64, id uint64) (*SavedSegment, error) {
+	var list []SavedSegment
+
+	if err := segments.store.Select(&list, segments.query("client_id = ? AND id = ? "), clientID, id); err != nil {
+		return nil, err
+	}
+
+	if len(list) == 0 {
+		return nil, ErrSegmentNotFound
+	}
+
+	return &list[0], nil
+}
+
+// Save creates a new saved segment if the ID is zero, or updates the existing one otherwise.
+// The ID and time are set on given segment.
+func (segments *Segments) Save(segment *SavedSegment) error {
+	segment.Name = strings.TrimSpace(segment.Name)
+
+	if segment.Name == "" {
+		return ErrNoSegmentName
+	}
+
+	if _, err := segment.Filter.Filter(); err != nil {
+		return err
+	}
+
+	if segment.ID == 0 {
+		segment.ID = uint64(rand.Int63n(maxSegmentID)) + 1
+	} else if _, err := segments.Get(segment.ClientID, segment.ID); err != nil {
+		return err
+	}
+
+	segment.Deleted = false
+	segment.Time = time.Now().UTC()
+	return segments.store.SaveSegments([]SavedSegment{*segment})
+}
+
+// Delete deletes the saved segment for given client and ID.
+// ErrSegmentNotFound is returned in case it doesn't exist.
+func (segments *Segments) Delete(clientID int64, id uint64) error {
+	segment, err := segments.Get(clientID, id)
+
+	if err != nil {
+		return err
+	}
+
+	segment.Deleted = true
+	segment.Time = time.Now().UTC()
+	return segments.store.SaveSegments([]SavedSegment{*segment})
+}
+
+// Apply adds the saved segment for given client and ID to the filter.
+// ErrSegmentNotFound is returned in case it doesn't exist.
+func (segments *Segments) Apply(filter *Filter, id uint64) error {
+	segment, err := segments.Get(filter.ClientID, id)
+
+	if err != nil {
+		return err
+	}
+
+	return segment.Apply(filter)
+}
+
+func (segments *Segments) query(where string) string {
+	return `SELECT client_id, id, name, filter, deleted, time
+		FROM (
+			SELECT client_id, id, name, filter, deleted, time
+			FROM saved_segment
+			WHERE ` + where + `
+			ORDER BY time DESC
+			LIMIT 1 BY client_id, id
+		)
+		WHERE deleted = 0
+		ORDER BY name, id`
+}
@@ -0,0 +1,112 @@
+package omisocial
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestSegments(t *testing.T) {
+	cleanupDB()
+	segments := NewSegments(dbClient)
+	filter, err := NewSegmentFilter(&Filter{Country: "vn", Segments: []Segment{{EventName: "purchase"}}})
+	assert.NoError(t, err)
+	segment := &SavedSegment{ClientID: 1, Name: "Buyers", Filter: filter}
+	assert.NoError(t, segments.Save(segment))
+	assert.NotZero(t, segment.ID)
+	assert.NoError(t, segments.Save(&SavedSegment{ClientID: 1, Name: "All", Filter: filter}))
+	assert.NoError(t, segments.Save(&SavedSegment{ClientID: 2, Name: "Other client", Filter: filter}))
+	time.Sleep(time.Millisecond * 20)
+	list, err := segments.List(1)
+	assert.NoError(t, err)
+	assert.Len(t, list, 2)
+	assert.Equal(t, "All", list[0].Name)
+	assert.Equal(t, "Buyers", list[1].Name)
+	segment.Name = "Customers"
+	assert.NoError(t, segments.Save(segment))
+	time.Sleep(time.Millisecond * 20)
+	found, err := segments.Get(1, segment.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "Customers", found.Name)
+	assert.Equal(t, filter, found.Filter)
+	_, err = segments.Get(2, segment.ID)
+	assert.Equal(t, ErrSegmentNotFound, err)
+	assert.NoError(t, segments.Delete(1, segment.ID))
+	time.Sleep(time.Millisecond * 20)
+	_, err = segments.Get(1, segment.ID)
+	assert.Equal(t, ErrSegmentNotFound, err)
+	assert.Equal(t, ErrSegmentNotFound, segments.Delete(1, segment.ID))
+	list, err = segments.List(1)
+	assert.NoError(t, err)
+	assert.Len(t, list, 1)
+}
+
+func TestSegments_Save(t *testing.T) {
+	client := NewMockClient()
+	segments := NewSegments(client)
+	assert.Equal(t, ErrNoSegmentName, segments.Save(&SavedSegment{ClientID: 1, Name: " "}))
+	assert.Error(t, segments.Save(&SavedSegment{ClientID: 1, Name: "Invalid", Filter: "{"}))
+	assert.Equal(t, ErrSegmentNotFound, segments.Save(&SavedSegment{ClientID: 1, ID: 42, Name: "Unknown"}))
+	segment := &SavedSegment{ClientID: 1, Name: " Buyers "}
+	assert.NoError(t, segments.Save(segment))
+	assert.Len(t, client.Segments, 1)
+	assert.Equal(t, "Buyers", client.Segments[0].Name)
+	assert.NotZero(t, client.Segments[0].ID)
+	assert.LessOrEqual(t, client.Segments[0].ID, uint64(maxSegmentID))
+	assert.False(t, client.Segments[0].Time.IsZero())
+}
+
+func TestSegmentFilter(t *testing.T) {
+	filter, err := NewSegmentFilter(&Filter{
+		ClientID:   42,
+		From:       pastDay(7),
+		To:         Today(),
+		Limit:      10,
+		Country:    "vn",
+		Conditions: []Condition{{Field: "utm_source", Operator: FilterIn, Values: []string{"facebook", "zalo"}}},
+	})
+	assert.NoError(t, err)
+	decoded, err := filter.Filter()
+	assert.NoError(t, err)
+	assert.Zero(t, decoded.ClientID)
+	assert.True(t, decoded.From.IsZero())
+	assert.True(t, decoded.To.IsZero())
+	assert.Zero(t, decoded.Limit)
+	assert.Equal(t, "vn", decoded.Country)
+	assert.Len(t, decoded.Conditions, 1)
+	var segment SavedSegment
+	assert.NoError(t, json.Unmarshal([]byte(`{"name": "Vietnam", "filter": {"ClientID": 42, "Country": "vn"}}`), &segment))
+	decoded, err = segment.Filter.Filter()
+	assert.NoError(t, err)
+	assert.Zero(t, decoded.ClientID)
+	assert.Equal(t, "vn", decoded.Country)
+	out, err := json.Marshal(&segment)
+	assert.NoError(t, err)
+	assert.Contains(t, string(out), `"filter":{"ClientID":0,`)
+	assert.NotContains(t, string(out), "Deleted")
+}
+
+func TestSavedSegment_Apply(t *testing.T) {
+	filter, err := NewSegmentFilter(&Filter{
+		Country:    "vn",
+		Browser:    BrowserChrome,
+		Conditions: []Condition{{Field: "utm_source", Operator: FilterIn, Values: []string{"facebook"}}},
+		Segments:   []Segment{{EventName: "purchase"}},
+	})
+	assert.NoError(t, err)
+	segment := SavedSegment{Filter: filter}
+	target := &Filter{
+		ClientID:   1,
+		From:       pastDay(7),
+		Browser:    BrowserFirefox,
+		Conditions: []Condition{{Field: "title", Operator: FilterContains, Values: []string{"blog"}}},
+	}
+	assert.NoError(t, segment.Apply(target))
+	assert.Equal(t, int64(1), target.ClientID)
+	assert.Equal(t, pastDay(7), target.From)
+	assert.Equal(t, "vn", target.Country)
+	assert.Equal(t, BrowserFirefox, target.Browser)
+	assert.Len(t, target.Conditions, 2)
+	assert.Len(t, target.Segments, 1)
+}
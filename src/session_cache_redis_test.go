@@ -3,6 +3,8 @@ package omisocial
 import (
 	"github.com/go-redis/redis/v8"
 	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 	"time"
 )
@@ -26,3 +28,28 @@ func TestSessionCacheRedis(t *testing.T) {
 	session = cache.Get(1, 1, time.Time{})
 	assert.Nil(t, session)
 }
+
+func TestSessionCacheRedisNewVisitor(t *testing.T) {
+	sessionCache := NewSessionCacheRedis(time.Second, nil, &redis.Options{
+		Addr: "localhost:6379",
+	})
+	sessionCache.Clear()
+	store := NewMockClient()
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/84.0.4147.135 Safari/537.36")
+	_, sessionState, _ := HitFromRequest(req, "salt", &HitOptions{
+		SessionCache: sessionCache,
+		store:        store,
+	})
+	assert.True(t, sessionState.State.NewVisitor)
+
+	// the session has expired from the cache, but is still in the database
+	time.Sleep(time.Second * 2)
+	store.ReturnSession = &sessionState.State
+	_, sessionState, _ = HitFromRequest(req, "salt", &HitOptions{
+		SessionCache: sessionCache,
+		store:        store,
+	})
+	assert.Nil(t, sessionState.Cancel)
+	assert.False(t, sessionState.State.NewVisitor)
+}
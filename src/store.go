@@ -18,9 +18,24 @@ type Store interface {
 	// SaveUserAgents saves given UserAgent headers.
 	SaveUserAgents([]UserAgent) error
 
+	// SaveBotHits saves given requests filtered as bot traffic.
+	SaveBotHits([]BotHit) error
+
+	// SaveAndroidApps saves given resolved Android apps.
+	SaveAndroidApps([]AndroidApp) error
+
+	// SaveSegments saves given saved segments.
+	SaveSegments([]SavedSegment) error
+
+	// UpdateReferrerName sets the referrer name and icon for all sessions, page views, and events with one of given referrers and no referrer name.
+	UpdateReferrerName([]string, string, string) error
+
 	// Session returns the last hit for given client, fingerprint, and maximum age.
 	Session(uint64, uint64, time.Time) (*Session, error)
 
+	// HasSession returns whether there is a session for given client, fingerprint, and maximum age.
+	HasSession(uint64, uint64, time.Time) (bool, error)
+
 	// Count returns the number of results for given query.
 	Count(string, ...interface{}) (int, error)
 
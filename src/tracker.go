@@ -53,9 +53,24 @@ type TrackerConfig struct {
 	// SessionMaxAge see HitOptions.SessionMaxAge.
 	SessionMaxAge time.Duration
 
-	// GeoDB enables/disabled mapping IPs to country codes.
+	// ReturningVisitorMaxAge see HitOptions.ReturningVisitorMaxAge.
+	ReturningVisitorMaxAge time.Duration
+
+	// GeoDB enables/disabled mapping IPs to their location (see GeoDB and IP2LocationDB).
 	// Can be set/updated at runtime by calling Tracker.SetGeoDB.
-	GeoDB *GeoDB
+	GeoDB GeoResolver
+
+	// BotDetector enables the behavioural bot detection.
+	// Requests considered to be bots are flagged or dropped and stored in the bot table. Set it to nil to disable the feature.
+	BotDetector *BotDetector
+
+	// SearchQueryParams maps client IDs to the query parameters containing site search terms (see HitOptions.SearchQueryParams).
+	// The parameters for client ID 0 are used for all clients not in the map.
+	SearchQueryParams map[uint64][]string
+
+	// AndroidAppResolver enables resolving android-app:// referrers to the app name and icon.
+	// Apps not covered by the ReferrerSource mapping are looked up in the background. Set it to nil to disable the feature.
+	AndroidAppResolver *AndroidAppResolver
 
 	// Logger is the log.Logger used for logging.
 	// The default log will be used printing to os.Stdout with "pirsch" in its prefix in case it is not set.
@@ -98,6 +113,7 @@ type Tracker struct {
 	sessions                                  chan SessionState
 	events                                    chan Event
 	userAgents                                chan UserAgent
+	botHits                                   chan BotHit
 	stopped                                   int32
 	worker                                    int
 	workerBufferSize                          int
@@ -107,8 +123,12 @@ type Tracker struct {
 	referrerDomainBlacklist                   []string
 	referrerDomainBlacklistIncludesSubdomains bool
 	sessionMaxAge                             time.Duration
-	geoDB                                     *GeoDB
+	returningVisitorMaxAge                    time.Duration
+	geoDB                                     GeoResolver
 	geoDBMutex                                sync.RWMutex
+	botDetector                               *BotDetector
+	androidApps                               *AndroidAppResolver
+	searchQueryParams                         map[uint64][]string
 	logger                                    *log.Logger
 }
 
@@ -134,15 +154,20 @@ func NewTracker(client Store, salt string, config *TrackerConfig) *Tracker {
 		sessions:                make(chan SessionState, config.Worker*config.WorkerBufferSize),
 		events:                  make(chan Event, config.Worker*config.WorkerBufferSize),
 		userAgents:              make(chan UserAgent, config.Worker*config.WorkerBufferSize),
+		botHits:                 make(chan BotHit, config.Worker*config.WorkerBufferSize),
 		worker:                  config.Worker,
 		workerBufferSize:        config.WorkerBufferSize,
 		workerTimeout:           config.WorkerTimeout,
 		workerDone:              make(chan bool),
 		referrerDomainBlacklist: config.ReferrerDomainBlacklist,
 		referrerDomainBlacklistIncludesSubdomains: config.ReferrerDomainBlacklistIncludesSubdomains,
-		sessionMaxAge: config.SessionMaxAge,
-		geoDB:         config.GeoDB,
-		logger:        config.Logger,
+		sessionMaxAge:          config.SessionMaxAge,
+		returningVisitorMaxAge: config.ReturningVisitorMaxAge,
+		geoDB:                  geoResolver(config.GeoDB),
+		botDetector:            config.BotDetector,
+		androidApps:            config.AndroidAppResolver,
+		searchQueryParams:      config.SearchQueryParams,
+		logger:                 config.Logger,
 	}
 	tracker.startWorker()
 	return tracker
@@ -156,7 +181,9 @@ func (tracker *Tracker) Hit(r *http.Request, options *HitOptions) {
 		return
 	}
 
-	if !IgnoreHit(r) {
+	if IgnoreHit(r) {
+		tracker.ignoredBotHit(r, options)
+	} else {
 		if options == nil {
 			options = &HitOptions{
 				ReferrerDomainBlacklist:                   tracker.referrerDomainBlacklist,
@@ -171,16 +198,25 @@ func (tracker *Tracker) Hit(r *http.Request, options *HitOptions) {
 			tracker.geoDBMutex.RUnlock()
 		}
 
+		options.androidApps = tracker.androidApps
+		tracker.setSearchQueryParams(options)
 		options.SessionCache = tracker.sessionCache
-		pageView, sessionState, ua := HitFromRequest(r, tracker.salt, options)
-		if pageView != nil {
-			tracker.pageViews <- *pageView
-			tracker.sessions <- sessionState
+		options.store = tracker.store
+
+		if options.ReturningVisitorMaxAge == 0 {
+			options.ReturningVisitorMaxAge = tracker.returningVisitorMaxAge
 		}
 
+		pageView, sessionState, ua := HitFromRequest(r, tracker.salt, options)
+
 		if ua != nil {
 			tracker.userAgents <- *ua
 		}
+
+		if pageView != nil && tracker.keepBotHit(r, options, pageView, &sessionState) {
+			tracker.pageViews <- *pageView
+			tracker.sessions <- sessionState
+		}
 	}
 }
 
@@ -206,11 +242,19 @@ func (tracker *Tracker) Event(r *http.Request, eventOptions EventOptions, option
 			tracker.geoDBMutex.RUnlock()
 		}
 
+		options.androidApps = tracker.androidApps
+		tracker.setSearchQueryParams(options)
 		options.SessionCache = tracker.sessionCache
+		options.store = tracker.store
+
+		if options.ReturningVisitorMaxAge == 0 {
+			options.ReturningVisitorMaxAge = tracker.returningVisitorMaxAge
+		}
+
 		metaKeys, metaValues := eventOptions.getMetaData()
-		pageView, _, _ := HitFromRequest(r, tracker.salt, options)
+		pageView, sessionState, _ := HitFromRequest(r, tracker.salt, options)
 
-		if pageView != nil {
+		if pageView != nil && tracker.keepBotEvent(r, options, pageView, &sessionState) {
 			tracker.events <- Event{
 				ClientID:        pageView.ClientID,
 				VisitorID:       pageView.VisitorID,
@@ -225,15 +269,30 @@ func (tracker *Tracker) Event(r *http.Request, eventOptions EventOptions, option
 				Language:        pageView.Language,
 				CountryCode:     pageView.CountryCode,
 				City:            pageView.City,
+				CityGeoNameID:   pageView.CityGeoNameID,
+				Region:          pageView.Region,
+				PostalCode:      pageView.PostalCode,
+				Latitude:        pageView.Latitude,
+				Longitude:       pageView.Longitude,
+				TimeZone:        pageView.TimeZone,
+				ASN:             pageView.ASN,
+				ASOrganization:  pageView.ASOrganization,
 				Referrer:        pageView.Referrer,
 				ReferrerName:    pageView.ReferrerName,
 				ReferrerIcon:    pageView.ReferrerIcon,
+				Channel:         pageView.Channel,
 				OS:              pageView.OS,
 				OSVersion:       pageView.OSVersion,
 				Browser:         pageView.Browser,
 				BrowserVersion:  pageView.BrowserVersion,
 				Desktop:         pageView.Desktop,
 				Mobile:          pageView.Mobile,
+				WebView:         pageView.WebView,
+				NewVisitor:      pageView.NewVisitor,
+				DeviceType:      pageView.DeviceType,
+				DeviceVendor:    pageView.DeviceVendor,
+				DeviceModel:     pageView.DeviceModel,
+				BotReason:       pageView.BotReason,
 				ScreenWidth:     pageView.ScreenWidth,
 				ScreenHeight:    pageView.ScreenHeight,
 				ScreenClass:     pageView.ScreenClass,
@@ -246,6 +305,7 @@ func (tracker *Tracker) Event(r *http.Request, eventOptions EventOptions, option
 				OTMMedium:       pageView.OTMMedium,
 				OTMCampaign:     pageView.OTMCampaign,
 				OTMPosition:     pageView.OTMPosition,
+				SearchTerm:      pageView.SearchTerm,
 			}
 		}
 	}
@@ -277,16 +337,135 @@ func (tracker *Tracker) Stop() {
 		tracker.flushSessions()
 		tracker.flushEvents()
 		tracker.flushUserAgents()
+		tracker.flushBotHits()
 	}
 }
 
-// SetGeoDB sets the GeoDB for the Tracker.
+// SetGeoDB sets the GeoResolver (like GeoDB or IP2LocationDB) for the Tracker.
 // The call to this function is thread safe to enable live updates of the database.
 // Pass nil to disable the feature.
-func (tracker *Tracker) SetGeoDB(geoDB *GeoDB) {
+func (tracker *Tracker) SetGeoDB(geoDB GeoResolver) {
 	tracker.geoDBMutex.Lock()
 	defer tracker.geoDBMutex.Unlock()
-	tracker.geoDB = geoDB
+	tracker.geoDB = geoResolver(geoDB)
+}
+
+// setSearchQueryParams sets the site search query parameters for the client in case they haven't been set in the options.
+func (tracker *Tracker) setSearchQueryParams(options *HitOptions) {
+	if options.SearchQueryParams == nil && tracker.searchQueryParams != nil {
+		if params, found := tracker.searchQueryParams[options.ClientID]; found {
+			options.SearchQueryParams = params
+		} else {
+			options.SearchQueryParams = tracker.searchQueryParams[0]
+		}
+	}
+}
+
+// keepBotHit checks the page view for bot traffic and returns false if it should be dropped.
+// Sessions flagged as bot traffic keep their bot reason and the DeviceBot device type for all following page views.
+func (tracker *Tracker) keepBotHit(r *http.Request, options *HitOptions, pageView *PageView, sessionState *SessionState) bool {
+	if tracker.botDetector == nil {
+		return true
+	}
+
+	if pageView.BotReason == "" {
+		reason := tracker.botDetector.Check(r, pageView.VisitorID, options.ScreenWidth, options.ScreenHeight, true)
+
+		if reason == "" {
+			return true
+		}
+
+		pageView.BotReason = reason
+		pageView.DeviceType = DeviceBot
+		sessionState.State.BotReason = reason
+		sessionState.State.DeviceType = DeviceBot
+		state := sessionState.State
+		tracker.sessionCache.Put(pageView.ClientID, pageView.VisitorID, &state)
+	}
+
+	tracker.botHits <- botHitFromPageView(r, pageView)
+
+	if !tracker.botDetector.Drop() {
+		return true
+	}
+
+	// remove the session if it has been stored before it was considered a bot
+	if sessionState.Cancel != nil && sessionState.Cancel.BotReason == "" {
+		tracker.sessions <- SessionState{State: *sessionState.Cancel}
+	}
+
+	return false
+}
+
+// keepBotEvent checks the event for bot traffic and returns false if it should be dropped.
+// The bot reason is set on the page view used to create the event.
+func (tracker *Tracker) keepBotEvent(r *http.Request, options *HitOptions, pageView *PageView, sessionState *SessionState) bool {
+	if tracker.botDetector == nil {
+		return true
+	}
+
+	if pageView.BotReason == "" {
+		pageView.BotReason = tracker.botDetector.Check(r, pageView.VisitorID, options.ScreenWidth, options.ScreenHeight, false)
+
+		if pageView.BotReason == "" {
+			return true
+		}
+
+		pageView.DeviceType = DeviceBot
+		state := sessionState.State
+		state.BotReason = pageView.BotReason
+		state.DeviceType = DeviceBot
+		tracker.sessionCache.Put(pageView.ClientID, pageView.VisitorID, &state)
+	}
+
+	tracker.botHits <- botHitFromPageView(r, pageView)
+	return !tracker.botDetector.Drop()
+}
+
+// ignoredBotHit stores requests ignored because of a blacklisted User-Agent header as bot traffic.
+func (tracker *Tracker) ignoredBotHit(r *http.Request, options *HitOptions) {
+	if tracker.botDetector == nil || !isBotUserAgent(r.UserAgent()) {
+		return
+	}
+
+	salt := tracker.salt
+	hit := BotHit{
+		Time:      time.Now().UTC(),
+		Reason:    BotReasonUserAgent,
+		UserAgent: r.UserAgent(),
+		Path:      r.URL.Path,
+	}
+
+	if options != nil {
+		salt += options.Salt
+		hit.ClientID = options.ClientID
+
+		if options.Path != "" {
+			hit.Path = options.Path
+		}
+	}
+
+	hit.VisitorID = Fingerprint(r, salt)
+	tracker.geoDBMutex.RLock()
+
+	if tracker.geoDB != nil {
+		hit.CountryCode = tracker.geoDB.Resolve(getIP(r)).CountryCode
+	}
+
+	tracker.geoDBMutex.RUnlock()
+	tracker.botHits <- hit
+}
+
+func botHitFromPageView(r *http.Request, pageView *PageView) BotHit {
+	return BotHit{
+		ClientID:    pageView.ClientID,
+		VisitorID:   pageView.VisitorID,
+		Time:        pageView.Time,
+		Reason:      pageView.BotReason,
+		UserAgent:   r.UserAgent(),
+		CountryCode: pageView.CountryCode,
+		Path:        pageView.Path,
+	}
 }
 
 // ClearSessionCache clears the session cache.
@@ -303,13 +482,14 @@ func (tracker *Tracker) startWorker() {
 		go tracker.aggregateSessions(ctx)
 		go tracker.aggregateEvents(ctx)
 		go tracker.aggregateUserAgents(ctx)
+		go tracker.aggregateBotHits(ctx)
 	}
 }
 
 func (tracker *Tracker) stopWorker() {
 	tracker.workerCancel()
 
-	for i := 0; i < tracker.worker*4; i++ {
+	for i := 0; i < tracker.worker*5; i++ {
 		<-tracker.workerDone
 	}
 }
@@ -565,3 +745,64 @@ func (tracker *Tracker) saveUserAgents(userAgents []UserAgent) {
 		}
 	}
 }
+
+func (tracker *Tracker) flushBotHits() {
+	hits := make([]BotHit, 0, tracker.workerBufferSize)
+
+	for {
+		stop := false
+
+		select {
+		case hit := <-tracker.botHits:
+			hits = append(hits, hit)
+
+			if len(hits) == tracker.workerBufferSize {
+				tracker.saveBotHits(hits)
+				hits = hits[:0]
+			}
+		default:
+			stop = true
+		}
+
+		if stop {
+			break
+		}
+	}
+
+	tracker.saveBotHits(hits)
+}
+
+func (tracker *Tracker) aggregateBotHits(ctx context.Context) {
+	hits := make([]BotHit, 0, tracker.workerBufferSize)
+	timer := time.NewTimer(tracker.workerTimeout)
+	defer timer.Stop()
+
+	for {
+		timer.Reset(tracker.workerTimeout)
+
+		select {
+		case hit := <-tracker.botHits:
+			hits = append(hits, hit)
+
+			if len(hits) == tracker.workerBufferSize {
+				tracker.saveBotHits(hits)
+				hits = hits[:0]
+			}
+		case <-timer.C:
+			tracker.saveBotHits(hits)
+			hits = hits[:0]
+		case <-ctx.Done():
+			tracker.saveBotHits(hits)
+			tracker.workerDone <- true
+			return
+		}
+	}
+}
+
+func (tracker *Tracker) saveBotHits(hits []BotHit) {
+	if len(hits) > 0 {
+		if err := tracker.store.SaveBotHits(hits); err != nil {
+			tracker.logger.Printf("error saving bot hits: %s", err)
+		}
+	}
+}
@@ -146,6 +146,25 @@ func TestTracker_HitCountryCode(t *testing.T) {
 	assert.True(t, foundEmpty)
 }
 
+func TestTracker_HitGeoDBNil(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Add("User-Agent", "Mozilla/5.0 (X11; Linux x86_64; rv:89.0) Gecko/20100101 Firefox/89.0")
+	req.RemoteAddr = "81.2.69.142"
+	var geoDB *GeoDB
+	client := NewMockClient()
+	tracker := NewTracker(client, "salt", &TrackerConfig{
+		WorkerTimeout: time.Second,
+		GeoDB:         geoDB,
+	})
+	tracker.Hit(req, nil)
+	var ip2Location *IP2LocationDB
+	tracker.SetGeoDB(ip2Location)
+	tracker.Hit(req, nil)
+	tracker.Stop()
+	assert.Len(t, client.PageViews, 2)
+	assert.Empty(t, client.PageViews[0].CountryCode)
+}
+
 func TestTracker_HitSession(t *testing.T) {
 	req1 := httptest.NewRequest(http.MethodGet, "/", nil)
 	req1.Header.Add("User-Agent", "Mozilla/5.0 (X11; Linux x86_64; rv:89.0) Gecko/20100101 Firefox/89.0")
@@ -183,6 +202,33 @@ func TestTracker_HitTitle(t *testing.T) {
 	assert.Equal(t, "title", client.PageViews[0].Title)
 }
 
+func TestTracker_HitSearchTerm(t *testing.T) {
+	client := NewMockClient()
+	tracker := NewTracker(client, "salt", &TrackerConfig{
+		WorkerTimeout: time.Second,
+		SearchQueryParams: map[uint64][]string{
+			0: {"q"},
+			2: {"keyword"},
+		},
+	})
+	req := httptest.NewRequest(http.MethodGet, "/search?q=running+shoes&keyword=ignored", nil)
+	req.Header.Add("User-Agent", "Mozilla/5.0 (X11; Linux x86_64; rv:89.0) Gecko/20100101 Firefox/89.0")
+	tracker.Hit(req, &HitOptions{ClientID: 1})
+	req = httptest.NewRequest(http.MethodGet, "/search?q=ignored&keyword=Sneakers", nil)
+	req.Header.Add("User-Agent", "Mozilla/5.0 (X11; Linux x86_64; rv:89.0) Gecko/20100101 Firefox/89.0")
+	tracker.Hit(req, &HitOptions{ClientID: 2})
+	req = httptest.NewRequest(http.MethodGet, "/search?search=dress", nil)
+	req.Header.Add("User-Agent", "Mozilla/5.0 (X11; Linux x86_64; rv:89.0) Gecko/20100101 Firefox/89.0")
+	tracker.Event(req, EventOptions{Name: "event"}, &HitOptions{ClientID: 3, SearchQueryParams: []string{"search"}})
+	tracker.Stop()
+	assert.Len(t, client.PageViews, 2)
+	assert.Len(t, client.Events, 1)
+	terms := []string{client.PageViews[0].SearchTerm, client.PageViews[1].SearchTerm}
+	assert.Contains(t, terms, "running shoes")
+	assert.Contains(t, terms, "Sneakers")
+	assert.Equal(t, "dress", client.Events[0].SearchTerm)
+}
+
 func TestTracker_HitIgnoreSubdomain(t *testing.T) {
 	client := NewMockClient()
 	tracker := NewTracker(client, "salt", &TrackerConfig{
@@ -218,6 +264,68 @@ func TestTracker_HitIgnoreSubdomain(t *testing.T) {
 	}
 }
 
+func TestTracker_HitBotFlag(t *testing.T) {
+	client := NewMockClient()
+	tracker := NewTracker(client, "salt", &TrackerConfig{
+		BotDetector: NewBotDetector(&BotDetectorConfig{MaxRequestsPerMinute: 2}),
+	})
+
+	for i := 0; i < 3; i++ {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("User-Agent", "Mozilla/5.0 (X11; Linux x86_64; rv:89.0) Gecko/20100101 Firefox/89.0")
+		req.Header.Set("Accept-Language", "en")
+		tracker.Hit(req, &HitOptions{ScreenWidth: 1920, ScreenHeight: 1080})
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("User-Agent", "Googlebot/2.1")
+	tracker.Hit(req, nil)
+	tracker.Stop()
+	assert.Len(t, client.PageViews, 3)
+	assert.Len(t, client.Sessions, 5)
+	assert.Empty(t, client.PageViews[1].BotReason)
+	assert.Equal(t, DeviceDesktop, client.PageViews[1].DeviceType)
+	assert.Equal(t, BotReasonRate, client.PageViews[2].BotReason)
+	assert.Equal(t, DeviceBot, client.PageViews[2].DeviceType)
+
+	for _, session := range client.Sessions {
+		if session.BotReason != "" {
+			assert.Equal(t, DeviceBot, session.DeviceType)
+		}
+	}
+
+	assert.Len(t, client.BotHits, 2)
+	assert.Equal(t, BotReasonRate, client.BotHits[0].Reason)
+	assert.Equal(t, BotReasonUserAgent, client.BotHits[1].Reason)
+	assert.Equal(t, "Googlebot/2.1", client.BotHits[1].UserAgent)
+}
+
+func TestTracker_HitBotDrop(t *testing.T) {
+	client := NewMockClient()
+	tracker := NewTracker(client, "salt", &TrackerConfig{
+		BotDetector: NewBotDetector(&BotDetectorConfig{MaxRequestsPerMinute: 2, Drop: true}),
+	})
+
+	for i := 0; i < 4; i++ {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("User-Agent", "Mozilla/5.0 (X11; Linux x86_64; rv:89.0) Gecko/20100101 Firefox/89.0")
+		req.Header.Set("Accept-Language", "en")
+		tracker.Hit(req, &HitOptions{ScreenWidth: 1920, ScreenHeight: 1080})
+	}
+
+	tracker.Stop()
+	assert.Len(t, client.PageViews, 2)
+	assert.Len(t, client.BotHits, 2)
+	sign := 0
+
+	for _, session := range client.Sessions {
+		sign += int(session.Sign)
+	}
+
+	// the session stored before it was considered a bot is cancelled
+	assert.Equal(t, 0, sign)
+}
+
 func TestTracker_Event(t *testing.T) {
 	req := httptest.NewRequest(http.MethodGet, "/", nil)
 	req.Header.Add("User-Agent", "Mozilla/5.0 (X11; Linux x86_64; rv:89.0) Gecko/20100101 Firefox/89.0")
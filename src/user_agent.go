@@ -25,6 +25,30 @@ const (
 	// BrowserIE represents the Internet Explorer browser.
 	BrowserIE = "IE"
 
+	// BrowserCocCoc represents the Coc Coc browser.
+	BrowserCocCoc = "Coc Coc"
+
+	// BrowserSamsung represents the Samsung Internet browser.
+	BrowserSamsung = "Samsung Internet"
+
+	// BrowserUC represents the UC Browser.
+	BrowserUC = "UC Browser"
+
+	// BrowserYandex represents the Yandex browser.
+	BrowserYandex = "Yandex"
+
+	// BrowserFacebook represents the Facebook and Messenger in-app browser.
+	BrowserFacebook = "Facebook"
+
+	// BrowserInstagram represents the Instagram in-app browser.
+	BrowserInstagram = "Instagram"
+
+	// BrowserZalo represents the Zalo in-app browser.
+	BrowserZalo = "Zalo"
+
+	// BrowserTikTok represents the TikTok in-app browser.
+	BrowserTikTok = "TikTok"
+
 	// OSWindows represents the Windows operating system.
 	OSWindows = "Windows"
 
@@ -43,6 +67,9 @@ const (
 	// OSWindowsMobile represents the Windows Mobile operating system.
 	OSWindowsMobile = "Windows Mobile"
 
+	// OSChromeOS represents the Chrome OS operating system.
+	OSChromeOS = "Chrome OS"
+
 	// used to parse the User-Agent header
 	uaSystemLeftDelimiter     = '('
 	uaSystemRightDelimiter    = ')'
@@ -52,7 +79,7 @@ const (
 )
 
 // UserAgent contains information extracted from the User-Agent header.
-// The creation time and User-Agent string are stored in the database to find bots.
+// The creation time, User-Agent string, and session are stored in the database to find bots (see Analyzer.BotCandidates).
 type UserAgent struct {
 	// Time is the creation date for the database record.
 	Time time.Time
@@ -60,6 +87,11 @@ type UserAgent struct {
 	// UserAgent is the full User-Agent for the database record.
 	UserAgent string `db:"user_agent"`
 
+	// ClientID, VisitorID, and SessionID reference the session the User-Agent has been sent for.
+	ClientID  uint64 `db:"client_id"`
+	VisitorID uint64 `db:"visitor_id"`
+	SessionID uint32 `db:"session_id"`
+
 	// Browser is the browser name.
 	Browser string `db:"-"`
 
@@ -71,16 +103,28 @@ type UserAgent struct {
 
 	// OSVersion is the operating system version number.
 	OSVersion string `db:"-"`
+
+	// Mobile is set if the browser reports a mobile device through the Sec-CH-UA-Mobile client hint.
+	Mobile bool `db:"-"`
+
+	// Vendor is the device vendor, like Samsung or Apple.
+	Vendor string `db:"-"`
+
+	// Model is the device model extracted from the User-Agent header or reported through the Sec-CH-UA-Model client hint.
+	Model string `db:"-"`
+
+	// WebView is set for in-app browsers and embedded webviews.
+	WebView bool `db:"-"`
 }
 
 // IsDesktop returns true if the user agent is a desktop device.
 func (ua *UserAgent) IsDesktop() bool {
-	return ua.OS == OSWindows || ua.OS == OSMac || ua.OS == OSLinux
+	return !ua.Mobile && (ua.OS == OSWindows || ua.OS == OSMac || ua.OS == OSLinux || ua.OS == OSChromeOS)
 }
 
 // IsMobile returns true if the user agent is a mobile device.
 func (ua *UserAgent) IsMobile() bool {
-	return ua.OS == OSAndroid || ua.OS == OSiOS || ua.OS == OSWindowsMobile
+	return ua.Mobile || ua.OS == OSAndroid || ua.OS == OSiOS || ua.OS == OSWindowsMobile
 }
 
 // ParseUserAgent parses given User-Agent header and returns the extracted information.
@@ -94,6 +138,8 @@ func ParseUserAgent(ua string) UserAgent {
 	}
 	userAgent.OS, userAgent.OSVersion = getOS(system)
 	userAgent.Browser, userAgent.BrowserVersion = getBrowser(products, system, userAgent.OS)
+	userAgent.WebView = isWebView(products, system, userAgent.Browser, userAgent.OS)
+	userAgent.Vendor, userAgent.Model = getDevice(system, userAgent.OS)
 	return userAgent
 }
 
@@ -146,6 +192,11 @@ func getBrowser(products []string, system []string, os string) (string, string)
 		return BrowserIE, v
 	}
 
+	// in-app browsers add their products to the User-Agent of the system webview
+	if browser, version := getInAppBrowser(products); browser != "" {
+		return browser, version
+	}
+
 	productChrome := ""
 	productSafari := ""
 
@@ -164,6 +215,14 @@ func getBrowser(products []string, system []string, os string) (string, string)
 			return BrowserFirefox, getProductVersion(product, 1)
 		} else if strings.HasPrefix(product, "Opera/") || strings.HasPrefix(product, "OPR/") {
 			return BrowserOpera, getProductVersion(product, 1)
+		} else if strings.HasPrefix(product, "coc_coc_browser/") {
+			return BrowserCocCoc, getProductVersion(product, 1)
+		} else if strings.HasPrefix(product, "SamsungBrowser/") {
+			return BrowserSamsung, getProductVersion(product, 1)
+		} else if strings.HasPrefix(product, "UCBrowser/") || strings.HasPrefix(product, "UCWEB/") {
+			return BrowserUC, getProductVersion(product, 1)
+		} else if strings.HasPrefix(product, "YaBrowser/") {
+			return BrowserYandex, getProductVersion(product, 1)
 		}
 	}
 
@@ -180,6 +239,68 @@ func getBrowser(products []string, system []string, os string) (string, string)
 	return browser, version
 }
 
+// getInAppBrowser returns the in-app browser and app version for given products, or else empty strings are returned.
+func getInAppBrowser(products []string) (string, string) {
+	for i, product := range products {
+		if strings.HasPrefix(product, "[FBAN/") || strings.HasPrefix(product, "[FB_IAB/") ||
+			strings.HasPrefix(product, "FBAN/") || strings.HasPrefix(product, "FB_IAB/") {
+			// [FB_IAB/FB4A;FBAV/403.0.0.27.81;]
+			meta := strings.Join(products[i:], " ")
+
+			if j := strings.Index(meta, "FBAV/"); j > -1 {
+				meta = meta[j:]
+
+				if k := strings.IndexByte(meta, ';'); k > -1 {
+					meta = meta[:k]
+				}
+
+				return BrowserFacebook, getProductVersion(meta, 1)
+			}
+
+			return BrowserFacebook, ""
+		} else if product == "Instagram" {
+			// Instagram 271.1.0.11.84 Android (...)
+			if i+1 < len(products) {
+				return BrowserInstagram, getOSVersion(products[i+1], 1)
+			}
+
+			return BrowserInstagram, ""
+		} else if product == "Zalo" || strings.HasPrefix(product, "ZaloTheme/") {
+			return BrowserZalo, ""
+		} else if strings.HasPrefix(product, "musical_ly_") || strings.HasPrefix(product, "trill_") ||
+			strings.HasPrefix(product, "BytedanceWebview/") || strings.HasPrefix(product, "AppName/musical_ly") ||
+			strings.HasPrefix(product, "AppName/trill") {
+			if appVersion := findPrefix(products, "app_version/"); appVersion != "" {
+				return BrowserTikTok, getProductVersion(appVersion, 1)
+			}
+
+			// iOS sends the version as part of the app name: musical_ly_28.6.0
+			if appName := findPrefix(products, "musical_ly_", "trill_"); strings.ContainsRune(appName, uaVersionDelimiter) {
+				return BrowserTikTok, getOSVersion(appName[strings.LastIndexByte(appName, '_')+1:], 1)
+			}
+
+			return BrowserTikTok, ""
+		}
+	}
+
+	return "", ""
+}
+
+// isWebView returns true if the user agent belongs to an in-app browser or embedded webview.
+// Android webviews add "wv" to the system information, iOS webviews of unknown apps don't send the Safari product.
+func isWebView(products []string, system []string, browser, os string) bool {
+	switch browser {
+	case BrowserFacebook, BrowserInstagram, BrowserZalo, BrowserTikTok:
+		return true
+	}
+
+	if os == OSAndroid && findPrefix(system, "wv") != "" {
+		return true
+	}
+
+	return os == OSiOS && browser == "" && len(products) > 0 && findPrefix(products, "Safari/") == ""
+}
+
 // older Safari versions send their version number inside the Version/ product string instead of the Safari/ part
 func getSafariVersion(products []string, productSafari string) string {
 	productVersion := findPrefix(products, "Version/")
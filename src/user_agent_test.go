@@ -40,6 +40,13 @@ func TestGetBrowserChromeSafari(t *testing.T) {
 	assert.Equal(t, "14.0", version)
 }
 
+func TestIsWebView(t *testing.T) {
+	for _, ua := range userAgentsAll {
+		system, products := parseUserAgent(ua.ua)
+		assert.Equal(t, ua.webView, isWebView(products, system, ua.browser, ua.os), ua.ua)
+	}
+}
+
 func TestGetOS(t *testing.T) {
 	for _, ua := range userAgentsAll {
 		system, _ := parseUserAgent(ua.ua)
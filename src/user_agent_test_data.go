@@ -6,6 +6,7 @@ type testUserAgent struct {
 	browserVersion string
 	os             string
 	osVersion      string
+	webView        bool
 }
 
 var userAgentsEdge = []testUserAgent{
@@ -336,6 +337,7 @@ var userAgentsChrome = []testUserAgent{
 		browserVersion: "43.0",
 		os:             OSAndroid,
 		osVersion:      "5.1.1",
+		webView:        true,
 	},
 }
 
@@ -446,12 +448,175 @@ var userAgentsIE = []testUserAgent{
 	},
 }
 
+var userAgentsCocCoc = []testUserAgent{
+	{
+		ua:             "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) coc_coc_browser/117.0.222 Chrome/111.0.5563.222 Safari/537.36",
+		browser:        BrowserCocCoc,
+		browserVersion: "117.0",
+		os:             OSWindows,
+		osVersion:      "10",
+	},
+	{
+		ua:             "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) coc_coc_browser/116.0.190 Chrome/110.0.5481.190 Safari/537.36",
+		browser:        BrowserCocCoc,
+		browserVersion: "116.0",
+		os:             OSMac,
+		osVersion:      "10.15.7",
+	},
+	{
+		ua:             "Mozilla/5.0 (Linux; Android 11; SM-A125F) AppleWebKit/537.36 (KHTML, like Gecko) coc_coc_browser/116.0.196 Mobile Chrome/110.0.5481.196 Mobile Safari/537.36",
+		browser:        BrowserCocCoc,
+		browserVersion: "116.0",
+		os:             OSAndroid,
+		osVersion:      "11",
+	},
+}
+
+var userAgentsSamsung = []testUserAgent{
+	{
+		ua:             "Mozilla/5.0 (Linux; Android 13; SAMSUNG SM-S908E) AppleWebKit/537.36 (KHTML, like Gecko) SamsungBrowser/20.0 Chrome/106.0.5249.126 Mobile Safari/537.36",
+		browser:        BrowserSamsung,
+		browserVersion: "20.0",
+		os:             OSAndroid,
+		osVersion:      "13",
+	},
+	{
+		ua:             "Mozilla/5.0 (Linux; Android 10; SAMSUNG SM-T515) AppleWebKit/537.36 (KHTML, like Gecko) SamsungBrowser/19.0 Chrome/102.0.5005.125 Safari/537.36",
+		browser:        BrowserSamsung,
+		browserVersion: "19.0",
+		os:             OSAndroid,
+		osVersion:      "10",
+	},
+}
+
+var userAgentsUC = []testUserAgent{
+	{
+		ua:             "Mozilla/5.0 (Linux; U; Android 10; en-US; RMX2185 Build/QP1A.190711.020) AppleWebKit/537.36 (KHTML, like Gecko) Version/4.0 Chrome/78.0.3904.108 UCBrowser/13.4.0.1306 Mobile Safari/537.36",
+		browser:        BrowserUC,
+		browserVersion: "13.4",
+		os:             OSAndroid,
+		osVersion:      "10",
+	},
+	{
+		ua:             "Mozilla/5.0 (iPhone; CPU iPhone OS 15_6 like Mac OS X; vi-VN) AppleWebKit/605.1.15 (KHTML, like Gecko) Mobile/15E148 UCBrowser/13.7.5.1814 Mobile AliApp(TUnionSDK/0.1.20.4)",
+		browser:        BrowserUC,
+		browserVersion: "13.7",
+		os:             OSiOS,
+		osVersion:      "15.6",
+	},
+}
+
+var userAgentsYandex = []testUserAgent{
+	{
+		ua:             "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/108.0.0.0 YaBrowser/23.1.1.1138 Yowser/2.5 Safari/537.36",
+		browser:        BrowserYandex,
+		browserVersion: "23.1",
+		os:             OSWindows,
+		osVersion:      "10",
+	},
+	{
+		ua:             "Mozilla/5.0 (iPhone; CPU iPhone OS 16_3 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/16.0 YaBrowser/23.1.4.371.10 Mobile/15E148 Safari/604.1",
+		browser:        BrowserYandex,
+		browserVersion: "23.1",
+		os:             OSiOS,
+		osVersion:      "16.3",
+	},
+}
+
+var userAgentsInApp = []testUserAgent{
+	{
+		ua:             "Mozilla/5.0 (Linux; Android 12; SM-A515F Build/SP1A.210812.016; wv) AppleWebKit/537.36 (KHTML, like Gecko) Version/4.0 Chrome/110.0.5481.153 Mobile Safari/537.36 [FB_IAB/FB4A;FBAV/403.0.0.27.81;]",
+		browser:        BrowserFacebook,
+		browserVersion: "403.0",
+		os:             OSAndroid,
+		osVersion:      "12",
+		webView:        true,
+	},
+	{
+		ua:             "Mozilla/5.0 (iPhone; CPU iPhone OS 16_3 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Mobile/15E148 [FBAN/FBIOS;FBAV/403.0.0.36.111;FBBV/480102187;FBDV/iPhone13,2;FBMD/iPhone;FBSN/iOS;FBSV/16.3;FBSS/3;FBID/phone;FBLC/vi_VN;FBOP/5]",
+		browser:        BrowserFacebook,
+		browserVersion: "403.0",
+		os:             OSiOS,
+		osVersion:      "16.3",
+		webView:        true,
+	},
+	{
+		ua:             "Mozilla/5.0 (iPhone; CPU iPhone OS 15_7 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Mobile/15E148 [FBAN/MessengerForiOS;FBDV/iPhone11,8;FBMD/iPhone;FBSN/iOS;FBSV/15.7;FBSS/2;FBID/phone;FBLC/en_US;FBOP/5]",
+		browser:        BrowserFacebook,
+		browserVersion: "",
+		os:             OSiOS,
+		osVersion:      "15.7",
+		webView:        true,
+	},
+	{
+		ua:             "Mozilla/5.0 (Linux; Android 12; CPH2205 Build/SP1A.210812.016; wv) AppleWebKit/537.36 (KHTML, like Gecko) Version/4.0 Chrome/110.0.5481.153 Mobile Safari/537.36 Instagram 271.1.0.11.84 Android (31/12; 480dpi; 1080x2177; OPPO; CPH2205; OP4F2F; mt6779; vi_VN; 449503005)",
+		browser:        BrowserInstagram,
+		browserVersion: "271.1",
+		os:             OSAndroid,
+		osVersion:      "12",
+		webView:        true,
+	},
+	{
+		ua:             "Mozilla/5.0 (iPhone; CPU iPhone OS 16_3 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Mobile/15E148 Instagram 270.0.0.13.83 (iPhone13,2; iOS 16_3; vi_VN; vi-VN; scale=3.00; 1170x2532; 445843262)",
+		browser:        BrowserInstagram,
+		browserVersion: "270.0",
+		os:             OSiOS,
+		osVersion:      "16.3",
+		webView:        true,
+	},
+	{
+		ua:             "Mozilla/5.0 (Linux; Android 11; M2101K6G Build/RKQ1.200826.002; wv) AppleWebKit/537.36 (KHTML, like Gecko) Version/4.0 Chrome/110.0.5481.153 Mobile Safari/537.36 Zalo android/12110620 ZaloTheme/light ZaloLanguage/vn",
+		browser:        BrowserZalo,
+		browserVersion: "",
+		os:             OSAndroid,
+		osVersion:      "11",
+		webView:        true,
+	},
+	{
+		ua:             "Mozilla/5.0 (iPhone; CPU iPhone OS 16_1_1 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Mobile/15E148 ZaloTheme/light ZaloLanguage/vi",
+		browser:        BrowserZalo,
+		browserVersion: "",
+		os:             OSiOS,
+		osVersion:      "16.1.1",
+		webView:        true,
+	},
+	{
+		ua:             "Mozilla/5.0 (Linux; Android 12; SM-A325F Build/SP1A.210812.016; wv) AppleWebKit/537.36 (KHTML, like Gecko) Version/4.0 Chrome/103.0.5060.129 Mobile Safari/537.36 trill_2022806050 JsSdk/1.0 NetType/WIFI Channel/googleplay AppName/trill app_version/28.6.5 ByteLocale/vi ByteFullLocale/vi Region/VN BytedanceWebview/d8a21c6",
+		browser:        BrowserTikTok,
+		browserVersion: "28.6",
+		os:             OSAndroid,
+		osVersion:      "12",
+		webView:        true,
+	},
+	{
+		ua:             "Mozilla/5.0 (iPhone; CPU iPhone OS 16_2 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Mobile/15E148 musical_ly_28.6.0 JsSdk/2.0 NetType/WIFI Channel/App Store ByteLocale/vi Region/VN RevealType/Dialog isDarkMode/0 WKWebView/1 BytedanceWebview/d8a21c6",
+		browser:        BrowserTikTok,
+		browserVersion: "28.6",
+		os:             OSiOS,
+		osVersion:      "16.2",
+		webView:        true,
+	},
+	{
+		ua:             "Mozilla/5.0 (Linux; Android 10; Redmi Note 8 Build/QKQ1.200114.002; wv) AppleWebKit/537.36 (KHTML, like Gecko) Version/4.0 Chrome/110.0.5481.153 Mobile Safari/537.36",
+		browser:        BrowserChrome,
+		browserVersion: "110.0",
+		os:             OSAndroid,
+		osVersion:      "10",
+		webView:        true,
+	},
+}
+
 var userAgentsAll = mergeUserAgentLists(userAgentsEdge,
 	userAgentsOpera,
 	userAgentsFirefox,
 	userAgentsChrome,
 	userAgentsSafari,
-	userAgentsIE)
+	userAgentsIE,
+	userAgentsCocCoc,
+	userAgentsSamsung,
+	userAgentsUC,
+	userAgentsYandex,
+	userAgentsInApp)
 
 func mergeUserAgentLists(ua ...[]testUserAgent) []testUserAgent {
 	list := make([]testUserAgent, 0)
@@ -48,3 +48,15 @@ func Contains(s []string, str string) bool {
 
 	return false
 }
+
+func uniqueStrings(list []string) []string {
+	unique := make([]string, 0, len(list))
+
+	for _, str := range list {
+		if !containsString(unique, str) {
+			unique = append(unique, str)
+		}
+	}
+
+	return unique
+}